@@ -21,7 +21,10 @@ type arguments struct {
 	*args.MongoDB
 	*args.RabbitMQ
 	*args.Logging
-	httpPort int
+	httpPort          int
+	noContent         bool
+	forwardedLocation string
+	revealConflicts   bool
 }
 
 func (arg *arguments) Flags() []cli.Flag {
@@ -34,6 +37,24 @@ func (arg *arguments) Flags() []cli.Flag {
 			Value:       8080,
 			Destination: &arg.httpPort,
 		},
+		&cli.BoolFlag{
+			Name:        "no-content",
+			Usage:       "Respond to successful replace and patch requests with 204 (No Content), without resource in the body, unless the client prefers return=representation",
+			EnvVars:     []string{"NO_CONTENT"},
+			Destination: &arg.noContent,
+		},
+		&cli.StringFlag{
+			Name:        "forwarded-location",
+			Usage:       "Base path (i.e. /scim/v2) of the resource URLs behind a reverse proxy; when set, the Location header is formed from the X-Forwarded-Proto and X-Forwarded-Host request headers, followed by the base path, instead of the stored meta.location",
+			EnvVars:     []string{"FORWARDED_LOCATION"},
+			Destination: &arg.forwardedLocation,
+		},
+		&cli.BoolFlag{
+			Name:        "reveal-conflicting-id",
+			Usage:       "Include the id of the existing resource in the body of 409 (Conflict) responses to uniqueness violations; off by default as it discloses the existence of resources to the client",
+			EnvVars:     []string{"REVEAL_CONFLICTING_ID"},
+			Destination: &arg.revealConflicts,
+		},
 	}
 	flags = append(flags, arg.Scim.Flags()...)
 	flags = append(flags, arg.MemoryDB.Flags()...)
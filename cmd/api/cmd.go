@@ -2,6 +2,8 @@ package api
 
 import (
 	"fmt"
+	"github.com/imulab/go-scim/internal/handler"
+	"github.com/imulab/go-scim/pkg/v2/handlerutil"
 	"github.com/julienschmidt/httprouter"
 	"github.com/urfave/cli/v2"
 	"net/http"
@@ -22,34 +24,52 @@ func Command() *cli.Command {
 
 			var router = httprouter.New()
 			{
-				router.GET("/ServiceProviderConfig", ServiceProviderConfigHandler(app.ServiceProviderConfig()))
-				router.GET("/Schemas", SchemasHandler())
-				router.GET("/Schemas/:id", SchemaByIdHandler())
-				router.GET("/ResourceTypes", ResourceTypesHandler(app.UserResourceType(), app.GroupResourceType()))
-				router.GET("/ResourceTypes/:id", ResourceTypeByIdHandler(app.userResourceType, app.GroupResourceType()))
-
-				router.GET("/Users/:id", GetHandler(app.UserGetService(), app.Logger()))
-				router.GET("/Users", SearchHandler(app.UserQueryService(), app.Logger()))
-				router.POST("/Users", CreateHandler(app.UserCreateService(), app.Logger()))
-				router.PUT("/Users/:id", ReplaceHandler(app.UserReplaceService(), app.Logger()))
-				router.PATCH("/Users/:id", PatchHandler(app.UserPatchService(), app.Logger()))
-				router.DELETE("/Users/:id", DeleteHandler(app.UserDeleteService(), app.Logger()))
-
-				router.GET("/Groups/:id", GetHandler(app.GroupGetService(), app.Logger()))
-				router.GET("/Groups", SearchHandler(app.GroupQueryService(), app.Logger()))
-				router.POST("/Groups", CreateHandler(app.GroupCreateService(), app.Logger()))
-				router.PUT("/Groups/:id", ReplaceHandler(app.GroupReplaceService(), app.Logger()))
-				router.PATCH("/Groups/:id", PatchHandler(app.GroupPatchService(), app.Logger()))
-				router.DELETE("/Groups/:id", DeleteHandler(app.GroupDeleteService(), app.Logger()))
+				router.GET("/ServiceProviderConfig", handler.ServiceProviderConfigHandler(app.ServiceProviderConfig()))
+				router.GET("/Schemas", handler.SchemasHandler())
+				router.GET("/Schemas/:id", handler.SchemaByIdHandler())
+				router.GET("/ResourceTypes", handler.ResourceTypesHandler(app.UserResourceType(), app.GroupResourceType()))
+				router.GET("/ResourceTypes/:id", handler.ResourceTypeByIdHandler(app.userResourceType, app.GroupResourceType()))
+
+				router.GET("/Users/:id", handler.GetHandler(app.UserGetService(), app.Logger()))
+				router.HEAD("/Users/:id", handler.HeadHandler(app.UserGetService(), app.Logger()))
+				router.GET("/Users", handler.SearchHandler(app.UserQueryService(), app.Logger()))
+				router.POST("/Users/.search", handler.SearchHandler(app.UserQueryService(), app.Logger()))
+				router.POST("/Users", handler.CreateHandler(app.UserCreateService(), app.Logger()))
+				router.PUT("/Users/:id", handler.ReplaceOrCreateHandler(app.UserReplaceService(), app.UserCreateService(), args.noContent, app.Logger()))
+				router.PATCH("/Users/:id", handler.PatchHandler(app.UserPatchService(), args.noContent, app.Logger()))
+				router.DELETE("/Users/:id", handler.DeleteHandler(app.UserDeleteService(), app.Logger()))
+				router.DELETE("/Users/:id/:attribute/:key", handler.DeleteElementHandler(app.UserDeleteElementService(), app.Logger()))
+
+				router.GET("/Groups/:id", handler.GetHandler(app.GroupGetService(), app.Logger()))
+				router.HEAD("/Groups/:id", handler.HeadHandler(app.GroupGetService(), app.Logger()))
+				router.GET("/Groups", handler.SearchHandler(app.GroupQueryService(), app.Logger()))
+				router.POST("/Groups/.search", handler.SearchHandler(app.GroupQueryService(), app.Logger()))
+				router.POST("/Groups", handler.CreateHandler(app.GroupCreateService(), app.Logger()))
+				router.PUT("/Groups/:id", handler.ReplaceOrCreateHandler(app.GroupReplaceService(), app.GroupCreateService(), args.noContent, app.Logger()))
+				router.PATCH("/Groups/:id", handler.PatchHandler(app.GroupPatchService(), args.noContent, app.Logger()))
+				router.DELETE("/Groups/:id", handler.DeleteHandler(app.GroupDeleteService(), app.Logger()))
+				router.DELETE("/Groups/:id/:attribute/:key", handler.DeleteElementHandler(app.GroupDeleteElementService(), app.Logger()))
 
 				router.GET("/health", HealthHandler(app.MongoClient(), app.RabbitMQConnection()))
 			}
 
+			var location handlerutil.LocationBuilder
+			if len(args.forwardedLocation) > 0 {
+				location = handlerutil.ForwardedLocationBuilder(args.forwardedLocation)
+			}
+
 			app.Logger().Info().Fields(map[string]interface{}{
 				"port": args.httpPort,
 			}).Msg("Listening for incoming requests.")
 
-			return http.ListenAndServe(fmt.Sprintf(":%d", args.httpPort), router)
+			return http.ListenAndServe(fmt.Sprintf(":%d", args.httpPort), handlerutil.Recover(handlerutil.LocationContext(router, location), func(r *http.Request, recovered interface{}, stack []byte) {
+				app.Logger().Error().Fields(map[string]interface{}{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"panic":  fmt.Sprintf("%v", recovered),
+					"stack":  string(stack),
+				}).Msg("recovered from panic when serving request")
+			}))
 		},
 	}
 }
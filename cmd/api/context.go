@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/imulab/go-scim/cmd/internal/groupsync"
 	scimmongo "github.com/imulab/go-scim/mongo/v2"
+	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/handlerutil"
 	"github.com/imulab/go-scim/pkg/v2/service"
 	"github.com/imulab/go-scim/pkg/v2/service/filter"
 	"github.com/imulab/go-scim/pkg/v2/spec"
@@ -37,6 +39,8 @@ type applicationContext struct {
 	groupPatchService         service.Patch
 	userDeleteService         service.Delete
 	groupDeleteService        service.Delete
+	userDeleteElementService  service.DeleteElement
+	groupDeleteElementService service.DeleteElement
 	userGetService            service.Get
 	groupGetService           service.Get
 	userQueryService          service.Query
@@ -72,6 +76,7 @@ func (ctx *applicationContext) UserResourceType() *spec.ResourceType {
 			ctx.logInitFailure("user resource type", err)
 			panic(err)
 		}
+		crud.Register(u)
 		ctx.userResourceType = u
 		ctx.logInitialized("user resource type")
 	}
@@ -86,6 +91,7 @@ func (ctx *applicationContext) GroupResourceType() *spec.ResourceType {
 			ctx.logInitFailure("group resource type", err)
 			panic(err)
 		}
+		crud.Register(g)
 		ctx.groupResourceType = g
 		ctx.logInitialized("group resource type")
 	}
@@ -122,7 +128,7 @@ func (ctx *applicationContext) MongoClient() *mongo.Client {
 func (ctx *applicationContext) UserDatabase() db.DB {
 	if ctx.userDatabase == nil {
 		if ctx.args.UseMemoryDB {
-			ctx.userDatabase = db.Memory()
+			ctx.userDatabase = db.Memory("userName")
 			ctx.logInitialized("in-memory user database")
 		} else {
 			ctx.ensureMongoMetadata()
@@ -130,7 +136,11 @@ func (ctx *applicationContext) UserDatabase() db.DB {
 			collection := ctx.MongoClient().
 				Database(ctx.args.MongoDB.Database, options.Database()).
 				Collection(resourceType.Name(), options.Collection())
-			ctx.userDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().IgnoreProjection())
+			ctx.userDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().
+				IgnoreProjection().
+				LogIndexError(func(err error) {
+					ctx.Logger().Warn().Err(err).Msg("failed to create mongo user database index")
+				}))
 			ctx.logInitialized("mongo user database")
 		}
 	}
@@ -140,7 +150,7 @@ func (ctx *applicationContext) UserDatabase() db.DB {
 func (ctx *applicationContext) GroupDatabase() db.DB {
 	if ctx.groupDatabase == nil {
 		if ctx.args.UseMemoryDB {
-			ctx.groupDatabase = db.Memory()
+			ctx.groupDatabase = db.Memory("displayName")
 			ctx.logInitialized("in-memory group database")
 		} else {
 			ctx.ensureMongoMetadata()
@@ -148,7 +158,11 @@ func (ctx *applicationContext) GroupDatabase() db.DB {
 			collection := ctx.MongoClient().
 				Database(ctx.args.MongoDB.Database, options.Database()).
 				Collection(resourceType.Name(), options.Collection())
-			ctx.groupDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().IgnoreProjection())
+			ctx.groupDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().
+				IgnoreProjection().
+				LogIndexError(func(err error) {
+					ctx.Logger().Warn().Err(err).Msg("failed to create mongo group database index")
+				}))
 			ctx.logInitialized("mongo group database")
 		}
 	}
@@ -165,6 +179,15 @@ func (ctx *applicationContext) ensureMongoMetadata() {
 	})
 }
 
+// validationFilter returns the validation filter backed by the database, which reveals the id of the resource holding
+// the conflicting value in uniqueness errors when configured to.
+func (ctx *applicationContext) validationFilter(database db.DB) filter.ByProperty {
+	if ctx.args.revealConflicts {
+		return filter.ValidationFilterRevealingConflicts(database)
+	}
+	return filter.ValidationFilter(database)
+}
+
 func (ctx *applicationContext) UserCreateService() service.Create {
 	if ctx.userCreateService == nil {
 		ctx.userCreateService = service.CreateService(ctx.UserResourceType(), ctx.UserDatabase(), []filter.ByResource{
@@ -174,7 +197,7 @@ func (ctx *applicationContext) UserCreateService() service.Create {
 				filter.BCryptFilter(),
 			),
 			filter.MetaFilter(),
-			filter.ByPropertyToByResource(filter.ValidationFilter(ctx.UserDatabase())),
+			filter.ByPropertyToByResource(ctx.validationFilter(ctx.UserDatabase())),
 		})
 		ctx.logInitialized("user create service")
 	}
@@ -190,7 +213,7 @@ func (ctx *applicationContext) GroupCreateService() service.Create {
 					filter.UUIDFilter(),
 				),
 				filter.MetaFilter(),
-				filter.ByPropertyToByResource(filter.ValidationFilter(ctx.GroupDatabase())),
+				filter.ByPropertyToByResource(ctx.validationFilter(ctx.GroupDatabase())),
 			}),
 			sender: &groupSyncSender{
 				channel: ctx.RabbitMQChannel(),
@@ -209,7 +232,7 @@ func (ctx *applicationContext) UserReplaceService() service.Replace {
 				filter.ReadOnlyFilter(),
 				filter.BCryptFilter(),
 			),
-			filter.ByPropertyToByResource(filter.ValidationFilter(ctx.UserDatabase())),
+			filter.ByPropertyToByResource(ctx.validationFilter(ctx.UserDatabase())),
 			filter.MetaFilter(),
 		})
 		ctx.logInitialized("user replace service")
@@ -224,7 +247,7 @@ func (ctx *applicationContext) GroupReplaceService() service.Replace {
 				filter.ByPropertyToByResource(
 					filter.ReadOnlyFilter(),
 				),
-				filter.ByPropertyToByResource(filter.ValidationFilter(ctx.UserDatabase())),
+				filter.ByPropertyToByResource(ctx.validationFilter(ctx.UserDatabase())),
 				filter.MetaFilter(),
 			}),
 			sender: &groupSyncSender{
@@ -244,7 +267,7 @@ func (ctx *applicationContext) UserPatchService() service.Patch {
 				filter.ReadOnlyFilter(),
 				filter.BCryptFilter(),
 			),
-			filter.ByPropertyToByResource(filter.ValidationFilter(ctx.UserDatabase())),
+			filter.ByPropertyToByResource(ctx.validationFilter(ctx.UserDatabase())),
 			filter.MetaFilter(),
 		})
 		ctx.logInitialized("user patch service")
@@ -259,7 +282,7 @@ func (ctx *applicationContext) GroupPatchService() service.Patch {
 				filter.ByPropertyToByResource(
 					filter.ReadOnlyFilter(),
 				),
-				filter.ByPropertyToByResource(filter.ValidationFilter(ctx.GroupDatabase())),
+				filter.ByPropertyToByResource(ctx.validationFilter(ctx.GroupDatabase())),
 				filter.MetaFilter(),
 			}),
 			sender: &groupSyncSender{
@@ -294,9 +317,25 @@ func (ctx *applicationContext) GroupDeleteService() service.Delete {
 	return ctx.groupDeleteService
 }
 
+func (ctx *applicationContext) UserDeleteElementService() service.DeleteElement {
+	if ctx.userDeleteElementService == nil {
+		ctx.userDeleteElementService = service.DeleteElementService(ctx.UserDatabase(), ctx.UserPatchService())
+		ctx.logInitialized("user delete element service")
+	}
+	return ctx.userDeleteElementService
+}
+
+func (ctx *applicationContext) GroupDeleteElementService() service.DeleteElement {
+	if ctx.groupDeleteElementService == nil {
+		ctx.groupDeleteElementService = service.DeleteElementService(ctx.GroupDatabase(), ctx.GroupPatchService())
+		ctx.logInitialized("group delete element service")
+	}
+	return ctx.groupDeleteElementService
+}
+
 func (ctx *applicationContext) UserGetService() service.Get {
 	if ctx.userGetService == nil {
-		ctx.userGetService = service.GetService(ctx.UserDatabase())
+		ctx.userGetService = service.GetService(ctx.UserDatabase(), service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext)))
 		ctx.logInitialized("user get service")
 	}
 	return ctx.userGetService
@@ -304,7 +343,7 @@ func (ctx *applicationContext) UserGetService() service.Get {
 
 func (ctx *applicationContext) GroupGetService() service.Get {
 	if ctx.groupGetService == nil {
-		ctx.groupGetService = service.GetService(ctx.GroupDatabase())
+		ctx.groupGetService = service.GetService(ctx.GroupDatabase(), service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext)))
 		ctx.logInitialized("group get service")
 	}
 	return ctx.groupGetService
@@ -312,7 +351,13 @@ func (ctx *applicationContext) GroupGetService() service.Get {
 
 func (ctx *applicationContext) UserQueryService() service.Query {
 	if ctx.userQueryService == nil {
-		ctx.userQueryService = service.QueryService(ctx.ServiceProviderConfig(), ctx.UserDatabase())
+		ctx.userQueryService = service.QueryService(
+			ctx.ServiceProviderConfig(),
+			ctx.UserDatabase(),
+			service.QueryWithDefaultPageSize(ctx.args.DefaultPageSize),
+			service.QueryWithResourceType(ctx.UserResourceType()),
+			service.QueryWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext)),
+		)
 		ctx.logInitialized("user query service")
 	}
 	return ctx.userQueryService
@@ -320,7 +365,13 @@ func (ctx *applicationContext) UserQueryService() service.Query {
 
 func (ctx *applicationContext) GroupQueryService() service.Query {
 	if ctx.groupQueryService == nil {
-		ctx.groupQueryService = service.QueryService(ctx.ServiceProviderConfig(), ctx.GroupDatabase())
+		ctx.groupQueryService = service.QueryService(
+			ctx.ServiceProviderConfig(),
+			ctx.GroupDatabase(),
+			service.QueryWithDefaultPageSize(ctx.args.DefaultPageSize),
+			service.QueryWithResourceType(ctx.GroupResourceType()),
+			service.QueryWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext)),
+		)
 		ctx.logInitialized("group query service")
 	}
 	return ctx.groupQueryService
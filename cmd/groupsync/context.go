@@ -120,7 +120,11 @@ func (ctx *applicationContext) UserDatabase() db.DB {
 			collection := ctx.MongoClient().
 				Database(ctx.args.MongoDB.Database, options.Database()).
 				Collection(resourceType.Name(), options.Collection())
-			ctx.userDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().IgnoreProjection())
+			ctx.userDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().
+				IgnoreProjection().
+				LogIndexError(func(err error) {
+					ctx.Logger().Warn().Err(err).Msg("failed to create mongo user database index")
+				}))
 			ctx.logInitialized("mongo user database")
 		}
 	}
@@ -138,7 +142,11 @@ func (ctx *applicationContext) GroupDatabase() db.DB {
 			collection := ctx.MongoClient().
 				Database(ctx.args.MongoDB.Database, options.Database()).
 				Collection(resourceType.Name(), options.Collection())
-			ctx.groupDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().IgnoreProjection())
+			ctx.groupDatabase = scimmongo.DB(resourceType, collection, scimmongo.Options().
+				IgnoreProjection().
+				LogIndexError(func(err error) {
+					ctx.Logger().Warn().Err(err).Msg("failed to create mongo group database index")
+				}))
 			ctx.logInitialized("mongo group database")
 		}
 	}
@@ -19,6 +19,11 @@ type Scim struct {
 	GroupResourceTypePath string
 	// Path to the directory containing all schema JSON file
 	SchemasDirectory string
+	// Number of resources to return in a query when client does not specify count; 0 for no default
+	DefaultPageSize int
+	// Maximum number of resources to return in a query; overrides the filter.maxResults in service provider config
+	// when positive
+	MaxPageSize int
 }
 
 // ParseServiceProviderConfig returns an instance of spec.ServiceProviderConfig from the JSON definition at
@@ -35,6 +40,10 @@ func (arg *Scim) ParseServiceProviderConfig() (*spec.ServiceProviderConfig, erro
 		return nil, err
 	}
 
+	if arg.MaxPageSize > 0 {
+		config.Filter.MaxResults = arg.MaxPageSize
+	}
+
 	return config, nil
 }
 
@@ -123,5 +132,17 @@ func (arg *Scim) Flags() []cli.Flag {
 			Required:    true,
 			Destination: &arg.ServiceProviderConfigPath,
 		},
+		&cli.IntFlag{
+			Name:        "default-page-size",
+			Usage:       "Number of resources to return in a query when count is not specified; 0 for no default",
+			EnvVars:     []string{"DEFAULT_PAGE_SIZE"},
+			Destination: &arg.DefaultPageSize,
+		},
+		&cli.IntFlag{
+			Name:        "max-page-size",
+			Usage:       "Maximum number of resources to return in a query; advertised as filter.maxResults",
+			EnvVars:     []string{"MAX_PAGE_SIZE"},
+			Destination: &arg.MaxPageSize,
+		},
 	}
 }
@@ -0,0 +1,520 @@
+// Package handler provides the httprouter route handlers serving the SCIM API on top of the services, shared by the
+// api command and the scimtest server.
+package handler
+
+import (
+	gojson "encoding/json"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
+	"github.com/imulab/go-scim/pkg/v2/handlerutil"
+	"github.com/imulab/go-scim/pkg/v2/json"
+	"github.com/imulab/go-scim/pkg/v2/service"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CreateHandler returns a route handler function for creating SCIM resources. When the client prefers return=minimal,
+// successful creation is responded with 201 and no body.
+func CreateHandler(svc service.Create, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		cr, closer := handlerutil.CreateRequest(r)
+		defer closer()
+
+		resp, err := svc.Do(r.Context(), cr)
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when creating resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		log.Info().Msg("resource created")
+		_ = handlerutil.WritePreferredResponse(rw, r, http.StatusCreated, false, resp.Resource)
+	}
+}
+
+// GetHandler returns a route handler function for getting SCIM resource.
+func GetHandler(svc service.Get, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving get request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		projection, err := handlerutil.GetRequestProjection(r)
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error parsing getting request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		resp, err := svc.Do(r.Context(), &service.GetRequest{
+			ResourceID: id,
+			Projection: projection,
+		})
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when getting resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		_ = handlerutil.WriteResourceToResponseFor(rw, r, resp.Resource, projectionOptions(projection)...)
+	}
+}
+
+// HeadHandler returns a route handler function for HEAD requests on a SCIM resource. The resource is fetched in the
+// same way as GetHandler, and the response carries the same status and headers as the equivalent GET, without body.
+func HeadHandler(svc service.Get, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving head request")
+			handlerutil.WriteErrorHeadersToResponse(rw, err)
+			return
+		}
+
+		resp, err := svc.Do(r.Context(), &service.GetRequest{
+			ResourceID: id,
+		})
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when getting resource")
+			handlerutil.WriteErrorHeadersToResponse(rw, err)
+			return
+		}
+
+		handlerutil.WriteResourceHeadersToResponseFor(rw, r, resp.Resource)
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+// DeleteHandler returns a route handler function for deleting SCIM resource.
+func DeleteHandler(svc service.Delete, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving delete request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		_, err := svc.Do(r.Context(), handlerutil.DeleteRequest(r)(id))
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when deleting resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		rw.WriteHeader(204)
+	}
+}
+
+// DeleteElementHandler returns a route handler function for deleting a single element of a multiValued attribute via
+// the sub resource path /<resource>/:id/:attribute/:key, such as /Users/:id/emails/:value.
+func DeleteElementHandler(svc service.DeleteElement, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving delete element request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		_, err := svc.Do(r.Context(), handlerutil.DeleteElementRequest(r)(id, params.ByName("attribute"), params.ByName("key")))
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when deleting element")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		rw.WriteHeader(204)
+	}
+}
+
+// ReplaceHandler returns a route handler function for replacing SCIM resource. When noContent is true, or when the
+// client prefers return=minimal, successful replacement is responded with 204 and no body. The client's preference of
+// return=representation takes precedence over noContent. The attributes and excludedAttributes parameters project the
+// replaced resource in the response. When the replaceScope parameter is specified, only the listed attributes are
+// replaced, and the rest of the stored resource is preserved (see handlerutil.ReplaceRequest).
+func ReplaceHandler(svc service.Replace, noContent bool, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving replace request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		projection, err := handlerutil.GetRequestProjection(r)
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error parsing replace request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		reqFunc, closer := handlerutil.ReplaceRequest(r)
+		defer closer()
+
+		resp, err := svc.Do(r.Context(), reqFunc(id))
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when replacing resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		if !resp.Replaced {
+			rw.WriteHeader(204)
+			return
+		}
+
+		_ = handlerutil.WritePreferredResponse(rw, r, http.StatusOK, noContent, resp.Resource, projectionOptions(projection)...)
+	}
+}
+
+// ReplaceOrCreateHandler returns a route handler function for replacing SCIM resource, like ReplaceHandler, unless the
+// client sends If-None-Match: *, in which case the resource is created with the id in the path if it does not exist, or
+// responded with 412 if it does. The existence check is atomic with the creation, as db.DB fails the insertion of an
+// existing id. Successful creation is responded in the same way as CreateHandler.
+func ReplaceOrCreateHandler(replace service.Replace, create service.Create, noContent bool, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	replaceHandler := ReplaceHandler(replace, noContent, log)
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 || !handlerutil.CreateIfAbsentRequested(r) {
+			replaceHandler(rw, r, params)
+			return
+		}
+
+		cr, closer := handlerutil.CreateRequest(r)
+		defer closer()
+		cr.ResourceID = id
+
+		resp, err := create.Do(r.Context(), cr)
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when creating resource with If-None-Match")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		log.Info().Msg("resource created")
+		_ = handlerutil.WritePreferredResponse(rw, r, http.StatusCreated, false, resp.Resource)
+	}
+}
+
+// PatchHandler returns a route handler function for patching SCIM resource. When noContent is true, or when the
+// client prefers return=minimal, successful patch is responded with 204 and no body. The client's preference of
+// return=representation takes precedence over noContent.
+func PatchHandler(svc service.Patch, noContent bool, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		id := params.ByName("id")
+		if len(id) == 0 {
+			err := fmt.Errorf("%w: id is empty", spec.ErrInvalidSyntax)
+			log.
+				Err(err).
+				Msg("error receiving patching request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		reqFunc, closer := handlerutil.PatchRequest(r)
+		defer closer()
+
+		resp, err := svc.Do(r.Context(), reqFunc(id))
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when patching resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		if !resp.Patched {
+			rw.WriteHeader(204)
+			return
+		}
+
+		_ = handlerutil.WritePreferredResponse(rw, r, http.StatusOK, noContent, resp.Resource, resp.Options...)
+	}
+}
+
+// SearchHandler returns a route handler function for searching SCIM resources. This handler could be used in HTTP GET and
+// HTTP POST scenarios, as defined in the SCIM specification. For HTTP POST, the handler shall be routed to the .search
+// path of the resource endpoint (i.e. POST /Users/.search), and the request body is a SearchRequest message. Both
+// methods execute the same query, and write a ListResponse message.
+func SearchHandler(svc service.Query, log *zerolog.Logger) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		var (
+			req    *service.QueryRequest
+			err    error
+			closer func()
+		)
+
+		switch r.Method {
+		case http.MethodGet:
+			req, err = handlerutil.QueryRequestFromGet(r)
+		case http.MethodPost:
+			req, closer, err = handlerutil.QueryRequestFromPost(r)
+		default:
+			err = errors.New("invalid method configured for search handler")
+		}
+
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when parsing search request")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		if closer != nil {
+			defer closer()
+		}
+
+		resp, err := svc.Do(r.Context(), req)
+		if err != nil {
+			log.
+				Err(err).
+				Msg("error when searching resource")
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+
+		_ = handlerutil.WriteSearchResultToResponse(rw, resp, projectionOptions(resp.Projection)...)
+	}
+}
+
+// projectionOptions returns the serialization options applying the projection, which may be nil.
+func projectionOptions(projection *crud.Projection) []json.Options {
+	var opt []json.Options
+	if projection != nil {
+		if len(projection.Attributes) > 0 {
+			opt = append(opt, json.Include(projection.Attributes...))
+		}
+		if len(projection.ExcludedAttributes) > 0 {
+			opt = append(opt, json.Exclude(projection.ExcludedAttributes...))
+		}
+	}
+	return opt
+}
+
+// ServiceProviderConfigHandler returns a http route handler to write service provider config info.
+func ServiceProviderConfigHandler(config *spec.ServiceProviderConfig) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	raw, err := gojson.Marshal(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		rw.Header().Set("Content-Type", "application/json+scim")
+		_, _ = rw.Write(raw)
+	}
+}
+
+// ResourceTypesHandler returns a route handler function for listing defined ResourceType. The list honors the
+// startIndex and count parameters, as well as a filter of the form 'id eq "<id>"'.
+func ResourceTypesHandler(resourceTypes ...*spec.ResourceType) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var items []discoverable
+	for _, resourceType := range resourceTypes {
+		items = append(items, discoverable{
+			id:           resourceType.ID(),
+			serializable: json.ResourceTypeToSerializable(resourceType),
+		})
+	}
+	return discoveryListHandler(items)
+}
+
+// ResourceTypeByIdHandler returns a route handler function get ResourceType by its id.
+func ResourceTypeByIdHandler(resourceTypes ...*spec.ResourceType) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	cache := map[string]gojson.RawMessage{}
+	for _, resourceType := range resourceTypes {
+		raw, err := json.Serialize(json.ResourceTypeToSerializable(resourceType))
+		if err != nil {
+			panic(err)
+		}
+		cache[resourceType.ID()] = raw
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		raw, ok := cache[params.ByName("id")]
+		if !ok {
+			_ = handlerutil.WriteError(rw, fmt.Errorf("%w: resource type is not found", spec.ErrNotFound))
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json+scim")
+		_, _ = rw.Write(raw)
+	}
+}
+
+// SchemasHandler returns a route handler function for listing defined Schema. The list honors the startIndex and
+// count parameters, as well as a filter of the form 'id eq "<id>"'.
+func SchemasHandler() func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var items []discoverable
+	if err := spec.Schemas().ForEachSchema(func(schema *spec.Schema) error {
+		if schema.ID() == spec.CoreSchemaId {
+			return nil
+		}
+		items = append(items, discoverable{
+			id:           schema.ID(),
+			serializable: json.SchemaToSerializable(schema),
+		})
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+	return discoveryListHandler(items)
+}
+
+// SchemaByIdHandler returns a route handler function get Schema by its id.
+func SchemaByIdHandler() func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	cache := map[string]gojson.RawMessage{}
+	if err := spec.Schemas().ForEachSchema(func(schema *spec.Schema) error {
+		if schema.ID() == spec.CoreSchemaId {
+			return nil
+		}
+
+		raw, err := json.Serialize(json.SchemaToSerializable(schema))
+		if err != nil {
+			return err
+		}
+		cache[schema.ID()] = raw
+
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		raw, ok := cache[params.ByName("id")]
+		if !ok {
+			_ = handlerutil.WriteError(rw, fmt.Errorf("%w: schema is not found", spec.ErrNotFound))
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json+scim")
+		_, _ = rw.Write(raw)
+	}
+}
+
+// discoverable is an item listed on the discovery endpoints.
+type discoverable struct {
+	id           string
+	serializable json.Serializable
+}
+
+// discoveryListHandler returns a route handler function that lists the items in a ListResponse, subject to the
+// pagination and a basic 'id eq' filter from the request.
+func discoveryListHandler(items []discoverable) func(rw http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	return func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		result, err := listDiscoverable(r, items)
+		if err != nil {
+			_ = handlerutil.WriteError(rw, err)
+			return
+		}
+		_ = handlerutil.WriteSearchResultToResponse(rw, result)
+	}
+}
+
+func listDiscoverable(r *http.Request, items []discoverable) (*service.QueryResponse, error) {
+	qr, err := handlerutil.QueryRequestFromGet(r)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := items
+	if len(qr.Filter) > 0 {
+		id, err := discoveryFilterId(qr.Filter)
+		if err != nil {
+			return nil, err
+		}
+		matched = []discoverable{}
+		for _, item := range items {
+			if strings.EqualFold(item.id, id) {
+				matched = append(matched, item)
+			}
+		}
+	}
+
+	result := &service.QueryResponse{
+		TotalResults: len(matched),
+		StartIndex:   1,
+		Resources:    []json.Serializable{},
+	}
+
+	start, end := 0, len(matched)
+	if qr.Pagination != nil {
+		result.StartIndex = qr.Pagination.StartIndex
+		start = qr.Pagination.StartIndex - 1
+		if start > len(matched) {
+			start = len(matched)
+		}
+		if qr.Pagination.Count != crud.CountUnspecified && start+qr.Pagination.Count < end {
+			end = start + qr.Pagination.Count
+		}
+	}
+	for _, item := range matched[start:end] {
+		result.Resources = append(result.Resources, item.serializable)
+	}
+	result.ItemsPerPage = len(result.Resources)
+
+	return result, nil
+}
+
+// discoveryFilterId returns the id from a filter in the form of 'id eq "<id>"', which is the only filter supported
+// by the discovery endpoints.
+func discoveryFilterId(filter string) (string, error) {
+	root, err := expr.CompileFilter(filter)
+	if err != nil {
+		return "", err
+	}
+
+	if !strings.EqualFold(root.Token(), expr.Eq) ||
+		root.Left() == nil || root.Left().Next() != nil || !strings.EqualFold(root.Left().Token(), "id") ||
+		root.Right() == nil || !root.Right().IsLiteral() {
+		return "", fmt.Errorf("%w: only 'id eq' filter is supported", spec.ErrInvalidFilter)
+	}
+
+	id, err := strconv.Unquote(root.Right().Token())
+	if err != nil {
+		return "", fmt.Errorf("%w: id must be a string", spec.ErrInvalidFilter)
+	}
+	return id, nil
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	gojson "encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResourceTypesHandler(t *testing.T) {
+	for _, name := range []string{"User", "Group", "Device"} {
+		schema := new(spec.Schema)
+		require.Nil(t, gojson.Unmarshal([]byte(`{"id": "urn:ietf:params:scim:schemas:test:2.0:`+name+`", "name": "`+name+`", "attributes": []}`), schema))
+		spec.Schemas().Register(schema)
+	}
+
+	var resourceTypes []*spec.ResourceType
+	for _, raw := range []string{
+		`{"id": "User", "name": "User", "endpoint": "/Users", "schema": "urn:ietf:params:scim:schemas:test:2.0:User"}`,
+		`{"id": "Group", "name": "Group", "endpoint": "/Groups", "schema": "urn:ietf:params:scim:schemas:test:2.0:Group"}`,
+		`{"id": "Device", "name": "Device", "endpoint": "/Devices", "schema": "urn:ietf:params:scim:schemas:test:2.0:Device"}`,
+	} {
+		resourceType := new(spec.ResourceType)
+		require.Nil(t, gojson.Unmarshal([]byte(raw), resourceType))
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+
+	type listResponse struct {
+		TotalResults int                      `json:"totalResults"`
+		ItemsPerPage int                      `json:"itemsPerPage"`
+		StartIndex   int                      `json:"startIndex"`
+		Resources    []map[string]interface{} `json:"Resources"`
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		expect func(t *testing.T, status int, resp *listResponse)
+	}{
+		{
+			name:  "list all",
+			query: "",
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, 3, resp.TotalResults)
+				assert.Equal(t, 3, resp.ItemsPerPage)
+				assert.Equal(t, 1, resp.StartIndex)
+				assert.Len(t, resp.Resources, 3)
+			},
+		},
+		{
+			name:  "list page",
+			query: "?startIndex=2&count=1",
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, 3, resp.TotalResults)
+				assert.Equal(t, 1, resp.ItemsPerPage)
+				assert.Equal(t, 2, resp.StartIndex)
+				require.Len(t, resp.Resources, 1)
+				assert.Equal(t, "Group", resp.Resources[0]["id"])
+			},
+		},
+		{
+			name:  "list from start index without count",
+			query: "?startIndex=3",
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, 3, resp.TotalResults)
+				assert.Equal(t, 1, resp.ItemsPerPage)
+				require.Len(t, resp.Resources, 1)
+				assert.Equal(t, "Device", resp.Resources[0]["id"])
+			},
+		},
+		{
+			name:  "list beyond the last page",
+			query: "?startIndex=5&count=10",
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, 3, resp.TotalResults)
+				assert.Equal(t, 0, resp.ItemsPerPage)
+				assert.Len(t, resp.Resources, 0)
+			},
+		},
+		{
+			name:  "filter by id",
+			query: `?filter=id+eq+%22group%22`,
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, 1, resp.TotalResults)
+				require.Len(t, resp.Resources, 1)
+				assert.Equal(t, "Group", resp.Resources[0]["id"])
+			},
+		},
+		{
+			name:  "unsupported filter",
+			query: `?filter=name+eq+%22Group%22`,
+			expect: func(t *testing.T, status int, resp *listResponse) {
+				assert.Equal(t, http.StatusBadRequest, status)
+			},
+		},
+	}
+
+	handler := ResourceTypesHandler(resourceTypes...)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			handler(rw, httptest.NewRequest(http.MethodGet, "/ResourceTypes"+test.query, nil), nil)
+
+			resp := new(listResponse)
+			require.Nil(t, gojson.Unmarshal(rw.Body.Bytes(), resp))
+			test.expect(t, rw.Code, resp)
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"strconv"
+	"strings"
 )
 
 // Create a db.DB implementation that persists data in MongoDB. This implementation supports one-to-one correspondence
@@ -19,7 +20,10 @@ import (
 //
 // The database will attempt to create MongoDB indexes on attributes whose uniqueness is global or server, or that has
 // been annotated with "@MongoIndex". For unique attributes, a unique MongoDB index will be created, otherwise, it is
-// just an ordinary index. Any index creation error are treated as non-error and simply ignored.
+// just an ordinary index. A unique index on id is always created, so that Insert returns a conflict error when a
+// resource with the same id exists, while the violation of the unique index of any other attribute by Insert or
+// Replace returns a uniqueness error. Index creation errors are not returned, but logged (see DBOptions.LogIndexError),
+// as the database remains usable without the indexes.
 //
 // This implementation has limited capability of correctly performing field projection according to the specification.
 // It dumbly treats the *crud.Projection parameter as it is without performing any sanitation. As a result, if any
@@ -56,6 +60,9 @@ import (
 // provided a resource as argument which was fetched from the database, hence, the resource by the id must have existed.
 // The only reason that id and version failed to match would then because another process modified the resource concurrently.
 // Therefore, conflict seems to be a reasonable error code.
+//
+// The returned database implements db.CompiledQuery, so that a filter compiled by the caller is transformed without
+// being compiled again.
 func DB(resourceType *spec.ResourceType, coll *mongo.Collection, opt *DBOptions) db.DB {
 	d := &mongoDB{
 		resourceType: resourceType,
@@ -79,13 +86,55 @@ type mongoDB struct {
 func (d *mongoDB) Insert(ctx context.Context, resource *prop.Resource) error {
 	_, err := d.coll.InsertOne(ctx, newBsonAdapter(resource), options.InsertOne())
 	if err != nil {
+		if dupErr := duplicateKeyError(err); dupErr != nil {
+			return dupErr
+		}
 		return fmt.Errorf("%w: %v", spec.ErrInternal, err)
 	}
 	return nil
 }
 
+// duplicateKeyError returns the error for the violation of a unique index, or nil if err is not such violation. The
+// violation of the index on id means a resource with the same id exists, and is reported as spec.ErrConflict, while
+// the violation of the index on any other attribute is reported as spec.ErrUniqueness.
+func duplicateKeyError(err error) error {
+	const duplicateKey = 11000
+
+	var messages []string
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == duplicateKey {
+				messages = append(messages, we.Message)
+			}
+		}
+	case mongo.CommandError:
+		if e.Code == duplicateKey {
+			messages = append(messages, e.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for _, message := range messages {
+		if strings.Contains(message, "index: "+indexName("id")+" ") {
+			return fmt.Errorf("%w: %v", spec.ErrConflict, err)
+		}
+	}
+	return fmt.Errorf("%w: %v", spec.ErrUniqueness, err)
+}
+
 func (d *mongoDB) Count(ctx context.Context, filter string) (int, error) {
-	tf, err := d.mongoFilter(filter)
+	cf, err := expr.CompileFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+	return d.CountCompiled(ctx, cf)
+}
+
+func (d *mongoDB) CountCompiled(ctx context.Context, filter *expr.Expression) (int, error) {
+	tf, err := d.t.transform(filter)
 	if err != nil {
 		return 0, err
 	}
@@ -125,6 +174,29 @@ func (d *mongoDB) Get(ctx context.Context, id string, projection *crud.Projectio
 	return w.Resource(), nil
 }
 
+// GetByAttribute queries for at most two resources matching the equality filter, which suffices to tell whether the
+// attribute is unique. The lookup uses the index on the attribute, if there is one (see @MongoIndex).
+func (d *mongoDB) GetByAttribute(ctx context.Context, attributePath string, value interface{}) (*prop.Resource, error) {
+	filter, err := db.EqualityFilter(attributePath, value)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := d.Query(ctx, filter, nil, &crud.Pagination{StartIndex: 1, Count: 2}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(results) {
+	case 0:
+		return nil, fmt.Errorf("%w: resource not found by '%s'", spec.ErrNotFound, attributePath)
+	case 1:
+		return results[0], nil
+	default:
+		return nil, fmt.Errorf("%w: more than one resource found by '%s', which is supposed to be unique", spec.ErrInternal, attributePath)
+	}
+}
+
 func (d *mongoDB) Replace(ctx context.Context, ref *prop.Resource, resource *prop.Resource) error {
 	var (
 		id      = ref.IdOrEmpty()
@@ -140,6 +212,9 @@ func (d *mongoDB) Replace(ctx context.Context, ref *prop.Resource, resource *pro
 		if err == mongo.ErrNoDocuments {
 			return d.errNotFoundOrModified(id)
 		}
+		if dupErr := duplicateKeyError(err); dupErr != nil {
+			return dupErr
+		}
 		return err
 	}
 
@@ -168,15 +243,26 @@ func (d *mongoDB) Delete(ctx context.Context, resource *prop.Resource) error {
 }
 
 func (d *mongoDB) Query(ctx context.Context, filter string, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error) {
+	cf, err := expr.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return d.QueryCompiled(ctx, cf, sort, pagination, projection)
+}
+
+func (d *mongoDB) QueryCompiled(ctx context.Context, filter *expr.Expression, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error) {
 	opt := options.Find()
 
-	tf, err := d.mongoFilter(filter)
+	tf, err := d.t.transform(filter)
 	if err != nil {
 		return nil, err
 	}
 
 	if sort != nil {
 		opt.SetSort(d.mongoSort(sort))
+		if collation := d.mongoCollation(sort); collation != nil {
+			opt.SetCollation(collation)
+		}
 	}
 	if pagination != nil {
 		skip, limit := d.mongoPagination(pagination)
@@ -221,29 +307,11 @@ func (d *mongoDB) Query(ctx context.Context, filter string, sort *crud.Sort, pag
 //
 // If this method is unable to find a path, or encounters any error, an empty string is returned.
 func (d *mongoDB) mongoPathFor(path string) string {
-	curAttr := d.superAttr
-	cursor, err := expr.CompilePath(path)
-	if err != nil {
+	curAttr, ok := d.resourceType.AttributeByPath(path)
+	if !ok {
 		return ""
 	}
 
-	// skip the first token in the path starts with the id of the resource type's default schema.
-	// For instance, "urn:ietf:params:scim:schemas:core:2.0:User:userName" should just be treated as "userName"
-	if cursor.Token() == d.resourceType.Schema().ID() {
-		cursor = cursor.Next()
-	}
-	if cursor == nil {
-		return ""
-	}
-
-	for cursor != nil {
-		curAttr = curAttr.SubAttributeForName(cursor.Token())
-		if curAttr == nil {
-			return ""
-		}
-		cursor = cursor.Next()
-	}
-
 	mp := curAttr.Path()
 	if md, ok := metadataHub[curAttr.ID()]; ok {
 		mp = md.MongoPath
@@ -276,11 +344,53 @@ func (d *mongoDB) mongoSort(sort *crud.Sort) bson.D {
 	}
 }
 
+// Convert the locale hint in crud.Sort to MongoDB collation option. The supplied sort parameter must not be nil. If the
+// locale is absent or not supported, nil is returned so that MongoDB falls back to its default simple binary comparison.
+// Note that MongoDB applies the collation to the entire query, not just the string typed sort keys.
+func (d *mongoDB) mongoCollation(sort *crud.Sort) *options.Collation {
+	locale, ok := crud.MatchCollationLocale(sort.Locale)
+	if !ok {
+		return nil
+	}
+	return &options.Collation{
+		Locale: mongoCollationLocale(locale),
+	}
+}
+
+// Convert a BCP 47 collation tag returned by crud.MatchCollationLocale to the ICU locale format accepted by MongoDB.
+// Subtags are joined by underscore, and the collation type in the "-u-co-" extension is carried by the "@collation="
+// keyword with its ICU name (i.e. "zh-Hant" becomes "zh_Hant", "de-u-co-phonebk" becomes "de@collation=phonebook").
+func mongoCollationLocale(tag string) string {
+	parts := strings.SplitN(tag, "-u-co-", 2)
+	locale := strings.ReplaceAll(parts[0], "-", "_")
+	if len(parts) < 2 {
+		return locale
+	}
+
+	collation := parts[1]
+	if icuName, ok := icuCollationTypes[collation]; ok {
+		collation = icuName
+	}
+	return locale + "@collation=" + collation
+}
+
+// BCP 47 collation types whose ICU names are different.
+var icuCollationTypes = map[string]string{
+	"dict":    "dictionary",
+	"gb2312":  "gb2312han",
+	"phonebk": "phonebook",
+	"trad":    "traditional",
+}
+
 // Convert crud.Pagination parameter to Mongo compatible option parameters. The supplied pagination parameter
 // must not be nil.
 func (d *mongoDB) mongoPagination(pagination *crud.Pagination) (skip int64, limit int64) {
 	skip = int64(pagination.StartIndex - 1)
 	limit = int64(pagination.Count)
+	if limit < 0 {
+		// zero limit is no limit for MongoDB
+		limit = 0
+	}
 	return
 }
 
@@ -301,7 +411,7 @@ func (d *mongoDB) mongoProjection(projection *crud.Projection) bson.D {
 
 	if len(projection.ExcludedAttributes) > 0 {
 		exclude := bson.D{}
-		for _, p := range projection.Attributes {
+		for _, p := range projection.ExcludedAttributes {
 			if mp := d.mongoPathFor(p); len(mp) > 0 {
 				exclude = append(exclude, bson.E{Key: mp, Value: 0})
 			}
@@ -337,6 +447,7 @@ func Options() *DBOptions {
 
 type DBOptions struct {
 	ignoreProjection bool
+	indexErrorLogger func(err error)
 }
 
 // Ask the database to ignore any projection parameters. This might be reasonable when the downstream services
@@ -346,6 +457,12 @@ func (opt *DBOptions) IgnoreProjection() *DBOptions {
 	return opt
 }
 
+// Ask the database to log index creation errors with the logger, instead of the standard logger of package log.
+func (opt *DBOptions) LogIndexError(logger func(err error)) *DBOptions {
+	opt.indexErrorLogger = logger
+	return opt
+}
+
 var (
 	_ db.DB = (*mongoDB)(nil)
 )
@@ -3,6 +3,7 @@ package v2
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
@@ -660,3 +661,91 @@ func envOrDefault(name string, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func TestMongoCollation(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		expect *options.Collation
+	}{
+		{
+			name:   "region is matched to its language",
+			locale: "de-DE",
+			expect: &options.Collation{Locale: "de"},
+		},
+		{
+			name:   "script and region",
+			locale: "zh-Hant-TW",
+			expect: &options.Collation{Locale: "zh_Hant"},
+		},
+		{
+			name:   "collation type",
+			locale: "de-DE-u-co-phonebk",
+			expect: &options.Collation{Locale: "de@collation=phonebook"},
+		},
+		{
+			name:   "unsupported locale",
+			locale: "tlh",
+			expect: nil,
+		},
+		{
+			name:   "absent locale",
+			locale: "",
+			expect: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, new(mongoDB).mongoCollation(&crud.Sort{By: "userName", Locale: test.locale}))
+		})
+	}
+}
+
+func TestMongoDuplicateKeyError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect error
+	}{
+		{
+			name: "duplicate id",
+			err: mongo.WriteException{WriteErrors: []mongo.WriteError{{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: scim.User index: idx_id dup key: { id: "foo" }`,
+			}}},
+			expect: spec.ErrConflict,
+		},
+		{
+			name: "duplicate attribute",
+			err: mongo.WriteException{WriteErrors: []mongo.WriteError{{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: scim.User index: idx_userName dup key: { userName: "foo" }`,
+			}}},
+			expect: spec.ErrUniqueness,
+		},
+		{
+			name: "duplicate attribute on replace",
+			err: mongo.CommandError{
+				Code:    11000,
+				Message: `E11000 duplicate key error collection: scim.User index: idx_userName dup key: { userName: "foo" }`,
+			},
+			expect: spec.ErrUniqueness,
+		},
+		{
+			name: "other write error",
+			err:  mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 121, Message: "Document failed validation"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := duplicateKeyError(test.err)
+			if test.expect == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.True(t, errors.Is(err, test.expect))
+			}
+		})
+	}
+}
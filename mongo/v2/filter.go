@@ -57,7 +57,16 @@ func (t *transformer) transform(root *expr.Expression) (bson.D, error) {
 	case expr.Not:
 		return t.transformNot(root)
 	default:
-		return t.transformRelational(t.superAttr, root.Left(), root, root.Right())
+		if root.IsPath() {
+			return t.transformValuePath(root)
+		}
+		path := root.Left()
+		// skip the first token in the path if it is the id of the resource type's main schema, which is the id of the
+		// super attribute. The comparison is case insensitive as the id is a URN.
+		if path != nil && path.IsPath() && strings.EqualFold(path.Token(), t.superAttr.ID()) {
+			path = path.Next()
+		}
+		return t.transformRelational(t.superAttr, path, root, root.Right())
 	}
 }
 
@@ -89,6 +98,8 @@ func (t *transformer) transformOr(root *expr.Expression) (bson.D, error) {
 	}, nil
 }
 
+// Transform the not operator to a $nor query, which negates a value path as a whole (including documents without the
+// multiValued attribute) to match the semantics of crud.Evaluate, see evalNot in package crud.
 func (t *transformer) transformNot(root *expr.Expression) (bson.D, error) {
 	left, err := t.transform(root.Left())
 	if err != nil {
@@ -99,6 +110,49 @@ func (t *transformer) transformNot(root *expr.Expression) (bson.D, error) {
 	}, nil
 }
 
+// Transform the value path (i.e. emails[type eq "work"]) to an $elemMatch query on the multiValued attribute, so that
+// the value filter must be satisfied by the same element as a whole. The value filter is transformed with the element
+// attribute of the multiValued attribute as the container.
+func (t *transformer) transformValuePath(root *expr.Expression) (bson.D, error) {
+	var (
+		cursorAttr = t.superAttr
+		pathNames  = make([]string, 0)
+		path       = root
+	)
+	if strings.EqualFold(path.Token(), t.superAttr.ID()) {
+		path = path.Next()
+	}
+	for ; path != nil && !path.IsRootOfFilter(); path = path.Next() {
+		if cursorAttr.MultiValued() {
+			return nil, fmt.Errorf("%w: value filter must be applied to '%s'", spec.ErrInvalidFilter, cursorAttr.Path())
+		}
+
+		cursorAttr = cursorAttr.SubAttributeForName(path.Token())
+		if cursorAttr == nil {
+			return nil, fmt.Errorf("%w: no path for '%s'", spec.ErrInvalidFilter, path.Token())
+		}
+
+		pathName := cursorAttr.Name()
+		if md, ok := metadataHub[cursorAttr.ID()]; ok {
+			pathName = md.MongoName
+		}
+		pathNames = append(pathNames, pathName)
+	}
+	if path == nil || !cursorAttr.MultiValued() {
+		return nil, fmt.Errorf("%w: filter applied to singular attribute", spec.ErrInvalidFilter)
+	}
+
+	criteria, err := (&transformer{superAttr: cursorAttr.DeriveElementAttribute()}).transform(path)
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{
+		{Key: strings.Join(pathNames, "."), Value: bson.D{
+			{Key: mongoElementMatch, Value: criteria},
+		}},
+	}, nil
+}
+
 func (t *transformer) transformRelational(containerAttr *spec.Attribute, path *expr.Expression, op *expr.Expression, value *expr.Expression) (bson.D, error) {
 	var (
 		cursorAttr = containerAttr
@@ -2,6 +2,7 @@ package v2
 
 import (
 	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -127,6 +128,24 @@ func (s *TransformFilterTestSuite) TestTransform() {
 				assert.JSONEq(t, expect, extJson)
 			},
 		},
+		{
+			name:   "mixed case second level string property pr",
+			filter: "NAME.FamilyName pr",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"$and":[{"name.familyName":{"$exists":true}},{"name.familyName":{"$ne":null}},{"name.familyName":{"$ne":""}}]}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
+		{
+			name:   "mixed case schema namespace prefixed property pr",
+			filter: "URN:IETF:PARAMS:SCIM:SCHEMAS:CORE:2.0:User:USERNAME pr",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"$and":[{"userName":{"$exists":true}},{"userName":{"$ne":null}},{"userName":{"$ne":""}}]}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
 		{
 			name:   "logical operator",
 			filter: "(userName eq \"imulab\") and (meta.created gt \"2019-12-20T04:40:00\")",
@@ -136,6 +155,42 @@ func (s *TransformFilterTestSuite) TestTransform() {
 				assert.JSONEq(t, expect, extJson)
 			},
 		},
+		{
+			name:   "value path",
+			filter: "emails[type eq \"work\" and value ew \"@foo.com\"]",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"emails":{"$elemMatch":{"$and":[{"type":{"$regularExpression":{"pattern":"^work$","options":"i"}}},{"value":{"$regularExpression":{"pattern":"@foo.com$","options":"i"}}}]}}}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
+		{
+			name:   "value path with $ref",
+			filter: "groups[$ref ew \"/Groups/123\"]",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"groups":{"$elemMatch":{"ref":{"$regularExpression":{"pattern":"/Groups/123$","options":"i"}}}}}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
+		{
+			name:   "not over value path combined with logical operator",
+			filter: "not (emails[type eq \"work\"]) and userName eq \"imulab\"",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"$and":[{"$nor":[{"emails":{"$elemMatch":{"type":{"$regularExpression":{"pattern":"^work$","options":"i"}}}}}]},{"userName":{"$regularExpression":{"pattern":"^imulab$","options":"i"}}}]}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
+		{
+			name:   "value path with ne",
+			filter: "emails[type ne \"work\"] and userName eq \"imulab\"",
+			expect: func(t *testing.T, extJson string, err error) {
+				assert.Nil(t, err)
+				expect := `{"$and":[{"emails":{"$elemMatch":{"type":{"$regularExpression":{"pattern":"^((?!work$).)","options":"i"}}}}},{"userName":{"$regularExpression":{"pattern":"^imulab$","options":"i"}}}]}`
+				assert.JSONEq(t, expect, extJson)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -174,6 +229,7 @@ func (s *TransformFilterTestSuite) SetupSuite() {
 			structure: new(spec.ResourceType),
 			post: func(parsed interface{}) {
 				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
 			},
 		},
 	} {
@@ -190,4 +246,8 @@ func (s *TransformFilterTestSuite) SetupSuite() {
 			each.post(each.structure)
 		}
 	}
+
+	raw, err := ioutil.ReadFile("../../public/mongo_metadata/user_metadata.json")
+	require.Nil(s.T(), err)
+	require.Nil(s.T(), ReadMetadata(raw))
 }
@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
 	"strings"
 )
 
@@ -16,12 +17,16 @@ const (
 	AnnotationMongoIndex = "@MongoIndex"
 )
 
+// ensureIndex creates the indexes of the attributes that are unique or annotated with @MongoIndex, and logs the index
+// creation errors.
 func (d *mongoDB) ensureIndex() {
+	var models []mongo.IndexModel
 	d.superAttr.DFS(func(a *spec.Attribute) {
 		if a.Uniqueness() == spec.UniquenessNone {
 			return
 		}
-		if _, ok := a.Annotation(AnnotationMongoIndex); !ok {
+		// id is always indexed, so that Insert fails atomically on duplicate ids.
+		if _, ok := a.Annotation(AnnotationMongoIndex); !ok && a.ID() != "id" {
 			return
 		}
 
@@ -37,22 +42,34 @@ func (d *mongoDB) ensureIndex() {
 		if a.Uniqueness() == spec.UniquenessServer || a.Uniqueness() == spec.UniquenessGlobal {
 			idm.Options.SetUnique(true)
 		}
-		if name := fmt.Sprintf("idx_%s", strings.Replace(path, ".", "_", -1)); len(name) < 127 {
+		if name := indexName(path); len(name) < 127 {
 			// https://docs.mongodb.com/manual/reference/command/createIndexes/
 			// For MongoDB 4.0 and earlier, the index name has a limit of 127 bytes, here we still adhere to this
 			// constraint without checking for server version. If the formed name is greater than 127 bytes, we will
 			// just let MongoDB choose a random name.
 			idm.Options.SetName(name)
 		}
+		models = append(models, idm)
+	})
 
-		_, err := d.coll.Indexes().CreateOne(context.Background(), idm, options.CreateIndexes())
-		if err != nil {
-			// https://docs.mongodb.com/manual/reference/command/createIndexes/
-			// Starting from MongoDB 4.2, MongoDB will return error if the index was already created. Previous
-			// version will return ok to indicate implicit success. Here, we regard any error as "not really an
-			// error" and only display warning information to logger.
-			return
+	for _, idm := range models {
+		// Creating an index identical to an existing one succeeds, hence any error is a genuine failure, i.e. an
+		// existing index by the same name but different options.
+		if _, err := d.coll.Indexes().CreateOne(context.Background(), idm, options.CreateIndexes()); err != nil {
+			d.logIndexError(fmt.Errorf("%w: failed to create index: %v", spec.ErrInternal, err))
 		}
+	}
+}
+
+func (d *mongoDB) logIndexError(err error) {
+	if d.opt.indexErrorLogger != nil {
+		d.opt.indexErrorLogger(err)
 		return
-	})
+	}
+	log.Printf("scim mongo: %v", err)
+}
+
+// indexName returns the name of the index on the mongo path.
+func indexName(path string) string {
+	return fmt.Sprintf("idx_%s", strings.Replace(path, ".", "_", -1))
 }
@@ -48,4 +48,16 @@ const (
 	// canonicalValues. The defined values will be treated as strings and compared with respect to the caseExact
 	// setting.
 	Enum = "@Enum"
+	// @MaxCardinality annotates a multiValued attribute and limits the number of elements its property can hold. The
+	// annotation takes an integer parameter named "max", which is the maximum number of elements allowed. The limit is
+	// enforced by the validation filter.
+	MaxCardinality = "@MaxCardinality"
+	// @Ordered annotates a multiValued attribute whose element order is significant (i.e. roles expressing priority).
+	// Element order is always preserved from input to output; without this annotation, however, two properties
+	// holding the same elements in different orders are considered equal, so that a reordering alone is not
+	// recognized as a change. With this annotation, the order participates in the hash of the property, hence a
+	// reordering is a change to the resource. A PATCH add appends the new elements at the end, in the order supplied.
+	// Inserting at a position is out of scope, since SCIM paths cannot address an element by index (RFC 7644 section
+	// 3.5.2); a replace of the whole attribute reorders it instead. It must be annotated on a multiValued property.
+	Ordered = "@Ordered"
 )
@@ -0,0 +1,73 @@
+package crud
+
+import (
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Matcher to select the best supported collation locale for a requested locale.
+var collationMatcher = language.NewMatcher(collate.Supported())
+
+// MatchCollationLocale returns the BCP 47 tag of the supported collation that best matches the requested locale,
+// and true if such match exists. The requested locale can either be a single language tag (i.e. "sv-SE"), or a
+// value in the format of the Accept-Language header (i.e. "sv-SE,sv;q=0.9,en;q=0.8").
+//
+// A match is only accepted with high or exact confidence, and when the matched collation is of the same base language
+// as a requested tag, so that an unsupported language does not silently sort by another language's rules. The returned
+// tag is the matched collation without its collation type (i.e. "de", not "de-u-co-phonebk"), unless the type is
+// explicitly requested with the "co" extension (i.e. "de-DE-u-co-phonebk") and supported. When the requested locale
+// is empty, malformed, or not supported, it returns an empty string and false.
+func MatchCollationLocale(locale string) (string, bool) {
+	if len(locale) == 0 {
+		return "", false
+	}
+
+	tags, _, err := language.ParseAcceptLanguage(locale)
+	if err != nil || len(tags) == 0 {
+		return "", false
+	}
+
+	_, index, confidence := collationMatcher.Match(tags...)
+	if confidence < language.High {
+		return "", false
+	}
+
+	matched, err := collate.Supported()[index].SetTypeForKey("co", "")
+	if err != nil {
+		return "", false
+	}
+	base, _ := matched.Base()
+
+	for _, tag := range tags {
+		if b, _ := tag.Base(); b != base {
+			continue
+		}
+		if co := tag.TypeForKey("co"); len(co) > 0 {
+			if withType, err := matched.SetTypeForKey("co", co); err == nil && isSupportedCollation(withType) {
+				matched = withType
+			}
+		}
+		return matched.String(), true
+	}
+
+	return "", false
+}
+
+// isSupportedCollation returns true if the tag is exactly one of the supported collations.
+func isSupportedCollation(tag language.Tag) bool {
+	for _, supported := range collate.Supported() {
+		if supported == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// newCollator returns a collator for the requested locale, or nil if the locale is absent or not supported.
+func newCollator(locale string) *collate.Collator {
+	tag, ok := MatchCollationLocale(locale)
+	if !ok {
+		return nil
+	}
+	return collate.New(language.Make(tag))
+}
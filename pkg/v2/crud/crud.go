@@ -5,6 +5,7 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
 )
 
 // Add value to SCIM resource at the given SCIM path. If SCIM path is empty, value will be added
@@ -64,7 +65,8 @@ func skipMainSchemaNamespace(resource *prop.Resource, query *expr.Expression) *e
 		return nil
 	}
 
-	if query.IsPath() && query.Token() == resource.ResourceType().Schema().ID() {
+	// schema id is a URN, whose comparison is case insensitive
+	if query.IsPath() && strings.EqualFold(query.Token(), resource.ResourceType().Schema().ID()) {
 		return query.Next()
 	}
 
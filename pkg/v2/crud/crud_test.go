@@ -278,6 +278,70 @@ func (s *CrudTestSuite) TestReplace() {
 				}, r.Navigator().Dot("emails").Current().Raw())
 			},
 		},
+		{
+			name: "replace nested simple property with mixed case path",
+			getResource: func(t *testing.T) *prop.Resource {
+				return prop.NewResource(s.resourceType)
+			},
+			path:  "META.Version",
+			value: "v1",
+			expect: func(t *testing.T, r *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "v1", r.Navigator().Dot("meta").Dot("version").Current().Raw())
+			},
+		},
+		{
+			name: "replace filtered property with mixed case path",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("emails").Add([]interface{}{
+					map[string]interface{}{
+						"value": "foo",
+					},
+					map[string]interface{}{
+						"value": "bar",
+					},
+				}).HasError())
+				return r
+			},
+			path:  "Emails[VALUE eq \"bar\"].Primary",
+			value: true,
+			expect: func(t *testing.T, r *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, true, r.Navigator().Dot("emails").At(1).Dot("primary").Current().Raw())
+			},
+		},
+		{
+			name: "replace property with mixed case main schema namespace",
+			getResource: func(t *testing.T) *prop.Resource {
+				return prop.NewResource(s.resourceType)
+			},
+			path: "MAIN:Emails",
+			value: []interface{}{
+				map[string]interface{}{
+					"value": "foo",
+				},
+			},
+			expect: func(t *testing.T, r *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "foo", r.Navigator().Dot("emails").At(0).Dot("value").Current().Raw())
+			},
+		},
+		{
+			name: "replace extension property with mixed case path",
+			getResource: func(t *testing.T) *prop.Resource {
+				return prop.NewResource(s.resourceType)
+			},
+			path:  "URN:IETF:params:scim:schemas:extension:TEST:2.0:test:EMPLOYEENUMBER",
+			value: "123",
+			expect: func(t *testing.T, r *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "123", r.Navigator().
+					Dot("urn:ietf:params:scim:schemas:extension:test:2.0:Test").
+					Dot("employeeNumber").
+					Current().Raw())
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -359,6 +423,33 @@ func (s *CrudTestSuite) TestDelete() {
 				}, r.Navigator().Dot("emails").Current().Raw())
 			},
 		},
+		{
+			name: "delete multiValued property elements in the middle",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("emails").Add([]interface{}{
+					map[string]interface{}{"value": "foo"},
+					map[string]interface{}{"value": "bar"},
+					map[string]interface{}{"value": "baz"},
+					map[string]interface{}{"value": "qux"},
+				}).HasError())
+				return r
+			},
+			path: `emails[value sw "b"]`,
+			expect: func(t *testing.T, r *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{
+						"value":   "foo",
+						"primary": nil,
+					},
+					map[string]interface{}{
+						"value":   "qux",
+						"primary": nil,
+					},
+				}, r.Navigator().Dot("emails").Current().Raw())
+			},
+		},
 		{
 			name: "delete multiValued property element field with filter",
 			getResource: func(t *testing.T) *prop.Resource {
@@ -462,6 +553,10 @@ func (s *CrudTestSuite) SetupSuite() {
 	require.Nil(s.T(), json.Unmarshal([]byte(testMainSchema), schema))
 	spec.Schemas().Register(schema)
 
+	extension := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testExtensionSchema), extension))
+	spec.Schemas().Register(extension)
+
 	s.resourceType = new(spec.ResourceType)
 	require.Nil(s.T(), json.Unmarshal([]byte(testResourceType), s.resourceType))
 	Register(s.resourceType)
@@ -561,6 +656,46 @@ const (
           }
         }
       ]
+    },
+    {
+      "id": "members",
+      "name": "members",
+      "type": "complex",
+      "multiValued": true,
+      "_index": 101,
+      "_path": "members",
+      "subAttributes": [
+        {
+          "id": "members.value",
+          "name": "value",
+          "type": "string",
+          "_index": 0,
+          "_path": "members.value"
+        },
+        {
+          "id": "members.$ref",
+          "name": "$ref",
+          "type": "reference",
+          "referenceTypes": ["User", "Group"],
+          "_index": 1,
+          "_path": "members.$ref"
+        }
+      ]
+    }
+  ]
+}
+`
+	testExtensionSchema = `
+{
+  "id": "urn:ietf:params:scim:schemas:extension:test:2.0:Test",
+  "name": "extension",
+  "attributes": [
+    {
+      "id": "urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber",
+      "name": "employeeNumber",
+      "type": "string",
+      "_index": 0,
+      "_path": "urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber"
     }
   ]
 }
@@ -569,7 +704,13 @@ const (
 {
   "id": "Test",
   "name": "Test",
-  "schema": "main"
+  "schema": "main",
+  "schemaExtensions": [
+    {
+      "schema": "urn:ietf:params:scim:schemas:extension:test:2.0:Test",
+      "required": false
+    }
+  ]
 }
 `
 )
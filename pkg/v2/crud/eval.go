@@ -3,6 +3,7 @@ package crud
 import (
 	"errors"
 	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/annotation"
 	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
@@ -16,15 +17,23 @@ func Evaluate(resource *prop.Resource, filter string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	return EvaluateCompiled(resource, cf)
+}
+
+// EvaluateCompiled evaluates the resource with the SCIM filter compiled by expr.CompileFilter, and returns the boolean
+// result or an error. It saves the cost of compiling the same filter again when evaluating many resources.
+func EvaluateCompiled(resource *prop.Resource, filter *expr.Expression) (bool, error) {
 	return evaluator{
-		base:   resource.RootProperty(),
-		filter: cf,
+		base:         resource.RootProperty(),
+		filter:       filter,
+		resourceType: resource.ResourceType(),
 	}.evaluate()
 }
 
 type evaluator struct {
-	base   prop.Property
-	filter *expr.Expression
+	base         prop.Property
+	filter       *expr.Expression
+	resourceType *spec.ResourceType // resolves the paths on the root; nil when evaluating element filters
 }
 
 func (v evaluator) evaluate() (bool, error) {
@@ -41,10 +50,17 @@ func (v evaluator) evalAny(p prop.Property, op *expr.Expression) (bool, error) {
 		return v.evalNot(p, op)
 	}
 
+	// a value path (i.e. emails[type eq "work"]) is a predicate by itself
+	if op.IsPath() {
+		return v.evalValuePath(p, op)
+	}
+
 	if op.Left().ContainsFilter() {
 		return false, fmt.Errorf("%w: nested filter detected", spec.ErrInvalidFilter)
 	}
 
+	base, path := v.resolvePath(p, op.Left())
+
 	// Normally, we are expecting a single boolean result. For instance, conventional filters like
 	//
 	//		userName eq "imulab"
@@ -75,9 +91,15 @@ func (v evaluator) evalAny(p prop.Property, op *expr.Expression) (bool, error) {
 	// This filter leads to two comparisons of "user1@foo.com" sw "user1", and "user2@foo.com" sw "user1" respectively,
 	// which produces "true" and "false". As a result, this resource should pass the filter.
 	var results = make([]bool, 0)
-	if err := defaultTraverse(p, op.Left(), func(nav prop.Navigator) (fe error) {
+	if err := defaultTraverse(base, path, func(nav prop.Navigator) (fe error) {
 		var r bool
 
+		if isSchemas(nav.Current().Attribute()) {
+			r, fe = v.evalSchemas(nav.Current(), op)
+			results = append(results, r)
+			return
+		}
+
 		switch op.Token() {
 		case expr.Eq:
 			r, fe = v.evalEq(nav.Current(), op)
@@ -106,16 +128,7 @@ func (v evaluator) evalAny(p prop.Property, op *expr.Expression) (bool, error) {
 		results = append(results, r)
 		return
 	}); err != nil {
-		switch errors.Unwrap(err) {
-		case spec.ErrInvalidFilter:
-			return false, err
-		case spec.ErrInvalidPath, spec.ErrNoTarget:
-			return false, fmt.Errorf("%w: bad path in filter", spec.ErrInvalidFilter)
-		case spec.ErrInvalidValue:
-			return false, fmt.Errorf("%w: bad value in filter", spec.ErrInvalidFilter)
-		default:
-			return false, fmt.Errorf("%w: failed to evaluate resource", spec.ErrInvalidFilter)
-		}
+		return false, v.errEval(err)
 	}
 
 	for _, r := range results {
@@ -126,6 +139,135 @@ func (v evaluator) evalAny(p prop.Property, op *expr.Expression) (bool, error) {
 	return false, nil
 }
 
+// Evaluate the value path, which is a path whose last step is attached with a value filter. For instance,
+//
+//	emails[type eq "work" and value ew "@foo.com"]
+//
+// The value path evaluates to true if at least one element of the multiValued property satisfies the value filter
+// as a whole. This is different from the filter (emails.type eq "work" and emails.value ew "@foo.com"), in which the
+// two predicates can be satisfied by different elements.
+func (v evaluator) evalValuePath(p prop.Property, valuePath *expr.Expression) (bool, error) {
+	base, path := v.resolvePath(p, valuePath)
+
+	var found bool
+	if err := defaultTraverse(base, path, func(_ prop.Navigator) error {
+		found = true
+		return nil
+	}); err != nil {
+		return false, v.errEval(err)
+	}
+	return found, nil
+}
+
+// Returns the property to start the traversal from, and the path to traverse. Only the root attribute carries the main
+// schema id, which may prefix the path and is trimmed. On the root, a path whose first step names no attribute of the
+// core or main schema is resolved by the resource type (see spec.ResourceType.AttributeByPath), so that the short form
+// of an extension attribute (i.e. department) addresses the same attribute as the full form (i.e.
+// urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department): the traversal starts from the container of
+// the schema extension defining it.
+func (v evaluator) resolvePath(p prop.Property, path *expr.Expression) (prop.Property, *expr.Expression) {
+	if _, ok := p.Attribute().Annotation(annotation.Root); !ok || !path.IsPath() {
+		return p, path
+	}
+
+	if strings.EqualFold(path.Token(), p.Attribute().ID()) {
+		return p, path.Next()
+	}
+
+	if p.Attribute().SubAttributeForName(path.Token()) != nil || v.resourceType == nil {
+		return p, path
+	}
+
+	if attr, ok := v.resourceType.AttributeByPath(path.Token()); ok {
+		if extension := p.FindChild(func(child prop.Property) bool {
+			return child.Attribute().SubAttributeForName(path.Token()) == attr
+		}); extension != nil {
+			return extension, path
+		}
+	}
+	return p, path
+}
+
+// Evaluate the filter on the "schemas" attribute. The schemas attribute has multiValued string semantics: the filter
+// evaluates to true if any of the schema URIs satisfies the filter (or, in case of ne, none of the schema URIs equals to
+// the value). Because schema URIs are case insensitive, the comparison is always made as if caseExact were false.
+func (v evaluator) evalSchemas(target prop.Property, op *expr.Expression) (bool, error) {
+	if op.Token() == expr.Pr {
+		return v.evalPr(target)
+	}
+
+	value, err := v.normalize(target.Attribute(), op.Right().Token())
+	if err != nil {
+		return false, err
+	}
+	literal := strings.ToLower(value.(string))
+
+	var satisfies func(uri string) bool
+	switch op.Token() {
+	case expr.Eq, expr.Ne:
+		satisfies = func(uri string) bool { return uri == literal }
+	case expr.Sw:
+		satisfies = func(uri string) bool { return strings.HasPrefix(uri, literal) }
+	case expr.Ew:
+		satisfies = func(uri string) bool { return strings.HasSuffix(uri, literal) }
+	case expr.Co:
+		satisfies = func(uri string) bool { return strings.Contains(uri, literal) }
+	default:
+		return false, fmt.Errorf("%w: operator '%s' is not supported on schemas", spec.ErrInvalidFilter, op.Token())
+	}
+
+	var found bool
+	for _, uri := range schemaURIs(target) {
+		if satisfies(strings.ToLower(uri)) {
+			found = true
+			break
+		}
+	}
+
+	if op.Token() == expr.Ne {
+		return !found, nil
+	}
+	return found, nil
+}
+
+// Returns true if the attribute is the "schemas" attribute, or its element.
+func isSchemas(attr *spec.Attribute) bool {
+	return attr.Path() == "schemas"
+}
+
+// Returns the schema URIs in the "schemas" property, or its element.
+func schemaURIs(property prop.Property) []string {
+	if !property.Attribute().MultiValued() {
+		if uri, ok := property.Raw().(string); ok {
+			return []string{uri}
+		}
+		return nil
+	}
+
+	uris := make([]string, 0, property.CountChildren())
+	_ = property.ForEachChild(func(_ int, child prop.Property) error {
+		if uri, ok := child.Raw().(string); ok {
+			uris = append(uris, uri)
+		}
+		return nil
+	})
+	return uris
+}
+
+// Convert the error occurred during evaluation to a spec.ErrInvalidFilter error.
+func (v evaluator) errEval(err error) error {
+	switch errors.Unwrap(err) {
+	case spec.ErrInvalidFilter:
+		return err
+	case spec.ErrInvalidPath, spec.ErrNoTarget:
+		return fmt.Errorf("%w: bad path in filter", spec.ErrInvalidFilter)
+	case spec.ErrInvalidValue:
+		return fmt.Errorf("%w: bad value in filter", spec.ErrInvalidFilter)
+	default:
+		return fmt.Errorf("%w: failed to evaluate resource", spec.ErrInvalidFilter)
+	}
+}
+
 func (v evaluator) evalEq(target prop.Property, eq *expr.Expression) (bool, error) {
 	eqTarget, ok := target.(prop.EqCapable)
 	if !ok {
@@ -287,6 +429,10 @@ func (v evaluator) evalOr(p prop.Property, or *expr.Expression) (bool, error) {
 	}
 }
 
+// Negates the result of the operand. When the operand is a value path (i.e. not (emails[type eq "work"])), the
+// negation is applied outside: the result is true when no element satisfies the value filter, which is different from
+// negating the relation inside (i.e. emails[type ne "work"]), whose result is true when any element does not satisfy
+// the relation.
 func (v evaluator) evalNot(p prop.Property, not *expr.Expression) (bool, error) {
 	if left, err := v.evalAny(p, not.Left()); err != nil {
 		return false, err
@@ -2,6 +2,7 @@ package crud
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
@@ -29,6 +30,134 @@ func (s *EvaluateTestSuite) TestEvaluate() {
 		filter      string
 		expect      func(t *testing.T, result bool, err error)
 	}{
+		{
+			name:        `[schemas eq "URN:...:Test"] evaluates to true against {"schemas":[core, extension]} ignoring case`,
+			getResource: s.schemas,
+			filter:      `schemas eq "URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:TEST:2.0:Test"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[schemas eq "...:Other"] evaluates to false against {"schemas":[core, extension]}`,
+			getResource: s.schemas,
+			filter:      `schemas eq "urn:ietf:params:scim:schemas:extension:other:2.0:Other"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[schemas co "extension:test"] evaluates to true against {"schemas":[core, extension]}`,
+			getResource: s.schemas,
+			filter:      `schemas co "EXTENSION:test"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[schemas co "extension:other"] evaluates to false against {"schemas":[core, extension]}`,
+			getResource: s.schemas,
+			filter:      `schemas co "extension:other"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[schemas sw "urn:ietf"] evaluates to true against {"schemas":[core, extension]}`,
+			getResource: s.schemas,
+			filter:      `schemas sw "urn:ietf"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[schemas ne "core"] evaluates to false against {"schemas":[core, extension]}`,
+			getResource: s.schemas,
+			filter:      `schemas ne "CORE"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[schemas gt "core"] is an invalid filter`,
+			getResource: s.schemas,
+			filter:      `schemas gt "core"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidFilter, errors.Unwrap(err))
+			},
+		},
+		{
+			name:        `[urn:...:Test:employeeNumber eq "E123"] evaluates to true against extension {"employeeNumber":"E123"}`,
+			getResource: s.extension,
+			filter:      `urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber eq "E123"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[URN:...:TEST:employeeNumber co "12"] evaluates to true against extension {"employeeNumber":"E123"}`,
+			getResource: s.extension,
+			filter:      `URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:TEST:2.0:TEST:employeeNumber co "12"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[urn:...:Test:employeeNumber co "45"] evaluates to false against extension {"employeeNumber":"E123"}`,
+			getResource: s.extension,
+			filter:      `urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber co "45"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[urn:...:Test:employeeNumber pr] evaluates to true against extension {"employeeNumber":"E123"}`,
+			getResource: s.extension,
+			filter:      `urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber pr`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name: `[urn:...:Test:employeeNumber pr] evaluates to false against {}`,
+			getResource: func(t *testing.T) *prop.Resource {
+				return prop.NewResource(s.resourceType)
+			},
+			filter: `urn:ietf:params:scim:schemas:extension:test:2.0:Test:employeeNumber pr`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[employeeNumber eq "E123"] short form evaluates to true against extension {"employeeNumber":"E123"}`,
+			getResource: s.extension,
+			filter:      `employeeNumber eq "E123"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[main:id eq "foobar"] evaluates to true against {"id":"foobar"} with main schema prefix`,
+			getResource: s.extension,
+			filter:      `main:id eq "foobar"`,
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
 		{
 			name: `[id eq "foobar"] evaluates to true against {"id":"foobar"}`,
 			getResource: func(t *testing.T) *prop.Resource {
@@ -214,6 +343,188 @@ func (s *EvaluateTestSuite) TestEvaluate() {
 				assert.False(t, result)
 			},
 		},
+		{
+			name: `[ID eq "foobar"] evaluates to true against {"id":"foobar"}`,
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("id").Replace("foobar").HasError())
+				return r
+			},
+			filter: fmt.Sprintf("ID eq %s", strconv.Quote("foobar")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name: `[Meta.VERSION eq "v1"] evaluates to true against {"meta":{"version":"v1"}}`,
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("meta").Dot("version").Replace("v1").HasError())
+				return r
+			},
+			filter: fmt.Sprintf("Meta.VERSION eq %s", strconv.Quote("v1")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name: `[MAIN:Emails.Value eq "bar"] evaluates to true against {"emails": [{"value": "bar"}]}`,
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("emails").Replace([]interface{}{
+					map[string]interface{}{"value": "bar"},
+				}).HasError())
+				return r
+			},
+			filter: fmt.Sprintf("MAIN:Emails.Value eq %s", strconv.Quote("bar")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name: `[urn:ietf:params:scim:schemas:extension:TEST:2.0:test:EmployeeNumber eq "123"] evaluates to true against extension value "123"`,
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().
+					Dot("urn:ietf:params:scim:schemas:extension:test:2.0:Test").
+					Dot("employeeNumber").
+					Replace("123").HasError())
+				return r
+			},
+			filter: fmt.Sprintf("urn:ietf:params:scim:schemas:extension:TEST:2.0:test:EmployeeNumber eq %s", strconv.Quote("123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref eq "/Users/123"]] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref eq %s]", strconv.Quote("/Users/123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref eq "/Users/456"]] evaluates to false against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref eq %s]", strconv.Quote("/Users/456")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref ew "/456"]] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref ew %s]", strconv.Quote("/456")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref co "Groups"]] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref co %s]", strconv.Quote("Groups")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref co "Users" and value eq "456"]] evaluates to false against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref co %s and value eq %s]", strconv.Quote("Users"), strconv.Quote("456")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[members.$ref co "Users" and members.value eq "456"] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members.$ref co %s and members.value eq %s", strconv.Quote("Users"), strconv.Quote("456")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[(members[$ref sw "/Groups"]) and members.value pr] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("(members[$ref sw %s]) and members.value pr", strconv.Quote("/Groups")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[MAIN:members[$ref ew "/123"]] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("MAIN:members[$ref ew %s]", strconv.Quote("/123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[not (members[$ref eq "/Users/123"])] evaluates to false against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("not (members[$ref eq %s])", strconv.Quote("/Users/123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref ne "/Users/123"]] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref ne %s]", strconv.Quote("/Users/123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[not (members[$ref eq "/Users/999"]) and members.value pr] evaluates to true against {"members": [{"value": "123", "$ref": "/Users/123"}, {"value": "456", "$ref": "/Groups/456"}]}`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("not (members[$ref eq %s]) and members.value pr", strconv.Quote("/Users/999")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[not (members[$ref eq "/Users/123"]) and id eq "foobar"] evaluates to true against {"id":"foobar"}`,
+			getResource: s.extension,
+			filter:      fmt.Sprintf("not (members[$ref eq %s]) and id eq %s", strconv.Quote("/Users/123"), strconv.Quote("foobar")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.True(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref ne "/Users/123"] and id eq "foobar"] evaluates to false against {"id":"foobar"}`,
+			getResource: s.extension,
+			filter:      fmt.Sprintf("members[$ref ne %s] and id eq %s", strconv.Quote("/Users/123"), strconv.Quote("foobar")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.Nil(t, err)
+				assert.False(t, result)
+			},
+		},
+		{
+			name:        `[members[$ref eq "/Users/123"].value eq "123"] is an error`,
+			getResource: s.members,
+			filter:      fmt.Sprintf("members[$ref eq %s].value eq %s", strconv.Quote("/Users/123"), strconv.Quote("123")),
+			expect: func(t *testing.T, result bool, err error) {
+				assert.NotNil(t, err)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -225,6 +536,35 @@ func (s *EvaluateTestSuite) TestEvaluate() {
 	}
 }
 
+func (s *EvaluateTestSuite) schemas(t *testing.T) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.False(t, r.Navigator().Dot("schemas").Replace([]interface{}{
+		"core",
+		"urn:ietf:params:scim:schemas:extension:test:2.0:Test",
+	}).HasError())
+	return r
+}
+
+func (s *EvaluateTestSuite) extension(t *testing.T) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.False(t, r.Navigator().Replace(map[string]interface{}{
+		"id": "foobar",
+		"urn:ietf:params:scim:schemas:extension:test:2.0:Test": map[string]interface{}{
+			"employeeNumber": "E123",
+		},
+	}).HasError())
+	return r
+}
+
+func (s *EvaluateTestSuite) members(t *testing.T) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.False(t, r.Navigator().Dot("members").Replace([]interface{}{
+		map[string]interface{}{"value": "123", "$ref": "/Users/123"},
+		map[string]interface{}{"value": "456", "$ref": "/Groups/456"},
+	}).HasError())
+	return r
+}
+
 // Prepares a core schema with 'schemas', 'id', 'meta'('version', 'location') attributes, and a main schema
 // with 'emails'('value', 'primary') attributes. Aggregate the two schemas in the test resource type.
 func (s *EvaluateTestSuite) SetupSuite() {
@@ -236,6 +576,10 @@ func (s *EvaluateTestSuite) SetupSuite() {
 	require.Nil(s.T(), json.Unmarshal([]byte(testMainSchema), schema))
 	spec.Schemas().Register(schema)
 
+	extension := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testExtensionSchema), extension))
+	spec.Schemas().Register(extension)
+
 	s.resourceType = new(spec.ResourceType)
 	require.Nil(s.T(), json.Unmarshal([]byte(testResourceType), s.resourceType))
 	Register(s.resourceType)
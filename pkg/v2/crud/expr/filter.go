@@ -19,6 +19,16 @@ import (
 //	                     /  \
 //	                primary true
 //
+// Value paths are compiled as the head of the linked list, with the value filter attached to the multiValued attribute
+// step, just like CompilePath. Hence, for a filter such as:
+//	emails[type eq "work"] and active eq true
+// CompileFilter will return an abstract syntax tree in the structure of:
+//	               and
+//	             /     \
+//	     emails -> eq    eq
+//	              / \    / \
+//	          type "work" active true
+//
 func CompileFilter(filter string) (*Expression, error) {
 	compiler := &filterCompiler{
 		scan:    &filterScanner{},
@@ -99,7 +109,7 @@ func CompileFilter(filter string) (*Expression, error) {
 	}
 
 	// assertion check
-	if len(compiler.rsStack) != 1 || !compiler.rsStack[0].IsOperator() {
+	if len(compiler.rsStack) != 1 || !(compiler.rsStack[0].IsOperator() || compiler.rsStack[0].ContainsFilter()) {
 		panic("flaw in algorithm")
 	}
 
@@ -210,13 +220,17 @@ func (c *filterCompiler) pushBuildResult(step *Expression) error {
 		return nil
 	}
 
-	// Path: re-compile and push
+	// Path: re-compile and push. A path containing filter is a value path, which is a
+	// complete predicate by itself: the filter must be at the end of the path.
 	if step.IsPath() {
 		head, err := CompilePath(step.token)
 		if err != nil {
 			return fmt.Errorf("%w: invalid path in filter", spec.ErrInvalidFilter)
-		} else if head.ContainsFilter() {
-			return fmt.Errorf("%w: illegal nested filter", spec.ErrInvalidFilter)
+		}
+		for cursor := head; cursor != nil; cursor = cursor.next {
+			if cursor.IsRootOfFilter() && cursor.next != nil {
+				return fmt.Errorf("%w: illegal nested filter", spec.ErrInvalidFilter)
+			}
 		}
 		c.rsStack = append(c.rsStack, head)
 		return nil
@@ -521,9 +535,9 @@ func (fs *filterScanner) stateN(scan *filterScanner, c byte) int {
 	}
 
 	// just a path
-	if c == '.' || c == ':' || isNonFirstAlphabet(c) {
+	if c == '[' || isPathCharacter(c) {
 		scan.step = fs.stateInPath
-		return scanFilterContinue
+		return fs.stateInPath(scan, c)
 	}
 
 	return fs.error(c, "invalid character in path")
@@ -540,9 +554,9 @@ func (fs *filterScanner) stateNo(scan *filterScanner, c byte) int {
 	}
 
 	// just a path
-	if c == '.' || c == ':' || isNonFirstAlphabet(c) {
+	if c == '[' || isPathCharacter(c) {
 		scan.step = fs.stateInPath
-		return scanFilterContinue
+		return fs.stateInPath(scan, c)
 	}
 
 	return fs.error(c, "invalid character in path")
@@ -564,29 +578,92 @@ func (fs *filterScanner) stateNot(scan *filterScanner, c byte) int {
 	}
 
 	// seem like just a path that starts with 'not' (i.e. notes.title)
-	if c == '.' || c == ':' || isNonFirstAlphabet(c) {
+	if c == '[' || isPathCharacter(c) {
 		scan.step = fs.stateInPath
-		return scanFilterContinue
+		return fs.stateInPath(scan, c)
 	}
 
 	return fs.error(c, "invalid character in path")
 }
 
 // Intermediate state where we are inside an attribute path name. A space character would end the path name and start
-// an operator.
+// an operator; a left bracket would start a value filter, turning the path into a value path (i.e. emails[type eq "work"]).
 func (fs *filterScanner) stateInPath(scan *filterScanner, c byte) int {
 	if c == ' ' {
 		scan.step = fs.stateBeginOp
 		return scanFilterEndPath
 	}
 
-	if c == '.' || c == ':' || isNonFirstAlphabet(c) {
+	if c == '[' {
+		scan.step = fs.stateInValueFilter
+		return scanFilterContinue
+	}
+
+	if isPathCharacter(c) {
 		return scanFilterContinue
 	}
 
 	return fs.error(c, "invalid character in path")
 }
 
+// Intermediate state where we are inside the value filter of a value path. The value filter is considered to be part
+// of the path and will be compiled along with it, hence we only skip through everything to look for the end of the
+// value filter (']'), while carefully skipping string literals which can entail a literal ending bracket.
+func (fs *filterScanner) stateInValueFilter(scan *filterScanner, c byte) int {
+	switch c {
+	case '"':
+		scan.step = fs.stateInValueFilterString
+		return scanFilterContinue
+	case ']':
+		scan.step = fs.stateEndValuePath
+		return scanFilterContinue
+	case 0:
+		return fs.error(c, "unterminated value filter")
+	}
+	return scanFilterContinue
+}
+
+// Intermediate state where we are inside a string literal of the value filter. Another double quote shall end the string
+// and return us to the value filter state. The character after an escape character is skipped regardless, as the actual
+// validation of the string literal is carried out when the value filter is compiled.
+func (fs *filterScanner) stateInValueFilterString(scan *filterScanner, c byte) int {
+	switch c {
+	case '\\':
+		scan.step = fs.stateInValueFilterStringEsc
+	case '"':
+		scan.step = fs.stateInValueFilter
+	case 0:
+		return fs.error(c, "unterminated string literal in value filter")
+	}
+	return scanFilterContinue
+}
+
+// Intermediate state where we are just after an escape character in a string literal of the value filter.
+func (fs *filterScanner) stateInValueFilterStringEsc(scan *filterScanner, c byte) int {
+	if c == 0 {
+		return fs.error(c, "unterminated string literal in value filter")
+	}
+	scan.step = fs.stateInValueFilterString
+	return scanFilterContinue
+}
+
+// Intermediate state where the value filter of a value path has ended. A value path is a complete predicate, hence
+// it could only be followed by a space, a right parenthesis or the termination byte, all of which end the predicate.
+func (fs *filterScanner) stateEndValuePath(scan *filterScanner, c byte) int {
+	switch c {
+	case ' ':
+		scan.step = fs.stateEndPredicate
+		return scanFilterEndPath
+	case ')':
+		// ask caller to replay with a space so we can end the path first
+		return scanFilterInsertSpace
+	case 0:
+		scan.step = fs.stateEof
+		return scanFilterEndPath
+	}
+	return fs.error(c, "invalid character after value path")
+}
+
 // Intermediate state at the beginning of an operator defined by SCIM query protocol.
 func (fs *filterScanner) stateBeginOp(scan *filterScanner, c byte) int {
 	if c == ' ' {
@@ -97,6 +97,24 @@ func (s *FilterTestSuite) TestFilterCompiler() {
 				assert.Equal(t, literal, trail[4].typ)
 			},
 		},
+		{
+			name:   "simple filter with unregistered urn prefix",
+			filter: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department eq \"Sales\"",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 4)
+
+				assert.Equal(t, Eq, trail[0].value)
+				assert.Equal(t, "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User", trail[1].value)
+				assert.Equal(t, "department", trail[2].value)
+				assert.Equal(t, "\"Sales\"", trail[3].value)
+
+				assert.Equal(t, operator, trail[0].typ)
+				assert.Equal(t, step, trail[1].typ)
+				assert.Equal(t, step, trail[2].typ)
+				assert.Equal(t, literal, trail[3].typ)
+			},
+		},
 		{
 			name:   "filter starts with not operator",
 			filter: "not (name pr)",
@@ -137,6 +155,59 @@ func (s *FilterTestSuite) TestFilterCompiler() {
 				assert.Equal(t, literal, trail[6].typ)
 			},
 		},
+		{
+			name:   "value path with $ref",
+			filter: "members[$ref ew \"/Users/123\"]",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 4)
+
+				assert.Equal(t, "members", trail[0].value)
+				assert.Equal(t, Ew, trail[1].value)
+				assert.Equal(t, "$ref", trail[2].value)
+				assert.Equal(t, "\"/Users/123\"", trail[3].value)
+
+				assert.Equal(t, step, trail[0].typ)
+				assert.Equal(t, operator, trail[1].typ)
+				assert.Equal(t, step, trail[2].typ)
+				assert.Equal(t, literal, trail[3].typ)
+			},
+		},
+		{
+			name:   "value path in composite filter",
+			filter: "(members[value eq \"]\" and $ref co \"Users\"]) or members.$ref pr",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 12)
+
+				assert.Equal(t, Or, trail[0].value)
+				assert.Equal(t, "members", trail[1].value)
+				assert.Equal(t, And, trail[2].value)
+				assert.Equal(t, Eq, trail[3].value)
+				assert.Equal(t, "value", trail[4].value)
+				assert.Equal(t, "\"]\"", trail[5].value)
+				assert.Equal(t, Co, trail[6].value)
+				assert.Equal(t, "$ref", trail[7].value)
+				assert.Equal(t, "\"Users\"", trail[8].value)
+				assert.Equal(t, Pr, trail[9].value)
+				assert.Equal(t, "members", trail[10].value)
+				assert.Equal(t, "$ref", trail[11].value)
+			},
+		},
+		{
+			name:   "invalid filter: value path followed by sub attribute",
+			filter: "emails[value eq \"foo\"].primary eq true",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.NotNil(t, err)
+			},
+		},
+		{
+			name:   "invalid filter: unterminated value path",
+			filter: "emails[value eq \"foo\"",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.NotNil(t, err)
+			},
+		},
 		{
 			name:   "invalid filter: starts with literal",
 			filter: "\"hello\" eq false",
@@ -313,6 +384,15 @@ func (s *FilterTestSuite) TestFilterScanner() {
 				{scanFilterError, 4},
 			},
 		},
+		{
+			name:   "value path",
+			filter: "members[$ref eq \"x\"]",
+			expect: []signals{
+				{scanFilterBeginPath, 1},
+				{scanFilterContinue, 19},
+				{scanFilterEndPath, 1},
+			},
+		},
 		{
 			name:   "single cardinality",
 			filter: "id pr",
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"strconv"
+	"strings"
 )
 
 // CompilePath compiles the given SCIM path expression and returns the head of the path expression linked list, or any error.
@@ -21,7 +22,53 @@ import (
 //	            /  \
 //	         value  "foo@bar.com"
 //
+// A path may be prefixed by a schema URN (i.e. urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department),
+// which becomes a single step. URNs registered with RegisterURN are always recognized. URNs not registered are only
+// recognized when followed by an attribute name, as the URN is then taken to span until the last colon before any filter.
 func CompilePath(path string) (*Expression, error) {
+	head, err := compilePath(path)
+	if err != nil {
+		if urnHead, ok := compileUnregisteredURNPath(path); ok {
+			return urnHead, nil
+		}
+	}
+	return head, err
+}
+
+// Compiles a path prefixed by a schema URN not registered with RegisterURN, by splitting the URN at the last colon
+// before any filter, since attribute names cannot contain colons. Returns false if the path cannot be compiled this way.
+func compileUnregisteredURNPath(raw string) (*Expression, bool) {
+	if len(raw) < 4 || !strings.EqualFold(raw[:4], "urn:") {
+		return nil, false
+	}
+
+	end := len(raw)
+	if i := strings.IndexByte(raw, '['); i >= 0 {
+		end = i
+	}
+	i := strings.LastIndexByte(raw[:end], ':')
+	if i <= len("urn:") {
+		return nil, false
+	}
+	for j := 0; j < i; j++ {
+		if !isPathCharacter(raw[j]) {
+			return nil, false
+		}
+	}
+
+	next, err := compilePath(raw[i+1:])
+	if err != nil || next == nil {
+		return nil, false
+	}
+
+	return &Expression{
+		token: raw[:i],
+		typ:   path,
+		next:  next,
+	}, true
+}
+
+func compilePath(path string) (*Expression, error) {
 	compiler := &pathCompiler{
 		scan: &pathScanner{},
 		data: append(copyOf(path), 0, 0),
@@ -403,6 +450,12 @@ func isFirstAlphabet(c byte) bool {
 	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '$'
 }
 
+// Returns true if the byte can be a non-first character of a SCIM attribute path in filters. This includes the path
+// separators and the dollar sign, which starts the special sub attribute names like "$ref".
+func isPathCharacter(c byte) bool {
+	return c == '.' || c == ':' || c == '$' || isNonFirstAlphabet(c)
+}
+
 // Returns true if the byte can be the non-first alphabet of a SCIM attribute name.
 func isNonFirstAlphabet(c byte) bool {
 	return ('a' <= c && c <= 'z') ||
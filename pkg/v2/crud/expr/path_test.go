@@ -67,6 +67,20 @@ func (s *PathTestSuite) TestPathCompiler() {
 				assert.Equal(t, step, trail[1].typ)
 			},
 		},
+		{
+			name: "path with mixed case urn namespace",
+			path: "URN:IETF:params:scim:schemas:CORE:2.0:user:Emails.PRIMARY",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 3)
+				assert.Equal(t, "URN:IETF:params:scim:schemas:CORE:2.0:user", trail[0].value)
+				assert.Equal(t, "Emails", trail[1].value)
+				assert.Equal(t, "PRIMARY", trail[2].value)
+				assert.Equal(t, step, trail[0].typ)
+				assert.Equal(t, step, trail[1].typ)
+				assert.Equal(t, step, trail[2].typ)
+			},
+		},
 		{
 			name: "path with urn namespace",
 			path: "urn:ietf:params:scim:schemas:core:2.0:User:emails.primary",
@@ -81,6 +95,41 @@ func (s *PathTestSuite) TestPathCompiler() {
 				assert.Equal(t, step, trail[2].typ)
 			},
 		},
+		{
+			name: "path with unregistered urn namespace",
+			path: "urn:ietf:params:scim:schemas:extension:unregistered:2.0:User:manager.value",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 3)
+				assert.Equal(t, "urn:ietf:params:scim:schemas:extension:unregistered:2.0:User", trail[0].value)
+				assert.Equal(t, "manager", trail[1].value)
+				assert.Equal(t, "value", trail[2].value)
+				assert.Equal(t, step, trail[0].typ)
+				assert.Equal(t, step, trail[1].typ)
+				assert.Equal(t, step, trail[2].typ)
+			},
+		},
+		{
+			name: "path with unregistered urn namespace and filter",
+			path: "urn:ietf:params:scim:schemas:extension:unregistered:2.0:User:emails[value co \":\"]",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.Nil(t, err)
+				assert.Len(t, trail, 5)
+				assert.Equal(t, "urn:ietf:params:scim:schemas:extension:unregistered:2.0:User", trail[0].value)
+				assert.Equal(t, "emails", trail[1].value)
+				assert.Equal(t, Co, trail[2].value)
+				assert.Equal(t, step, trail[0].typ)
+				assert.Equal(t, step, trail[1].typ)
+				assert.Equal(t, operator, trail[2].typ)
+			},
+		},
+		{
+			name: "unregistered urn namespace without attribute",
+			path: "urn:ietf:params:scim:schemas:extension:unregistered:2.0:User:",
+			assert: func(t *testing.T, trail []expect, err error) {
+				assert.NotNil(t, err)
+			},
+		},
 		{
 			name: "simple path with filter",
 			path: "emails[primary eq true]",
@@ -0,0 +1,95 @@
+package expr
+
+import (
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+)
+
+// CompileFilterFor compiles the given SCIM filter like CompileFilter, and additionally validates that every attribute
+// path referenced in the filter is defined by the resource type. Paths are resolved by spec.ResourceType.AttributeByPath,
+// in the same way as they are evaluated: they may be prefixed by the main schema id or an extension schema id, and
+// attributes of a schema extension may be referenced by the short form (i.e. department) as long as no other schema
+// extension defines the same attribute. Paths in a value filter (i.e. emails[type eq "work"]) are resolved against the
+// sub attributes of the value path.
+//
+// A filter referencing an undefined attribute is rejected with spec.ErrInvalidFilter naming the attribute, before any
+// evaluation takes place. When resourceType is nil, only the syntax is checked, the same as CompileFilter.
+func CompileFilterFor(filter string, resourceType *spec.ResourceType) (*Expression, error) {
+	root, err := CompileFilter(filter)
+	if err != nil || resourceType == nil {
+		return root, err
+	}
+	if err := validateFilter(root, resourceType, ""); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// validateFilter validates the paths in the filter against the resource type. The prefix is the path of the attribute
+// a value filter is attached to, which prefixes the paths in the value filter; it is empty for the top level filter.
+func validateFilter(filter *Expression, resourceType *spec.ResourceType, prefix string) error {
+	switch {
+	case filter.IsLogicalOperator():
+		if err := validateFilter(filter.Left(), resourceType, prefix); err != nil {
+			return err
+		}
+		if filter.Right() != nil {
+			return validateFilter(filter.Right(), resourceType, prefix)
+		}
+		return nil
+	case filter.IsRelationalOperator():
+		return validatePath(filter.Left(), resourceType, prefix)
+	case filter.IsPath():
+		return validatePath(filter, resourceType, prefix)
+	default:
+		return nil
+	}
+}
+
+// validatePath validates every step of the path against the resource type. Schema URN steps are validated along with
+// the attribute following them. If the path carries a value filter, the value filter is validated against the
+// attribute the filter is attached to.
+func validatePath(path *Expression, resourceType *spec.ResourceType, prefix string) error {
+	var last *Expression
+	for step := path; step != nil; step = step.Next() {
+		if step.IsRootOfFilter() {
+			return validateFilter(step, resourceType, prefixedPath(prefix, pathUntil(path, last)))
+		}
+		last = step
+		if strings.Contains(step.Token(), ":") && step.Next() != nil && !step.Next().IsRootOfFilter() {
+			continue
+		}
+		full := prefixedPath(prefix, pathUntil(path, step))
+		if _, ok := resourceType.AttributeByPath(full); !ok {
+			return fmt.Errorf("%w: attribute '%s' is not defined", spec.ErrInvalidFilter, full)
+		}
+	}
+	return nil
+}
+
+// prefixedPath returns the path prefixed by the path of the attribute a value filter is attached to, if any.
+func prefixedPath(prefix string, path string) string {
+	if len(prefix) == 0 {
+		return path
+	}
+	return prefix + "." + path
+}
+
+// pathUntil returns the string representation of the path from its head to the last step, inclusive. A schema URN step
+// is delimited from the next step by a colon, other steps by a period.
+func pathUntil(head *Expression, last *Expression) string {
+	var sb strings.Builder
+	for step := head; step != nil; step = step.Next() {
+		_, _ = sb.WriteString(step.Token())
+		if step == last {
+			break
+		}
+		if strings.Contains(step.Token(), ":") {
+			_ = sb.WriteByte(':')
+		} else {
+			_ = sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
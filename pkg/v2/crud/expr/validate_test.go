@@ -0,0 +1,166 @@
+package expr
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCompileFilterFor(t *testing.T) {
+	s := new(CompileFilterForTestSuite)
+	suite.Run(t, s)
+}
+
+type CompileFilterForTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *CompileFilterForTestSuite) TestCompileFilterFor() {
+	tests := []struct {
+		name      string
+		filter    string
+		expectErr error
+		expectMsg string
+	}{
+		{
+			name:   "defined attributes",
+			filter: `userName eq "foo" and name.familyName sw "F" or emails.value ew "@foo.com" and not (meta.version pr)`,
+		},
+		{
+			name:   "value filter",
+			filter: `emails[type eq "work" and value co "@"]`,
+		},
+		{
+			name:   "schema id qualified attribute",
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:name.givenName eq "foo"`,
+		},
+		{
+			name:   "extension attribute",
+			filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value eq "foo"`,
+		},
+		{
+			name:   "short form extension attribute",
+			filter: `department eq "foo"`,
+		},
+		{
+			name:   "short form extension sub attribute",
+			filter: `manager.value eq "foo"`,
+		},
+		{
+			name:   "extension attribute in value filter",
+			filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager[value eq "foo"]`,
+		},
+		{
+			name:      "unknown sub attribute in value filter",
+			filter:    `emails[type eq "work" or display.foo pr]`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "emails.display.foo",
+		},
+		{
+			name:      "unknown top level attribute",
+			filter:    `nonExistentAttr eq "x"`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "nonExistentAttr",
+		},
+		{
+			name:      "unknown top level attribute in logical operator",
+			filter:    `userName eq "foo" or nonExistentAttr pr`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "nonExistentAttr",
+		},
+		{
+			name:      "unknown sub attribute",
+			filter:    `name.nonExistentAttr eq "x"`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "name.nonExistentAttr",
+		},
+		{
+			name:      "sub attribute of simple attribute",
+			filter:    `userName.value eq "x"`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "userName.value",
+		},
+		{
+			name:      "unknown attribute in value filter",
+			filter:    `emails[nonExistentAttr eq "work"]`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "nonExistentAttr",
+		},
+		{
+			name:      "unknown schema id qualified attribute",
+			filter:    `urn:ietf:params:scim:schemas:core:2.0:User:nonExistentAttr eq "x"`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "nonExistentAttr",
+		},
+		{
+			name:      "unknown extension attribute",
+			filter:    `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:nonExistentAttr eq "x"`,
+			expectErr: spec.ErrInvalidFilter,
+			expectMsg: "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:nonExistentAttr",
+		},
+		{
+			name:      "invalid filter",
+			filter:    `userName xx "foo"`,
+			expectErr: spec.ErrInvalidFilter,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			root, err := CompileFilterFor(test.filter, s.resourceType)
+			if test.expectErr == nil {
+				assert.Nil(t, err)
+				assert.NotNil(t, root)
+			} else {
+				assert.Equal(t, test.expectErr, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), test.expectMsg)
+			}
+		})
+	}
+}
+
+func (s *CompileFilterForTestSuite) TestCompileFilterForWithoutResourceType() {
+	root, err := CompileFilterFor(`nonExistentAttr eq "x"`, nil)
+	assert.Nil(s.T(), err)
+	assert.NotNil(s.T(), root)
+
+	_, err = CompileFilterFor(`userName eq`, nil)
+	assert.True(s.T(), errors.Is(err, spec.ErrInvalidFilter))
+}
+
+func (s *CompileFilterForTestSuite) SetupSuite() {
+	for _, path := range []string{
+		"../../../../public/schemas/core_schema.json",
+		"../../../../public/schemas/user_schema.json",
+		"../../../../public/schemas/user_enterprise_schema.json",
+	} {
+		raw, err := ioutil.ReadFile(path)
+		require.Nil(s.T(), err)
+		schema := new(spec.Schema)
+		require.Nil(s.T(), json.Unmarshal(raw, schema))
+		spec.Schemas().Register(schema)
+		RegisterURN(schema.ID())
+	}
+
+	s.resourceType = new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "User",
+  "name": "User",
+  "endpoint": "/Users",
+  "schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+  "schemaExtensions": [
+    {
+      "schema": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+      "required": false
+    }
+  ]
+}
+`), s.resourceType))
+}
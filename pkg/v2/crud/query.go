@@ -3,7 +3,10 @@ package crud
 import (
 	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"golang.org/x/text/collate"
 	"sort"
+	"strings"
 )
 
 // Order for sorting
@@ -17,10 +20,14 @@ const (
 )
 
 type (
-	// Option to sort
+	// Option to sort. The optional Locale is a language tag (or an Accept-Language header value) that selects the
+	// collation rules used to compare string typed sort keys. It does not affect sort keys of other types, such as
+	// numeric or dateTime ones. When Locale is absent or not supported, the server default ordering, which compares
+	// strings by their code points, is used.
 	Sort struct {
-		By    string
-		Order SortOrder
+		By     string
+		Order  SortOrder
+		Locale string
 	}
 	// Option to include or exclude attributes in the return. At most one can be specified.
 	Projection struct {
@@ -30,10 +37,13 @@ type (
 	// Option to paginate.
 	Pagination struct {
 		StartIndex int // 1-based start index
-		Count      int
+		Count      int // maximum number of resources to return; CountUnspecified if not specified by client
 	}
 )
 
+// CountUnspecified is the Pagination.Count value to indicate that client has not specified the count.
+const CountUnspecified = -1
+
 // Sort the given list of resources according to the sort options.
 func (s Sort) Sort(resources []*prop.Resource) error {
 	if len(resources) <= 1 {
@@ -51,6 +61,7 @@ func (s Sort) Sort(resources []*prop.Resource) error {
 		resources: resources,
 		by:        head,
 		dir:       s.Order,
+		collator:  newCollator(s.Locale),
 	})
 	return nil
 }
@@ -59,6 +70,7 @@ type sortWrapper struct {
 	by        *expr.Expression
 	dir       SortOrder
 	resources []*prop.Resource
+	collator  *collate.Collator // nil if server default ordering is used
 }
 
 func (s *sortWrapper) Len() int {
@@ -96,6 +108,31 @@ func (s *sortWrapper) Less(i, j int) bool {
 		}
 	}
 
+	// schema URIs are not orderable by their property, and are compared case insensitively
+	if isSchemas(a.Attribute()) && !a.IsUnassigned() && !b.IsUnassigned() {
+		less := strings.ToLower(a.Raw().(string)) < strings.ToLower(b.Raw().(string))
+		switch s.dir {
+		case SortDefault, SortAsc:
+			return less
+		case SortDesc:
+			return !less
+		default:
+			panic("invalid sortOrder")
+		}
+	}
+
+	if s.collator != nil && a.Attribute().Type() == spec.TypeString && !a.IsUnassigned() && !b.IsUnassigned() {
+		less := s.collatedLessThan(a, b)
+		switch s.dir {
+		case SortDefault, SortAsc:
+			return less
+		case SortDesc:
+			return !less
+		default:
+			panic("invalid sortOrder")
+		}
+	}
+
 	if ltCapable, ok := a.(prop.LtCapable); !ok {
 		return false
 	} else {
@@ -110,6 +147,16 @@ func (s *sortWrapper) Less(i, j int) bool {
 	}
 }
 
+// collatedLessThan compares the string values of a and b using the locale specific collator. Case is ignored if the
+// attribute is not caseExact.
+func (s *sortWrapper) collatedLessThan(a prop.Property, b prop.Property) bool {
+	va, vb := a.Raw().(string), b.Raw().(string)
+	if !a.Attribute().CaseExact() {
+		va, vb = strings.ToLower(va), strings.ToLower(vb)
+	}
+	return s.collator.CompareString(va, vb) < 0
+}
+
 func (s *sortWrapper) Swap(i, j int) {
 	s.resources[i], s.resources[j] = s.resources[j], s.resources[i]
 }
@@ -0,0 +1,155 @@
+package crud
+
+import (
+	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestSort(t *testing.T) {
+	s := new(SortTestSuite)
+	suite.Run(t, s)
+}
+
+type SortTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *SortTestSuite) TestSort() {
+	tests := []struct {
+		name   string
+		ids    []string
+		sort   Sort
+		expect []string
+	}{
+		{
+			name:   "default ordering compares code points",
+			ids:    []string{"ä", "z", "å"},
+			sort:   Sort{By: "id", Order: SortAsc},
+			expect: []string{"z", "ä", "å"},
+		},
+		{
+			name:   "swedish collation",
+			ids:    []string{"ä", "z", "å"},
+			sort:   Sort{By: "id", Order: SortAsc, Locale: "sv-SE"},
+			expect: []string{"z", "å", "ä"},
+		},
+		{
+			name:   "swedish collation in descending order",
+			ids:    []string{"ä", "z", "å"},
+			sort:   Sort{By: "id", Order: SortDesc, Locale: "sv-SE"},
+			expect: []string{"ä", "å", "z"},
+		},
+		{
+			name:   "german collation from Accept-Language value",
+			ids:    []string{"ä", "z", "a"},
+			sort:   Sort{By: "id", Order: SortAsc, Locale: "de-DE,de;q=0.9"},
+			expect: []string{"a", "ä", "z"},
+		},
+		{
+			name:   "unsupported locale falls back to default ordering",
+			ids:    []string{"ä", "z", "a"},
+			sort:   Sort{By: "id", Order: SortAsc, Locale: "sw"},
+			expect: []string{"a", "z", "ä"},
+		},
+		{
+			name:   "malformed locale falls back to default ordering",
+			ids:    []string{"ä", "z", "a"},
+			sort:   Sort{By: "id", Order: SortAsc, Locale: "!!"},
+			expect: []string{"a", "z", "ä"},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			resources := make([]*prop.Resource, 0)
+			for _, id := range test.ids {
+				r := prop.NewResource(s.resourceType)
+				require.False(t, r.Navigator().Dot("id").Replace(id).HasError())
+				resources = append(resources, r)
+			}
+
+			assert.Nil(t, test.sort.Sort(resources))
+
+			actual := make([]string, 0)
+			for _, r := range resources {
+				actual = append(actual, r.IdOrEmpty())
+			}
+			assert.Equal(t, test.expect, actual)
+		})
+	}
+}
+
+func (s *SortTestSuite) TestSortBySchemas() {
+	resources := make([]*prop.Resource, 0)
+	for i, schemas := range [][]interface{}{
+		{"urn:b:Second"},
+		{"URN:C:Third", "urn:a:Other"},
+		{"urn:a:First"},
+	} {
+		r := prop.NewResource(s.resourceType)
+		require.False(s.T(), r.Navigator().Dot("id").Replace(string(rune('1'+i))).HasError())
+		require.False(s.T(), r.Navigator().Dot("schemas").Replace(schemas).HasError())
+		resources = append(resources, r)
+	}
+
+	assert.Nil(s.T(), Sort{By: "schemas", Order: SortAsc}.Sort(resources))
+
+	actual := make([]string, 0)
+	for _, r := range resources {
+		actual = append(actual, r.IdOrEmpty())
+	}
+	assert.Equal(s.T(), []string{"3", "1", "2"}, actual)
+}
+
+func (s *SortTestSuite) TestMatchCollationLocale() {
+	tag, ok := MatchCollationLocale("sv-SE")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "sv", tag)
+
+	tag, ok = MatchCollationLocale("de-DE")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "de", tag)
+
+	tag, ok = MatchCollationLocale("de-DE-u-co-phonebk")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "de-u-co-phonebk", tag)
+
+	tag, ok = MatchCollationLocale("sw,de;q=0.5")
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), "de", tag)
+
+	for _, unsupported := range []string{"sw", "tlh"} {
+		_, ok = MatchCollationLocale(unsupported)
+		assert.False(s.T(), ok, unsupported)
+	}
+
+	_, ok = MatchCollationLocale("")
+	assert.False(s.T(), ok)
+
+	_, ok = MatchCollationLocale("!!")
+	assert.False(s.T(), ok)
+}
+
+func (s *SortTestSuite) SetupSuite() {
+	core := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testCoreSchema), core))
+	spec.Schemas().Register(core)
+
+	schema := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testMainSchema), schema))
+	spec.Schemas().Register(schema)
+
+	extension := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testExtensionSchema), extension))
+	spec.Schemas().Register(extension)
+
+	s.resourceType = new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(testResourceType), s.resourceType))
+	Register(s.resourceType)
+}
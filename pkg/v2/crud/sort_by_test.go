@@ -159,6 +159,10 @@ func (s *SeekSortByTargetTestSuite) SetupSuite() {
 	require.Nil(s.T(), json.Unmarshal([]byte(testMainSchema), schema))
 	spec.Schemas().Register(schema)
 
+	extension := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(testExtensionSchema), extension))
+	spec.Schemas().Register(extension)
+
 	s.resourceType = new(spec.ResourceType)
 	require.Nil(s.T(), json.Unmarshal([]byte(testResourceType), s.resourceType))
 	Register(s.resourceType)
@@ -77,23 +77,39 @@ func (t traverser) traverseSelectedElements(query *expr.Expression) error {
 	})
 }
 
+// Elements are all qualified before any of them is traversed, and then traversed in the reverse order, so that the
+// callback may delete the element (i.e. remove emails[type eq "work"]) without shifting the index of the elements
+// yet to be traversed.
 func (t traverser) traverseQualifiedElements(filter *expr.Expression) error {
-	return t.nav.ForEachChild(func(index int, child prop.Property) error {
-		t.nav.At(index)
-		if err := t.nav.Error(); err != nil {
+	qualified := make([]int, 0)
+	if err := t.nav.ForEachChild(func(index int, child prop.Property) error {
+		r, err := evaluator{base: child, filter: filter}.evaluate()
+		if err != nil {
 			return err
+		} else if r {
+			qualified = append(qualified, index)
 		}
-		defer t.nav.Retract()
+		return nil
+	}); err != nil {
+		return err
+	}
 
-		r, err := evaluator{base: t.nav.Current(), filter: filter}.evaluate()
-		if err != nil {
+	for i := len(qualified) - 1; i >= 0; i-- {
+		if err := t.traverseElement(qualified[i], filter.Next()); err != nil {
 			return err
-		} else if !r {
-			return nil
 		}
+	}
+	return nil
+}
 
-		return t.traverse(filter.Next())
-	})
+func (t traverser) traverseElement(index int, query *expr.Expression) error {
+	t.nav.At(index)
+	if err := t.nav.Error(); err != nil {
+		return err
+	}
+	defer t.nav.Retract()
+
+	return t.traverse(query)
 }
 
 type elementStrategy func(multiValuedComplex prop.Property) func(index int, child prop.Property) bool
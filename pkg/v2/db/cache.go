@@ -0,0 +1,153 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"sync"
+	"time"
+)
+
+// Cache returns a DB that caches the resources fetched by Get from the underlying database. Cached resources are keyed
+// by their id, and expire after ttl; when there are more than size resources cached, the least recently used ones are
+// evicted. A non-positive ttl means cached resources never expire, and a non-positive size means the cache is not
+// bounded.
+//
+// A cached resource is invalidated when it is replaced or deleted through this DB. Changes made to the underlying
+// database by other means are only observed after the cached resource expires. Only Get requests without projection
+// are cached; Count and Query are always delegated to the underlying database, since their results cannot be easily
+// invalidated. Like Memory, resources returned by Get are copies, which can be freely modified by the callers.
+func Cache(database DB, ttl time.Duration, size int) DB {
+	return &cacheDB{
+		DB:      database,
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+type cacheDB struct {
+	DB
+	sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*list.Element // id to element of lru, whose value is *cacheEntry
+	lru     *list.List               // most recently used at front
+	version uint64                   // incremented on every invalidation
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	id        string
+	resource  *prop.Resource
+	expiresAt time.Time // zero if never expires
+}
+
+func (c *cacheDB) Get(ctx context.Context, id string, projection *crud.Projection) (*prop.Resource, error) {
+	if projection != nil {
+		return c.DB.Get(ctx, id, projection)
+	}
+
+	if r, ok := c.lookup(id); ok {
+		return r, nil
+	}
+
+	c.Lock()
+	version := c.version
+	c.Unlock()
+
+	r, err := c.DB.Get(ctx, id, nil)
+	if err != nil || r == nil {
+		return r, err
+	}
+
+	c.store(id, r, version)
+	return r, nil
+}
+
+func (c *cacheDB) Insert(ctx context.Context, resource *prop.Resource) error {
+	c.invalidate(resource.IdOrEmpty())
+	return c.DB.Insert(ctx, resource)
+}
+
+func (c *cacheDB) Replace(ctx context.Context, ref *prop.Resource, replacement *prop.Resource) error {
+	// invalidate both before and after the write, so that a concurrent Get that has read the old resource
+	// from the underlying database does not cache it.
+	c.invalidate(ref.IdOrEmpty(), replacement.IdOrEmpty())
+	defer c.invalidate(ref.IdOrEmpty(), replacement.IdOrEmpty())
+	return c.DB.Replace(ctx, ref, replacement)
+}
+
+func (c *cacheDB) Delete(ctx context.Context, resource *prop.Resource) error {
+	c.invalidate(resource.IdOrEmpty())
+	defer c.invalidate(resource.IdOrEmpty())
+	return c.DB.Delete(ctx, resource)
+}
+
+// lookup returns a copy of the cached resource, if it is cached and not expired.
+func (c *cacheDB) lookup(id string) (*prop.Resource, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !entry.expiresAt.IsZero() && !c.now().Before(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry.resource.Clone(), true
+}
+
+// store caches a copy of the resource, unless a cached resource was invalidated since version, in which case
+// the resource may be stale.
+func (c *cacheDB) store(id string, resource *prop.Resource, version uint64) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.version != version {
+		return
+	}
+
+	entry := &cacheEntry{id: id, resource: resource.Clone()}
+	if c.ttl > 0 {
+		entry.expiresAt = c.now().Add(c.ttl)
+	}
+
+	if elem, ok := c.entries[id]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+	} else {
+		c.entries[id] = c.lru.PushFront(entry)
+	}
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *cacheDB) invalidate(ids ...string) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.version++
+	for _, id := range ids {
+		if elem, ok := c.entries[id]; ok {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove the element from cache. Callers must hold the lock.
+func (c *cacheDB) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).id)
+}
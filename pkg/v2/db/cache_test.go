@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheDB(t *testing.T) {
+	s := new(CacheDBTestSuite)
+	suite.Run(t, s)
+}
+
+type CacheDBTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *CacheDBTestSuite) TestGet() {
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		size   int
+		expect func(t *testing.T, database DB, counter *countingDB, clock *time.Time)
+	}{
+		{
+			name: "repeated get is served from cache",
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				for i := 0; i < 3; i++ {
+					r, err := database.Get(context.Background(), "0001", nil)
+					assert.Nil(t, err)
+					assert.Equal(t, "user0001", r.Navigator().Dot("userName").Current().Raw())
+				}
+				assert.Equal(t, 1, counter.gets)
+			},
+		},
+		{
+			name: "cached resource expires after ttl",
+			ttl:  time.Minute,
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				_, _ = database.Get(context.Background(), "0001", nil)
+				*clock = clock.Add(30 * time.Second)
+				_, _ = database.Get(context.Background(), "0001", nil)
+				assert.Equal(t, 1, counter.gets)
+
+				*clock = clock.Add(30 * time.Second)
+				_, _ = database.Get(context.Background(), "0001", nil)
+				assert.Equal(t, 2, counter.gets)
+			},
+		},
+		{
+			name: "least recently used resource is evicted beyond size",
+			size: 2,
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				for _, id := range []string{"0001", "0002", "0001", "0003"} {
+					_, _ = database.Get(context.Background(), id, nil)
+				}
+				assert.Equal(t, 3, counter.gets)
+
+				_, _ = database.Get(context.Background(), "0001", nil)
+				_, _ = database.Get(context.Background(), "0003", nil)
+				assert.Equal(t, 3, counter.gets)
+
+				_, _ = database.Get(context.Background(), "0002", nil)
+				assert.Equal(t, 4, counter.gets)
+			},
+		},
+		{
+			name: "replace invalidates cached resource",
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				ref, err := database.Get(context.Background(), "0001", nil)
+				require.Nil(t, err)
+
+				replacement := ref.Clone()
+				require.False(t, replacement.Navigator().Dot("userName").Replace("renamed").HasError())
+				require.Nil(t, database.Replace(context.Background(), ref, replacement))
+
+				r, err := database.Get(context.Background(), "0001", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, "renamed", r.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, 2, counter.gets)
+			},
+		},
+		{
+			name: "delete invalidates cached resource",
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				r, err := database.Get(context.Background(), "0001", nil)
+				require.Nil(t, err)
+				require.Nil(t, database.Delete(context.Background(), r))
+
+				_, err = database.Get(context.Background(), "0001", nil)
+				assert.NotNil(t, err)
+				assert.Equal(t, 2, counter.gets)
+			},
+		},
+		{
+			name: "get with projection is not cached",
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				projection := &crud.Projection{Attributes: []string{"userName"}}
+				_, _ = database.Get(context.Background(), "0001", projection)
+				_, _ = database.Get(context.Background(), "0001", projection)
+				_, _ = database.Get(context.Background(), "0001", nil)
+				assert.Equal(t, 3, counter.gets)
+			},
+		},
+		{
+			name: "cached resource is returned as copy",
+			expect: func(t *testing.T, database DB, counter *countingDB, clock *time.Time) {
+				r, err := database.Get(context.Background(), "0001", nil)
+				require.Nil(t, err)
+				require.False(t, r.Navigator().Dot("userName").Replace("modified").HasError())
+
+				r, err = database.Get(context.Background(), "0001", nil)
+				require.Nil(t, err)
+				require.False(t, r.Navigator().Dot("userName").Replace("modified").HasError())
+
+				r, err = database.Get(context.Background(), "0001", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, "user0001", r.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, 1, counter.gets)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			counter := &countingDB{DB: seedUsers(t, s.resourceType, 5)}
+			clock := time.Now()
+			database := Cache(counter, test.ttl, test.size)
+			database.(*cacheDB).now = func() time.Time { return clock }
+			test.expect(t, database, counter, &clock)
+		})
+	}
+}
+
+func (s *CacheDBTestSuite) TestConcurrentAccess() {
+	database := Cache(seedUsers(s.T(), s.resourceType, 10), time.Minute, 5)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				r, err := database.Get(ctx, []string{"0000", "0001", "0002", "0003", "0004", "0005"}[(i+j)%6], nil)
+				if assert.Nil(s.T(), err) && j%10 == 0 {
+					assert.Nil(s.T(), database.Replace(ctx, r, r.Clone()))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (s *CacheDBTestSuite) SetupSuite() {
+	s.resourceType = loadUserResourceType(s.T())
+}
+
+// countingDB counts the Get requests reaching the underlying database.
+type countingDB struct {
+	DB
+	sync.Mutex
+	gets int
+}
+
+func (c *countingDB) Get(ctx context.Context, id string, projection *crud.Projection) (*prop.Resource, error) {
+	c.Lock()
+	c.gets++
+	c.Unlock()
+	return c.DB.Get(ctx, id, projection)
+}
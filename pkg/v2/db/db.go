@@ -2,13 +2,19 @@ package db
 
 import (
 	"context"
+	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strconv"
 )
 
 // DB is the abstraction for the database that provides the persistence and look up capabilities.
 type DB interface {
-	// Insert the given resource into the database, or return any error.
+	// Insert the given resource into the database, or return any error. Insert shall fail with spec.ErrConflict when
+	// a resource with the same id already exists, and the check shall be atomic with the insertion, so that concurrent
+	// inserts of the same id result in exactly one resource (i.e. conditional create with If-None-Match: *).
 	Insert(ctx context.Context, resource *prop.Resource) error
 	// Count the number of resources that meets the given SCIM filter.
 	Count(ctx context.Context, filter string) (int, error)
@@ -16,6 +22,12 @@ type DB interface {
 	// response. Implementations may elect to ignore this parameter in case caller services need all the attributes for
 	// additional processing.
 	Get(ctx context.Context, id string, projection *crud.Projection) (*prop.Resource, error)
+	// GetByAttribute gets the single resource whose singular attribute addressed by attributePath (i.e. userName)
+	// equals the value, which is the Go value of the attribute (i.e. string for string attributes). String values are
+	// compared respecting the caseExact setting of the attribute. The lookup is intended for unique attributes. It shall
+	// fail with spec.ErrNotFound when no resource matches, and with spec.ErrInternal when more than one resource
+	// matches, since that implies a violation of uniqueness.
+	GetByAttribute(ctx context.Context, attributePath string, value interface{}) (*prop.Resource, error)
 	// Replace overwrites an existing reference resource with the content of the replacement resource. The reference
 	// and the replacement resource are supposed to have the same id.
 	Replace(ctx context.Context, ref *prop.Resource, replacement *prop.Resource) error
@@ -26,3 +38,32 @@ type DB interface {
 	// additional processing.
 	Query(ctx context.Context, filter string, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error)
 }
+
+// CompiledQuery is implemented by databases which can count and query with a filter already compiled by
+// expr.CompileFilter (or expr.CompileFilterFor). It spares the cost of compiling the filter again for callers which
+// have compiled it to validate it, i.e. the query service. Both methods behave like their DB counterparts.
+type CompiledQuery interface {
+	// Count the number of resources that meets the compiled SCIM filter.
+	CountCompiled(ctx context.Context, filter *expr.Expression) (int, error)
+	// Query resources with the compiled SCIM filter.
+	QueryCompiled(ctx context.Context, filter *expr.Expression, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error)
+}
+
+// EqualityFilter returns the SCIM filter that compares the attribute addressed by path to the Go value for equality, for
+// implementations to carry out GetByAttribute as a query. The value can be a string, bool, int, int64 or float64.
+func EqualityFilter(path string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return path + " eq " + strconv.Quote(v), nil
+	case bool:
+		return path + " eq " + strconv.FormatBool(v), nil
+	case int:
+		return path + " eq " + strconv.Itoa(v), nil
+	case int64:
+		return path + " eq " + strconv.FormatInt(v, 10), nil
+	case float64:
+		return path + " eq " + strconv.FormatFloat(v, 'f', -1, 64), nil
+	default:
+		return "", fmt.Errorf("%w: value of type %T cannot be compared to '%s'", spec.ErrInvalidValue, value, path)
+	}
+}
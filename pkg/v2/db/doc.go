@@ -1,2 +1,3 @@
-// This package defines the database provider interface, and provides a simple in-memory implementation.
+// This package defines the database provider interface, and provides a simple in-memory implementation, as well as a
+// read-through cache and a sharding decorator that can be layered over any implementation.
 package db
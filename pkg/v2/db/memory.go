@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"sync"
@@ -13,17 +14,31 @@ import (
 // it does allow for concurrent access through the use of RWMutex, it does not support high throughput usage.
 // Hence, it is only intended for testing and showcasing purposes. This implementation also ignores all the field projection
 // parameters that it always returned the full resource regardless of the request to include or exclude attributes.
-func Memory() DB {
+//
+// The optional indexes are paths of singular string attributes (i.e. userName, name.familyName) to be indexed. Count
+// and Query use these indexes to narrow down the resources to evaluate, when the filter contains an eq or sw comparison
+// on an indexed attribute, which is either the filter itself, or one of the operands of its top level and operators.
+//
+// Memory implements CompiledQuery, so that a filter compiled by the caller is not compiled again.
+//
+// Get, GetByAttribute and Query return copies of the stored resources, so that callers may modify them (i.e. in
+// filters) without affecting the stored data.
+func Memory(indexes ...string) DB {
 	db := memoryDB{
 		RWMutex: sync.RWMutex{},
 		db:      make(map[string]*prop.Resource),
+		indexes: make([]*memoryIndex, 0, len(indexes)),
+	}
+	for _, path := range indexes {
+		db.indexes = append(db.indexes, newMemoryIndex(path))
 	}
 	return &db
 }
 
 type memoryDB struct {
 	sync.RWMutex
-	db map[string]*prop.Resource
+	db      map[string]*prop.Resource
+	indexes []*memoryIndex
 }
 
 func (m *memoryDB) Insert(_ context.Context, resource *prop.Resource) error {
@@ -32,41 +47,82 @@ func (m *memoryDB) Insert(_ context.Context, resource *prop.Resource) error {
 		return fmt.Errorf("%w: empty id", spec.ErrInternal)
 	}
 
-	if _, ok := m.db[id]; ok {
-		return fmt.Errorf("%w: id exists", spec.ErrInvalidValue)
-	}
-
 	m.Lock()
 	defer m.Unlock()
+
+	if _, ok := m.db[id]; ok {
+		return fmt.Errorf("%w: id exists", spec.ErrConflict)
+	}
 	m.db[id] = resource
+	for _, idx := range m.indexes {
+		idx.add(resource)
+	}
 
 	return nil
 }
 
+// Get returns a copy of the stored resource, so that callers modifying the returned resource (i.e. the patch service)
+// do not modify the stored resource until it is replaced.
 func (m *memoryDB) Get(_ context.Context, id string, _ *crud.Projection) (*prop.Resource, error) {
+	m.RLock()
+	defer m.RUnlock()
+
 	r, ok := m.db[id]
 	if !ok {
 		return nil, fmt.Errorf("%w: resource not found by id", spec.ErrNotFound)
 	}
-	return r, nil
+	return r.Clone(), nil
 }
 
-func (m *memoryDB) Count(_ context.Context, filter string) (int, error) {
+// GetByAttribute evaluates the equality filter on the attribute, using the indexes when the attribute is indexed.
+func (m *memoryDB) GetByAttribute(_ context.Context, attributePath string, value interface{}) (*prop.Resource, error) {
+	filter, err := EqualityFilter(attributePath, value)
+	if err != nil {
+		return nil, err
+	}
+
+	cf, err := expr.CompileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.RLock()
+	defer m.RUnlock()
+
+	switch candidates := m.plan(cf); len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: resource not found by '%s'", spec.ErrNotFound, attributePath)
+	case 1:
+		return candidates[0].Clone(), nil
+	default:
+		return nil, fmt.Errorf("%w: %d resources found by '%s', which is supposed to be unique", spec.ErrInternal, len(candidates), attributePath)
+	}
+}
+
+func (m *memoryDB) Count(ctx context.Context, filter string) (int, error) {
 	if len(filter) == 0 {
+		m.RLock()
+		defer m.RUnlock()
 		return len(m.db), nil
 	}
 
-	n := 0
-	for _, r := range m.db {
-		ok, _ := crud.Evaluate(r, filter)
-		if ok {
-			n++
-		}
+	cf, err := expr.CompileFilter(filter)
+	if err != nil {
+		return 0, nil
 	}
-	return n, nil
+	return m.CountCompiled(ctx, cf)
+}
+
+func (m *memoryDB) CountCompiled(_ context.Context, filter *expr.Expression) (int, error) {
+	m.RLock()
+	defer m.RUnlock()
+	return len(m.plan(filter)), nil
 }
 
 func (m *memoryDB) Replace(_ context.Context, ref *prop.Resource, replacement *prop.Resource) error {
+	m.Lock()
+	defer m.Unlock()
+
 	id := ref.IdOrEmpty()
 	_, ok := m.db[id]
 	if !ok {
@@ -78,22 +134,52 @@ func (m *memoryDB) Replace(_ context.Context, ref *prop.Resource, replacement *p
 		return spec.ErrConflict
 	}
 
+	for _, idx := range m.indexes {
+		idx.remove(m.db[id])
+		idx.add(replacement)
+	}
 	m.db[id] = replacement
 	return nil
 }
 
+// Delete removes the resource, only if the stored version still matches the version of the resource. The check and
+// the removal are carried out atomically under the same lock, so that a resource modified since it was read by the
+// caller (and hence checked against the caller's pre conditions) is never deleted.
 func (m *memoryDB) Delete(_ context.Context, resource *prop.Resource) error {
-	delete(m.db, resource.IdOrEmpty())
+	m.Lock()
+	defer m.Unlock()
+
+	id := resource.IdOrEmpty()
+	stored, ok := m.db[id]
+	if !ok {
+		return fmt.Errorf("%w: resource not found by id", spec.ErrNotFound)
+	}
+
+	version := resource.MetaVersionOrEmpty()
+	if len(version) > 0 && stored.MetaVersionOrEmpty() != version {
+		return fmt.Errorf("%w: resource by id '%s' was modified since by another request", spec.ErrConflict, id)
+	}
+
+	for _, idx := range m.indexes {
+		idx.remove(stored)
+	}
+	delete(m.db, id)
 	return nil
 }
 
-func (m *memoryDB) Query(_ context.Context, filter string, sort *crud.Sort, pagination *crud.Pagination, _ *crud.Projection) ([]*prop.Resource, error) {
-	var candidates = make([]*prop.Resource, 0)
-	for _, r := range m.db {
-		if ok, _ := crud.Evaluate(r, filter); ok {
-			candidates = append(candidates, r)
-		}
+func (m *memoryDB) Query(ctx context.Context, filter string, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error) {
+	cf, err := expr.CompileFilter(filter)
+	if err != nil {
+		return []*prop.Resource{}, nil
 	}
+	return m.QueryCompiled(ctx, cf, sort, pagination, projection)
+}
+
+func (m *memoryDB) QueryCompiled(_ context.Context, filter *expr.Expression, sort *crud.Sort, pagination *crud.Pagination, _ *crud.Projection) ([]*prop.Resource, error) {
+	m.RLock()
+	defer m.RUnlock()
+
+	var candidates = m.plan(filter)
 	if len(candidates) == 0 {
 		return []*prop.Resource{}, nil
 	}
@@ -108,13 +194,21 @@ func (m *memoryDB) Query(_ context.Context, filter string, sort *crud.Sort, pagi
 		lb := pagination.StartIndex - 1
 		if lb < 0 {
 			lb = 0
+		} else if lb > len(candidates) {
+			lb = len(candidates)
 		}
 		ub := pagination.StartIndex + pagination.Count - 1
-		if ub > len(candidates) {
+		if pagination.Count < 0 || ub > len(candidates) {
 			ub = len(candidates)
+		} else if ub < lb {
+			ub = lb
 		}
 		candidates = candidates[lb:ub]
 	}
 
-	return candidates, nil
+	results := make([]*prop.Resource, 0, len(candidates))
+	for _, r := range candidates {
+		results = append(results, r.Clone())
+	}
+	return results, nil
 }
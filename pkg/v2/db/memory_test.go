@@ -0,0 +1,343 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+)
+
+func TestMemoryDB(t *testing.T) {
+	s := new(MemoryDBTestSuite)
+	suite.Run(t, s)
+}
+
+type MemoryDBTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *MemoryDBTestSuite) TestQuery() {
+	tests := []struct {
+		name   string
+		filter string
+		expect []string
+	}{
+		{
+			name:   "indexed eq",
+			filter: `userName eq "user0042"`,
+			expect: []string{"0042"},
+		},
+		{
+			name:   "indexed eq is case insensitive for caseExact=false attribute",
+			filter: `userName eq "USER0042"`,
+			expect: []string{"0042"},
+		},
+		{
+			name:   "indexed eq and non-indexed predicate",
+			filter: `userName eq "user0042" and active eq false`,
+			expect: []string{"0042"},
+		},
+		{
+			name:   "indexed eq and non-indexed predicate not satisfied",
+			filter: `active eq true and userName eq "user0042"`,
+			expect: []string{},
+		},
+		{
+			name:   "indexed sw and non-indexed predicate",
+			filter: `userName sw "user000" and active eq true`,
+			expect: []string{"0001", "0003", "0005", "0007", "0009"},
+		},
+		{
+			name:   "and with two indexed predicates",
+			filter: `userName sw "user00" and active eq true and name.familyName eq "Family3"`,
+			expect: []string{"0003", "0013", "0023", "0033", "0043", "0053", "0063", "0073", "0083", "0093"},
+		},
+		{
+			name:   "or is not planned",
+			filter: `userName eq "user0042" or userName eq "user0043"`,
+			expect: []string{"0042", "0043"},
+		},
+		{
+			name:   "disabled index on multiValued attribute is not used",
+			filter: `emails.value eq "user0042@foo.com" and active eq false`,
+			expect: []string{"0042"},
+		},
+		{
+			name:   "disabled index on boolean attribute is not used",
+			filter: `active eq true and userName sw "user001"`,
+			expect: []string{"0011", "0013", "0015", "0017", "0019"},
+		},
+		{
+			name:   "invalid filter",
+			filter: `userName eq`,
+			expect: []string{},
+		},
+	}
+
+	planned := s.users(200, "userName", "name.familyName", "emails.value", "active")
+	naive := s.users(200)
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			for _, database := range []DB{planned, naive} {
+				results, err := database.Query(context.Background(), test.filter, nil, nil, nil)
+				assert.Nil(t, err)
+				assert.Equal(t, test.expect, s.idsOf(results))
+
+				n, err := database.Count(context.Background(), test.filter)
+				assert.Nil(t, err)
+				assert.Equal(t, len(test.expect), n)
+			}
+		})
+	}
+}
+
+func (s *MemoryDBTestSuite) TestGetByAttribute() {
+	tests := []struct {
+		name      string
+		path      string
+		value     interface{}
+		expect    string
+		expectErr error
+	}{
+		{
+			name:   "unique attribute",
+			path:   "userName",
+			value:  "user0004",
+			expect: "0004",
+		},
+		{
+			name:   "case insensitive for caseExact=false attribute",
+			path:   "userName",
+			value:  "USER0004",
+			expect: "0004",
+		},
+		{
+			name:   "path prefixed with schema id",
+			path:   "urn:ietf:params:scim:schemas:core:2.0:User:userName",
+			value:  "user0004",
+			expect: "0004",
+		},
+		{
+			name:   "sub attribute",
+			path:   "name.familyName",
+			value:  "Family3",
+			expect: "0003",
+		},
+		{
+			name:      "no match",
+			path:      "userName",
+			value:     "foobar",
+			expectErr: spec.ErrNotFound,
+		},
+		{
+			name:      "more than one match",
+			path:      "active",
+			value:     true,
+			expectErr: spec.ErrInternal,
+		},
+		{
+			name:      "unsupported value",
+			path:      "userName",
+			value:     []string{"user0004"},
+			expectErr: spec.ErrInvalidValue,
+		},
+	}
+
+	indexed := s.users(10, "userName", "name.familyName")
+	naive := s.users(10)
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			for _, database := range []DB{indexed, naive} {
+				r, err := database.GetByAttribute(context.Background(), test.path, test.value)
+				if test.expectErr != nil {
+					assert.Equal(t, test.expectErr, errors.Unwrap(err))
+					assert.Nil(t, r)
+				} else {
+					assert.Nil(t, err)
+					assert.Equal(t, test.expect, r.IdOrEmpty())
+				}
+			}
+		})
+	}
+}
+
+func (s *MemoryDBTestSuite) TestIndexMaintenance() {
+	database := s.users(10, "userName")
+	ctx := context.Background()
+
+	count := func(filter string) int {
+		n, err := database.Count(ctx, filter)
+		assert.Nil(s.T(), err)
+		return n
+	}
+
+	ref, err := database.Get(ctx, "0003", nil)
+	require.Nil(s.T(), err)
+
+	replacement := ref.Clone()
+	assert.False(s.T(), replacement.Navigator().Dot("userName").Replace("renamed").HasError())
+	assert.Nil(s.T(), database.Replace(ctx, ref, replacement))
+	assert.Equal(s.T(), 0, count(`userName eq "user0003"`))
+	assert.Equal(s.T(), 1, count(`userName eq "renamed"`))
+
+	assert.Nil(s.T(), database.Delete(ctx, replacement))
+	assert.Equal(s.T(), 0, count(`userName eq "renamed"`))
+	assert.Equal(s.T(), 9, count(`userName sw "user"`))
+}
+
+func (s *MemoryDBTestSuite) TestGetReturnsCopy() {
+	database := s.users(1, "userName")
+	ctx := context.Background()
+
+	r, err := database.Get(ctx, "0000", nil)
+	require.Nil(s.T(), err)
+	assert.False(s.T(), r.Navigator().Dot("userName").Replace("modified").HasError())
+
+	stored, err := database.Get(ctx, "0000", nil)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "user0000", stored.Navigator().Dot("userName").Current().Raw())
+
+	n, err := database.Count(ctx, `userName eq "user0000"`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 1, n)
+}
+
+func (s *MemoryDBTestSuite) TestQueryReturnsCopy() {
+	database := s.users(2, "userName")
+	ctx := context.Background()
+
+	resources, err := database.Query(ctx, `userName sw "user"`, nil, nil, nil)
+	require.Nil(s.T(), err)
+	require.Len(s.T(), resources, 2)
+	for _, r := range resources {
+		assert.False(s.T(), r.Navigator().Dot("userName").Replace("modified").HasError())
+	}
+
+	stored, err := database.Get(ctx, "0000", nil)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "user0000", stored.Navigator().Dot("userName").Current().Raw())
+
+	n, err := database.Count(ctx, `userName sw "user"`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 2, n)
+}
+
+func (s *MemoryDBTestSuite) SetupSuite() {
+	s.resourceType = loadUserResourceType(s.T())
+}
+
+// users returns a memory database with n users with ids 0000 to n-1, and the given indexes.
+func (s *MemoryDBTestSuite) users(n int, indexes ...string) DB {
+	return seedUsers(s.T(), s.resourceType, n, indexes...)
+}
+
+func (s *MemoryDBTestSuite) idsOf(resources []*prop.Resource) []string {
+	ids := make([]string, 0, len(resources))
+	for _, r := range resources {
+		ids = append(ids, r.IdOrEmpty())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func BenchmarkMemoryDBQuery(b *testing.B) {
+	resourceType := loadUserResourceType(b)
+	filter := `userName eq "user4242" and active eq false`
+
+	for _, bench := range []struct {
+		name    string
+		indexes []string
+	}{
+		{name: "naive"},
+		{name: "planned", indexes: []string{"userName"}},
+	} {
+		database := seedUsers(b, resourceType, 10000, bench.indexes...)
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := database.Query(context.Background(), filter, nil, nil, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func seedUsers(t testing.TB, resourceType *spec.ResourceType, n int, indexes ...string) DB {
+	database := Memory(indexes...)
+	for i := 0; i < n; i++ {
+		resource := prop.NewResource(resourceType)
+		require.False(t, resource.Navigator().Replace(map[string]interface{}{
+			"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":       fmt.Sprintf("%04d", i),
+			"userName": fmt.Sprintf("user%04d", i),
+			"active":   i%2 == 1,
+			"name": map[string]interface{}{
+				"familyName": fmt.Sprintf("Family%d", i%10),
+			},
+			"emails": []interface{}{
+				map[string]interface{}{
+					"value": fmt.Sprintf("user%04d@foo.com", i),
+				},
+			},
+		}).HasError())
+		require.Nil(t, database.Insert(context.Background(), resource))
+	}
+	return database
+}
+
+func loadUserResourceType(t testing.TB) *spec.ResourceType {
+	var resourceType *spec.ResourceType
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				resourceType = parsed.(*spec.ResourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(t, err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(t, err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(t, err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+	return resourceType
+}
@@ -7,8 +7,9 @@ import (
 )
 
 // NoOp return an no op implementation of DB. This implementation does nothing and always returns nil error. For Count
-// method, it returns 0 as count; for Get method, it returns nil resource; For Query method, it returns empty slice as
-// results. This implementation might be useful when implementing use cases where resource does not require persistence.
+// method, it returns 0 as count; for Get and GetByAttribute method, it returns nil resource; For Query method, it returns
+// empty slice as results. This implementation might be useful when implementing use cases where resource does not
+// require persistence.
 func NoOp() DB {
 	return noOpDB{}
 }
@@ -27,6 +28,10 @@ func (_ noOpDB) Get(_ context.Context, _ string, _ *crud.Projection) (*prop.Reso
 	return nil, nil
 }
 
+func (_ noOpDB) GetByAttribute(_ context.Context, _ string, _ interface{}) (*prop.Resource, error) {
+	return nil, nil
+}
+
 func (_ noOpDB) Replace(_ context.Context, _ *prop.Resource, _ *prop.Resource) error {
 	return nil
 }
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+)
+
+// memoryIndex indexes the ids of resources by the value of a singular string attribute. Values of attributes that
+// are not caseExact are indexed in lower case, in accordance with the way they are compared.
+//
+// The index learns about the attribute from the resources being indexed. If the path turns out to not denote a
+// singular string attribute (i.e. it passes through a multiValued attribute, or it ends on a boolean attribute), the
+// index is disabled and never used.
+type memoryIndex struct {
+	path      *expr.Expression
+	schemaId  string
+	caseExact bool
+	disabled  bool
+	entries   map[string]map[string]struct{}
+}
+
+func newMemoryIndex(path string) *memoryIndex {
+	idx := &memoryIndex{entries: make(map[string]map[string]struct{})}
+	if head, err := expr.CompilePath(path); err != nil || head == nil || head.ContainsFilter() {
+		idx.disabled = true
+	} else {
+		idx.path = head
+	}
+	return idx
+}
+
+func (idx *memoryIndex) add(resource *prop.Resource) {
+	if key, ok := idx.keyOf(resource); ok {
+		if _, ok := idx.entries[key]; !ok {
+			idx.entries[key] = make(map[string]struct{})
+		}
+		idx.entries[key][resource.IdOrEmpty()] = struct{}{}
+	}
+}
+
+func (idx *memoryIndex) remove(resource *prop.Resource) {
+	if key, ok := idx.keyOf(resource); ok {
+		delete(idx.entries[key], resource.IdOrEmpty())
+		if len(idx.entries[key]) == 0 {
+			delete(idx.entries, key)
+		}
+	}
+}
+
+// keyOf returns the index key of the resource, or false if the attribute is unassigned or not indexable.
+func (idx *memoryIndex) keyOf(resource *prop.Resource) (string, bool) {
+	if idx.disabled {
+		return "", false
+	}
+
+	nav := resource.Navigator()
+	for step := idx.path; step != nil; step = step.Next() {
+		if nav.Current().Attribute().MultiValued() {
+			idx.disabled = true
+			return "", false
+		}
+		if nav.Dot(step.Token()); nav.HasError() {
+			idx.disabled = true
+			return "", false
+		}
+	}
+
+	attr := nav.Current().Attribute()
+	if attr.MultiValued() || attr.Type() != spec.TypeString {
+		idx.disabled = true
+		return "", false
+	}
+	idx.schemaId = resource.ResourceType().Schema().ID()
+	idx.caseExact = attr.CaseExact()
+
+	if nav.Current().IsUnassigned() {
+		return "", false
+	}
+	return idx.formatCase(nav.Current().Raw().(string)), true
+}
+
+// matches returns true if the path in a filter refers to the indexed attribute. The path may be prefixed with the
+// main schema id of the resource.
+func (idx *memoryIndex) matches(path *expr.Expression) bool {
+	if idx.disabled || path.ContainsFilter() {
+		return false
+	}
+	if len(idx.schemaId) > 0 && strings.EqualFold(path.Token(), idx.schemaId) {
+		path = path.Next()
+	}
+
+	step := idx.path
+	for ; step != nil && path != nil; step, path = step.Next(), path.Next() {
+		if !strings.EqualFold(step.Token(), path.Token()) {
+			return false
+		}
+	}
+	return step == nil && path == nil
+}
+
+// lookup returns the ids of resources whose indexed value equals to (when op is expr.Eq), or starts with (when
+// op is expr.Sw) the value.
+func (idx *memoryIndex) lookup(op string, value string) map[string]struct{} {
+	value = idx.formatCase(value)
+	if op == expr.Eq {
+		return idx.entries[value]
+	}
+
+	ids := make(map[string]struct{})
+	for key, entry := range idx.entries {
+		if strings.HasPrefix(key, value) {
+			for id := range entry {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+	return ids
+}
+
+func (idx *memoryIndex) formatCase(value string) string {
+	if idx.caseExact {
+		return value
+	}
+	return strings.ToLower(value)
+}
+
+// plan selects the resources matching the compiled filter. When the filter is a conjunction (and) of predicates,
+// and at least one of these predicates is an eq or sw comparison on an indexed attribute, the index yielding the
+// fewest candidates is used to narrow down the candidates, and only the remaining predicates are evaluated against the
+// candidates. Otherwise, all resources are evaluated against the filter. Callers must hold the lock.
+func (m *memoryDB) plan(filter *expr.Expression) []*prop.Resource {
+	var (
+		predicates = conjunctionOf(filter, nil)
+		planned    = -1
+		candidates map[string]struct{}
+	)
+	for i, predicate := range predicates {
+		if ids, ok := m.lookupIndex(predicate); ok && (planned < 0 || len(ids) < len(candidates)) {
+			planned, candidates = i, ids
+		}
+	}
+
+	results := make([]*prop.Resource, 0)
+	if planned < 0 {
+		for _, r := range m.db {
+			if evaluateAll(r, predicates) {
+				results = append(results, r)
+			}
+		}
+		return results
+	}
+
+	remaining := append(append([]*expr.Expression{}, predicates[:planned]...), predicates[planned+1:]...)
+	for id := range candidates {
+		if r, ok := m.db[id]; ok && evaluateAll(r, remaining) {
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// lookupIndex returns the ids of resources matching the predicate using index, or false if no index can be used.
+func (m *memoryDB) lookupIndex(predicate *expr.Expression) (map[string]struct{}, bool) {
+	if !predicate.IsRelationalOperator() || (predicate.Token() != expr.Eq && predicate.Token() != expr.Sw) {
+		return nil, false
+	}
+
+	literal := predicate.Right().Token()
+	if !strings.HasPrefix(literal, "\"") || !strings.HasSuffix(literal, "\"") || len(literal) < 2 {
+		return nil, false
+	}
+
+	for _, idx := range m.indexes {
+		if idx.matches(predicate.Left()) {
+			return idx.lookup(predicate.Token(), literal[1:len(literal)-1]), true
+		}
+	}
+	return nil, false
+}
+
+// conjunctionOf appends the operands of the (nested) and operators in the filter to predicates, and returns them.
+func conjunctionOf(filter *expr.Expression, predicates []*expr.Expression) []*expr.Expression {
+	if filter.Token() == expr.And && filter.IsLogicalOperator() {
+		return conjunctionOf(filter.Right(), conjunctionOf(filter.Left(), predicates))
+	}
+	return append(predicates, filter)
+}
+
+// evaluateAll returns true if the resource satisfies all predicates. Evaluation error is deemed as not satisfied.
+func evaluateAll(resource *prop.Resource, predicates []*expr.Expression) bool {
+	for _, predicate := range predicates {
+		if ok, _ := crud.EvaluateCompiled(resource, predicate); !ok {
+			return false
+		}
+	}
+	return true
+}
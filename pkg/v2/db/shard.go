@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"hash/fnv"
+	"sync"
+)
+
+// Shard returns a DB that distributes resources across the shards by their id. A resource is routed to the shard at
+// the index of hash(id) modulo the number of shards, so that the same id is always routed to the same shard, as long
+// as the shards do not change. Adding or removing a shard changes the routing, hence requires redistributing the
+// existing resources. If hash is nil, the 64-bit FNV-1a hash is used. At least one shard is required.
+//
+// Insert, Get, Replace and Delete are routed to the single shard owning the id. GetByAttribute, Count and Query fan out
+// to all shards concurrently, and fail if any of the shards fails:
+//   - GetByAttribute finds the resource on at most one shard, and fails with spec.ErrInternal when it is found on more
+//     than one shard, as it would when more than one resource matches in a single shard;
+//   - Count is the sum of the counts of all shards, since each resource is stored in exactly one shard;
+//   - Query sorts and paginates globally: each shard is queried with the same filter and sort, for as many resources
+//     as the page may need (the first startIndex + count - 1 ones, or all of them when count is unspecified), and the
+//     combined results are sorted again before the page is sliced. Without sort, the resources are ordered by shard.
+//
+// As the global sort requires the sort attribute, projection is not passed on to the shards when sorting.
+func Shard(hash func(id string) uint64, shards ...DB) DB {
+	if len(shards) == 0 {
+		panic("at least one shard is required")
+	}
+	if hash == nil {
+		hash = fnvHash
+	}
+	return &shardDB{hash: hash, shards: shards}
+}
+
+type shardDB struct {
+	hash   func(id string) uint64
+	shards []DB
+}
+
+func fnvHash(id string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+	return h.Sum64()
+}
+
+// shardOf returns the shard owning the resource id.
+func (s *shardDB) shardOf(id string) DB {
+	return s.shards[s.hash(id)%uint64(len(s.shards))]
+}
+
+func (s *shardDB) Insert(ctx context.Context, resource *prop.Resource) error {
+	return s.shardOf(resource.IdOrEmpty()).Insert(ctx, resource)
+}
+
+func (s *shardDB) Get(ctx context.Context, id string, projection *crud.Projection) (*prop.Resource, error) {
+	return s.shardOf(id).Get(ctx, id, projection)
+}
+
+func (s *shardDB) GetByAttribute(ctx context.Context, attributePath string, value interface{}) (*prop.Resource, error) {
+	found := make([]*prop.Resource, len(s.shards))
+	if err := s.fanOut(func(i int, shard DB) error {
+		r, err := shard.GetByAttribute(ctx, attributePath, value)
+		if err != nil && errors.Unwrap(err) != spec.ErrNotFound {
+			return err
+		}
+		found[i] = r
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var result *prop.Resource
+	for _, r := range found {
+		if r == nil {
+			continue
+		}
+		if result != nil {
+			return nil, fmt.Errorf("%w: resources found by '%s' on more than one shard, which is supposed to be unique", spec.ErrInternal, attributePath)
+		}
+		result = r
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%w: resource not found by '%s'", spec.ErrNotFound, attributePath)
+	}
+	return result, nil
+}
+
+func (s *shardDB) Replace(ctx context.Context, ref *prop.Resource, replacement *prop.Resource) error {
+	return s.shardOf(ref.IdOrEmpty()).Replace(ctx, ref, replacement)
+}
+
+func (s *shardDB) Delete(ctx context.Context, resource *prop.Resource) error {
+	return s.shardOf(resource.IdOrEmpty()).Delete(ctx, resource)
+}
+
+func (s *shardDB) Count(ctx context.Context, filter string) (int, error) {
+	counts := make([]int, len(s.shards))
+	if err := s.fanOut(func(i int, shard DB) (err error) {
+		counts[i], err = shard.Count(ctx, filter)
+		return
+	}); err != nil {
+		return 0, err
+	}
+
+	var total int
+	for _, n := range counts {
+		total += n
+	}
+	return total, nil
+}
+
+func (s *shardDB) Query(ctx context.Context, filter string, sort *crud.Sort, pagination *crud.Pagination, projection *crud.Projection) ([]*prop.Resource, error) {
+	if len(s.shards) == 1 {
+		return s.shards[0].Query(ctx, filter, sort, pagination, projection)
+	}
+
+	sorted := sort != nil && len(sort.By) > 0
+	if sorted {
+		projection = nil
+	}
+
+	// Every shard may hold the whole page, hence each shard is asked for all resources up to the end of the page.
+	var shardPagination *crud.Pagination
+	if pagination != nil && pagination.Count >= 0 {
+		shardPagination = &crud.Pagination{StartIndex: 1, Count: s.startIndexOf(pagination) - 1 + pagination.Count}
+	}
+
+	results := make([][]*prop.Resource, len(s.shards))
+	if err := s.fanOut(func(i int, shard DB) (err error) {
+		results[i], err = shard.Query(ctx, filter, sort, shardPagination, projection)
+		return
+	}); err != nil {
+		return nil, err
+	}
+
+	combined := make([]*prop.Resource, 0)
+	for _, result := range results {
+		combined = append(combined, result...)
+	}
+
+	if sorted {
+		if err := sort.Sort(combined); err != nil {
+			return nil, err
+		}
+	}
+
+	if pagination != nil {
+		lb := s.startIndexOf(pagination) - 1
+		if lb > len(combined) {
+			lb = len(combined)
+		}
+		ub := lb + pagination.Count
+		if pagination.Count < 0 || ub > len(combined) {
+			ub = len(combined)
+		}
+		combined = combined[lb:ub]
+	}
+
+	return combined, nil
+}
+
+func (s *shardDB) startIndexOf(pagination *crud.Pagination) int {
+	if pagination.StartIndex < 1 {
+		return 1
+	}
+	return pagination.StartIndex
+}
+
+// fanOut invokes the function for each shard concurrently, and returns the first error by shard order, if any.
+func (s *shardDB) fanOut(f func(i int, shard DB) error) error {
+	errs := make([]error, len(s.shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard DB) {
+			defer wg.Done()
+			errs[i] = f(i, shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"testing"
+)
+
+func TestShardDB(t *testing.T) {
+	s := new(ShardDBTestSuite)
+	suite.Run(t, s)
+}
+
+type ShardDBTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *ShardDBTestSuite) TestRouting() {
+	database, shards := s.users(30)
+	ctx := context.Background()
+
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprintf("%04d", i)
+
+		r, err := database.Get(ctx, id, nil)
+		require.Nil(s.T(), err)
+		assert.Equal(s.T(), id, r.IdOrEmpty())
+
+		// resource is stored in exactly one shard
+		found := 0
+		for _, shard := range shards {
+			if _, err := shard.Get(ctx, id, nil); err == nil {
+				found++
+			}
+		}
+		assert.Equal(s.T(), 1, found, id)
+	}
+
+	// resources are actually distributed
+	for _, shard := range shards {
+		n, err := shard.Count(ctx, `id pr`)
+		assert.Nil(s.T(), err)
+		assert.NotZero(s.T(), n)
+	}
+}
+
+func (s *ShardDBTestSuite) TestReplaceAndDelete() {
+	database, _ := s.users(10)
+	ctx := context.Background()
+
+	ref, err := database.Get(ctx, "0003", nil)
+	require.Nil(s.T(), err)
+
+	replacement := ref.Clone()
+	require.False(s.T(), replacement.Navigator().Dot("userName").Replace("replaced").HasError())
+	require.Nil(s.T(), database.Replace(ctx, ref, replacement))
+
+	r, err := database.Get(ctx, "0003", nil)
+	require.Nil(s.T(), err)
+	assert.Equal(s.T(), "replaced", r.Navigator().Dot("userName").Current().Raw())
+
+	require.Nil(s.T(), database.Delete(ctx, r))
+	_, err = database.Get(ctx, "0003", nil)
+	assert.NotNil(s.T(), err)
+
+	n, err := database.Count(ctx, `id pr`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 9, n)
+}
+
+func (s *ShardDBTestSuite) TestGetByAttribute() {
+	database, _ := s.users(30)
+	ctx := context.Background()
+
+	r, err := database.GetByAttribute(ctx, "userName", "user0017")
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "0017", r.IdOrEmpty())
+
+	_, err = database.GetByAttribute(ctx, "userName", "none")
+	assert.Equal(s.T(), spec.ErrNotFound, errors.Unwrap(err))
+
+	_, err = database.GetByAttribute(ctx, "name.familyName", "Family3")
+	assert.Equal(s.T(), spec.ErrInternal, errors.Unwrap(err))
+}
+
+func (s *ShardDBTestSuite) TestCount() {
+	database, shards := s.users(30)
+	ctx := context.Background()
+
+	for _, filter := range []string{`id pr`, `active eq true`, `userName sw "user001"`, `userName eq "none"`} {
+		n, err := database.Count(ctx, filter)
+		assert.Nil(s.T(), err)
+
+		sum := 0
+		for _, shard := range shards {
+			m, err := shard.Count(ctx, filter)
+			assert.Nil(s.T(), err)
+			sum += m
+		}
+		assert.Equal(s.T(), sum, n, filter)
+
+		expect, err := seedUsers(s.T(), s.resourceType, 30).Count(ctx, filter)
+		assert.Nil(s.T(), err)
+		assert.Equal(s.T(), expect, n, filter)
+	}
+}
+
+func (s *ShardDBTestSuite) TestQuery() {
+	tests := []struct {
+		name       string
+		filter     string
+		sort       *crud.Sort
+		pagination *crud.Pagination
+	}{
+		{
+			name:   "sort without pagination",
+			filter: `active eq true`,
+			sort:   &crud.Sort{By: "userName"},
+		},
+		{
+			name:       "sort descending with first page",
+			sort:       &crud.Sort{By: "userName", Order: crud.SortDesc},
+			pagination: &crud.Pagination{StartIndex: 1, Count: 5},
+		},
+		{
+			name:       "sort with middle page",
+			sort:       &crud.Sort{By: "userName"},
+			pagination: &crud.Pagination{StartIndex: 11, Count: 7},
+		},
+		{
+			name:       "sort with filter and page past the end",
+			filter:     `userName sw "user00"`,
+			sort:       &crud.Sort{By: "userName"},
+			pagination: &crud.Pagination{StartIndex: 8, Count: 10},
+		},
+		{
+			name:       "page beyond total",
+			sort:       &crud.Sort{By: "userName"},
+			pagination: &crud.Pagination{StartIndex: 100, Count: 10},
+		},
+		{
+			name:       "zero count",
+			sort:       &crud.Sort{By: "userName"},
+			pagination: &crud.Pagination{StartIndex: 1, Count: 0},
+		},
+		{
+			name:       "unspecified count",
+			sort:       &crud.Sort{By: "userName"},
+			pagination: &crud.Pagination{StartIndex: 26, Count: crud.CountUnspecified},
+		},
+	}
+
+	database, _ := s.users(30)
+	reference := seedUsers(s.T(), s.resourceType, 30)
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			expect, err := reference.Query(context.Background(), test.filter, test.sort, test.pagination, nil)
+			require.Nil(t, err)
+
+			actual, err := database.Query(context.Background(), test.filter, test.sort, test.pagination, nil)
+			require.Nil(t, err)
+			assert.Equal(t, s.idsOf(expect), s.idsOf(actual))
+		})
+	}
+}
+
+func (s *ShardDBTestSuite) TestQueryWithoutSort() {
+	database, _ := s.users(30)
+
+	all, err := database.Query(context.Background(), `id pr`, nil, nil, nil)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), all, 30)
+
+	page, err := database.Query(context.Background(), `id pr`, nil, &crud.Pagination{StartIndex: 5, Count: 10}, nil)
+	assert.Nil(s.T(), err)
+	assert.Len(s.T(), page, 10)
+}
+
+func (s *ShardDBTestSuite) TestCustomHash() {
+	first, second := Memory(), Memory()
+	database := Shard(func(id string) uint64 {
+		if id < "0005" {
+			return 0
+		}
+		return 1
+	}, first, second)
+
+	for _, resource := range s.resources(10) {
+		require.Nil(s.T(), database.Insert(context.Background(), resource))
+	}
+
+	n, err := first.Count(context.Background(), `id pr`)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), 5, n)
+	_, err = second.Get(context.Background(), "0007", nil)
+	assert.Nil(s.T(), err)
+}
+
+func (s *ShardDBTestSuite) SetupSuite() {
+	s.resourceType = loadUserResourceType(s.T())
+}
+
+// users returns a database sharded across 3 memory databases with n users with ids 0000 to n-1, and the shards.
+func (s *ShardDBTestSuite) users(n int) (DB, []DB) {
+	shards := []DB{Memory(), Memory(), Memory()}
+	database := Shard(nil, shards...)
+	for _, resource := range s.resources(n) {
+		require.Nil(s.T(), database.Insert(context.Background(), resource))
+	}
+	return database, shards
+}
+
+// resources returns n users with ids 0000 to n-1, seeded in the same way as seedUsers.
+func (s *ShardDBTestSuite) resources(n int) []*prop.Resource {
+	resources, err := seedUsers(s.T(), s.resourceType, n).Query(context.Background(), `id pr`, nil, nil, nil)
+	require.Nil(s.T(), err)
+	return resources
+}
+
+// idsOf returns the ids of the resources, in order.
+func (s *ShardDBTestSuite) idsOf(resources []*prop.Resource) []string {
+	ids := make([]string, 0, len(resources))
+	for _, r := range resources {
+		ids = append(ids, r.IdOrEmpty())
+	}
+	return ids
+}
@@ -0,0 +1,7 @@
+// This package provides typed access to the values of the enterprise User extension on a resource.
+//
+// Setting enterprise extension values through the property API involves navigating to the namespaced schema extension
+// property and keeping the schemas property up to date. Builder and Get take care of these details and validate the
+// values against the registered enterprise User extension schema, which must be registered with spec.Schemas and be an
+// extension of the resource type of the target resource.
+package enterprise
@@ -0,0 +1,202 @@
+package enterprise
+
+import (
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+)
+
+// SchemaId is the id of the enterprise User extension schema.
+const SchemaId = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+const (
+	fieldSchemas        = "schemas"
+	fieldEmployeeNumber = "employeeNumber"
+	fieldCostCenter     = "costCenter"
+	fieldOrganization   = "organization"
+	fieldDivision       = "division"
+	fieldDepartment     = "department"
+	fieldManager        = "manager"
+	fieldValue          = "value"
+	fieldRef            = "$ref"
+	fieldDisplayName    = "displayName"
+)
+
+type (
+	// User holds the values of the enterprise User extension. Unassigned values are read back as empty strings, and an
+	// unassigned manager is read back as nil.
+	User struct {
+		EmployeeNumber string
+		CostCenter     string
+		Organization   string
+		Division       string
+		Department     string
+		Manager        *Manager
+	}
+	// Manager holds the values of the manager attribute of the enterprise User extension.
+	Manager struct {
+		Value       string
+		Ref         string
+		DisplayName string
+	}
+	// Builder collects the enterprise User extension values to be set on a resource. Values are not applied until
+	// Apply is called.
+	Builder struct {
+		names  []string
+		values map[string]interface{}
+	}
+)
+
+// NewBuilder returns a new Builder with no values.
+func NewBuilder() *Builder {
+	return &Builder{
+		names:  []string{},
+		values: map[string]interface{}{},
+	}
+}
+
+// EmployeeNumber sets the employeeNumber value.
+func (b *Builder) EmployeeNumber(employeeNumber string) *Builder {
+	return b.Set(fieldEmployeeNumber, employeeNumber)
+}
+
+// CostCenter sets the costCenter value.
+func (b *Builder) CostCenter(costCenter string) *Builder {
+	return b.Set(fieldCostCenter, costCenter)
+}
+
+// Organization sets the organization value.
+func (b *Builder) Organization(organization string) *Builder {
+	return b.Set(fieldOrganization, organization)
+}
+
+// Division sets the division value.
+func (b *Builder) Division(division string) *Builder {
+	return b.Set(fieldDivision, division)
+}
+
+// Department sets the department value.
+func (b *Builder) Department(department string) *Builder {
+	return b.Set(fieldDepartment, department)
+}
+
+// Manager sets the manager value to refer to the User of the given id and, if not empty, the given URI. The readOnly
+// displayName is left to the server.
+func (b *Builder) Manager(id string, ref string) *Builder {
+	manager := map[string]interface{}{fieldValue: id}
+	if len(ref) > 0 {
+		manager[fieldRef] = ref
+	}
+	return b.Set(fieldManager, manager)
+}
+
+// Set sets the value of the enterprise User extension attribute by its name. The value is in the same form accepted
+// by prop.Property's Replace method. Setting the same name again overwrites the previous value. The name is validated
+// on Apply: names that are not defined by the enterprise User extension schema cause Apply to fail.
+func (b *Builder) Set(name string, value interface{}) *Builder {
+	if _, ok := b.values[name]; !ok {
+		b.names = append(b.names, name)
+	}
+	b.values[name] = value
+	return b
+}
+
+// Apply sets the collected values on the enterprise User extension of the resource, in the order they were set, and
+// ensures the enterprise User extension schema id is among the schemas of the resource once the extension is assigned.
+//
+// All names are validated against the enterprise User extension schema before any value is set. An error wrapping
+// spec.ErrInvalidPath is returned for a name not defined by the schema, and no value is set. An error setting a value
+// (i.e. a value of the wrong type) aborts the rest of the values, which leaves the values set before it in place.
+func (b *Builder) Apply(resource *prop.Resource) error {
+	extension, err := extensionOf(resource)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range b.names {
+		if extension.Attribute().SubAttributeForName(name) == nil {
+			return fmt.Errorf("%w: '%s' is not defined in schema '%s'", spec.ErrInvalidPath, name, SchemaId)
+		}
+	}
+
+	for _, name := range b.names {
+		nav := resource.Navigator().Dot(SchemaId).Dot(name)
+		if nav.Replace(b.values[name]).HasError() {
+			return nav.Error()
+		}
+	}
+
+	if extension.IsUnassigned() {
+		return nil
+	}
+	return ensureSchema(resource)
+}
+
+// Get reads back the values of the enterprise User extension of the resource. An error is returned if the resource
+// type of the resource does not have the enterprise User extension.
+func Get(resource *prop.Resource) (*User, error) {
+	extension, err := extensionOf(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &User{
+		EmployeeNumber: stringOf(extension, fieldEmployeeNumber),
+		CostCenter:     stringOf(extension, fieldCostCenter),
+		Organization:   stringOf(extension, fieldOrganization),
+		Division:       stringOf(extension, fieldDivision),
+		Department:     stringOf(extension, fieldDepartment),
+	}
+	if manager, err := extension.ChildAtIndex(fieldManager); err == nil && !manager.IsUnassigned() {
+		user.Manager = &Manager{
+			Value:       stringOf(manager, fieldValue),
+			Ref:         stringOf(manager, fieldRef),
+			DisplayName: stringOf(manager, fieldDisplayName),
+		}
+	}
+	return user, nil
+}
+
+// extensionOf returns the enterprise User extension property of the resource, after checking the enterprise User
+// extension schema is registered and is an extension of the resource type.
+func extensionOf(resource *prop.Resource) (prop.Property, error) {
+	if _, ok := spec.Schemas().Get(SchemaId); !ok {
+		return nil, fmt.Errorf("%w: schema '%s' is not registered", spec.ErrInternal, SchemaId)
+	}
+
+	extension, err := resource.RootProperty().ChildAtIndex(SchemaId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: resource type '%s' does not have schema extension '%s'", spec.ErrInvalidValue,
+			resource.ResourceType().Name(), SchemaId)
+	}
+	return extension, nil
+}
+
+// ensureSchema adds the enterprise User extension schema id to the schemas property of the resource, if absent. It is
+// usually taken care of by the SchemaSyncSubscriber already.
+func ensureSchema(resource *prop.Resource) error {
+	nav := resource.Navigator().Dot(fieldSchemas)
+	if nav.HasError() {
+		return nav.Error()
+	}
+	if nav.Current().FindChild(func(child prop.Property) bool {
+		return child.Raw() == SchemaId
+	}) != nil {
+		return nil
+	}
+	if nav.Add(SchemaId).HasError() {
+		return nav.Error()
+	}
+	return nil
+}
+
+// stringOf returns the string value of the named child of the container, or empty string if it is unassigned or not
+// a string.
+func stringOf(container prop.Property, name string) string {
+	child, err := container.ChildAtIndex(name)
+	if err != nil || child.IsUnassigned() {
+		return ""
+	}
+	s, _ := child.Raw().(string)
+	return s
+}
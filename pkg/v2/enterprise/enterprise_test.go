@@ -0,0 +1,199 @@
+package enterprise
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEnterprise(t *testing.T) {
+	s := new(EnterpriseTestSuite)
+	suite.Run(t, s)
+}
+
+type EnterpriseTestSuite struct {
+	suite.Suite
+	userResourceType  *spec.ResourceType
+	groupResourceType *spec.ResourceType
+}
+
+func (s *EnterpriseTestSuite) TestApply() {
+	tests := []struct {
+		name         string
+		resourceType func() *spec.ResourceType
+		builder      func() *Builder
+		expect       func(t *testing.T, resource *prop.Resource, err error)
+	}{
+		{
+			name:         "set typed values",
+			resourceType: func() *spec.ResourceType { return s.userResourceType },
+			builder: func() *Builder {
+				return NewBuilder().
+					EmployeeNumber("701984").
+					CostCenter("4130").
+					Organization("Universal Studios").
+					Division("Theme Park").
+					Department("Tour Operations").
+					Manager("26118915-6090-4610-87e4-49d8ca9f808d", "/Users/26118915-6090-4610-87e4-49d8ca9f808d")
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User", SchemaId},
+					resource.Navigator().Dot("schemas").Current().Raw())
+
+				user, err := Get(resource)
+				assert.Nil(t, err)
+				assert.Equal(t, &User{
+					EmployeeNumber: "701984",
+					CostCenter:     "4130",
+					Organization:   "Universal Studios",
+					Division:       "Theme Park",
+					Department:     "Tour Operations",
+					Manager: &Manager{
+						Value: "26118915-6090-4610-87e4-49d8ca9f808d",
+						Ref:   "/Users/26118915-6090-4610-87e4-49d8ca9f808d",
+					},
+				}, user)
+			},
+		},
+		{
+			name:         "set values by name",
+			resourceType: func() *spec.ResourceType { return s.userResourceType },
+			builder: func() *Builder {
+				return NewBuilder().
+					Set("department", "Sales").
+					Set("manager", map[string]interface{}{"value": "m1"}).
+					Set("department", "Engineering")
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				user, err := Get(resource)
+				assert.Nil(t, err)
+				assert.Equal(t, &User{
+					Department: "Engineering",
+					Manager:    &Manager{Value: "m1"},
+				}, user)
+			},
+		},
+		{
+			name:         "unknown field is rejected",
+			resourceType: func() *spec.ResourceType { return s.userResourceType },
+			builder: func() *Builder {
+				return NewBuilder().Department("Engineering").Set("nickName", "foo")
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Equal(t, spec.ErrInvalidPath, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "nickName")
+				user, err := Get(resource)
+				assert.Nil(t, err)
+				assert.Equal(t, &User{}, user)
+				assert.Equal(t, []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					resource.Navigator().Dot("schemas").Current().Raw())
+			},
+		},
+		{
+			name:         "value of wrong type is rejected",
+			resourceType: func() *spec.ResourceType { return s.userResourceType },
+			builder: func() *Builder {
+				return NewBuilder().Set("department", 42)
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
+		{
+			name:         "resource type without enterprise extension is rejected",
+			resourceType: func() *spec.ResourceType { return s.groupResourceType },
+			builder: func() *Builder {
+				return NewBuilder().Department("Engineering")
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				_, err = Get(resource)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			resource := prop.NewResource(test.resourceType())
+			require.False(t, resource.Navigator().Dot("schemas").Replace([]interface{}{
+				test.resourceType().Schema().ID(),
+			}).HasError())
+			err := test.builder().Apply(resource)
+			test.expect(t, resource, err)
+		})
+	}
+}
+
+func (s *EnterpriseTestSuite) TestGet() {
+	resource := prop.NewResource(s.userResourceType)
+	require.False(s.T(), resource.Navigator().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User", SchemaId},
+		"userName": "foo",
+		SchemaId: map[string]interface{}{
+			"employeeNumber": "123",
+			"manager": map[string]interface{}{
+				"value":       "m1",
+				"displayName": "Manager",
+			},
+		},
+	}).HasError())
+
+	user, err := Get(resource)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &User{
+		EmployeeNumber: "123",
+		Manager: &Manager{
+			Value:       "m1",
+			DisplayName: "Manager",
+		},
+	}, user)
+
+	user, err = Get(prop.NewResource(s.userResourceType))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), &User{}, user)
+}
+
+func (s *EnterpriseTestSuite) SetupSuite() {
+	for _, path := range []string{
+		"../../../public/schemas/core_schema.json",
+		"../../../public/schemas/user_schema.json",
+		"../../../public/schemas/user_enterprise_schema.json",
+		"../../../public/schemas/group_schema.json",
+	} {
+		raw, err := ioutil.ReadFile(path)
+		require.Nil(s.T(), err)
+		schema := new(spec.Schema)
+		require.Nil(s.T(), json.Unmarshal(raw, schema))
+		spec.Schemas().Register(schema)
+	}
+
+	s.userResourceType = new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "User",
+  "name": "User",
+  "endpoint": "/Users",
+  "schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+  "schemaExtensions": [
+    {
+      "schema": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+      "required": false
+    }
+  ]
+}
+`), s.userResourceType))
+
+	raw, err := ioutil.ReadFile("../../../public/resource_types/group_resource_type.json")
+	require.Nil(s.T(), err)
+	s.groupResourceType = new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal(raw, s.groupResourceType))
+}
@@ -2,5 +2,8 @@
 //
 // The "groups" attribute of the User resource is a readOnly attribute, which shall be updated according to the change
 // of "members" in Group resources. This package provides mere utilities that may be helpful, it does not assume a
-// certain way to resolve this issue.
+// certain way to resolve this issue. Callers wishing to keep the "groups" attribute in sync synchronously may wrap the
+// Group services with CreateService, ReplaceService, PatchService and DeleteService, which use a Propagator to update
+// the affected users. Callers opting for an asynchronous approach may use Compare and SyncService directly, and
+// periodically run a Reconciler to detect and repair any drift.
 package groupsync
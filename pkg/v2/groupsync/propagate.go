@@ -0,0 +1,139 @@
+package groupsync
+
+import (
+	"context"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+)
+
+// NewPropagator returns a new Propagator. The optional filters are invoked through FilterRef on every user resource
+// before its updated version is saved to the database. This is the place to update meta attributes.
+func NewPropagator(userDB db.DB, groupDB db.DB, filters ...filter.ByResource) *Propagator {
+	return &Propagator{
+		userDB:      userDB,
+		groupDB:     groupDB,
+		filters:     filters,
+		syncService: NewSyncService(groupDB),
+	}
+}
+
+// Propagator synchronously maintains the "groups" attribute of users affected by a change in group membership.
+type Propagator struct {
+	userDB      db.DB
+	groupDB     db.DB
+	filters     []filter.ByResource
+	syncService *SyncService
+}
+
+// Propagate updates the "groups" attribute of every user affected by the membership change described in diff. It is
+// expected to be called after the changed group has been saved to (or deleted from) the group database.
+//
+// Members that are users have their "groups" attribute re-computed and saved when changed. Members that are groups are
+// expanded, so that users gaining or losing indirect memberships through nested groups are also updated. Members that
+// are neither are ignored. Because the "groups" attribute is re-computed from the latest state, propagating the same
+// diff more than once is harmless.
+func (p *Propagator) Propagate(ctx context.Context, diff *Diff) error {
+	tasks := make([]string, 0, diff.CountJoined()+diff.CountLeft())
+	diff.ForEachJoined(func(id string) {
+		tasks = append(tasks, id)
+	})
+	diff.ForEachLeft(func(id string) {
+		tasks = append(tasks, id)
+	})
+
+	// map to record the processed member ids, so we don't fall into cycles
+	completed := map[string]struct{}{}
+
+	for len(tasks) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		id := tasks[0]
+		tasks = tasks[1:]
+
+		if _, ok := completed[id]; ok {
+			continue
+		}
+		completed[id] = struct{}{}
+
+		if isUser, err := p.syncUser(ctx, id); err != nil {
+			return err
+		} else if isUser {
+			continue
+		}
+
+		members, err := p.expandGroup(ctx, id)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, members...)
+	}
+
+	return nil
+}
+
+// syncUser re-computes the "groups" attribute of the user by id and saves it if it has changed. It returns false if
+// the user does not exist.
+func (p *Propagator) syncUser(ctx context.Context, id string) (isUser bool, err error) {
+	user, err := p.userDB.Get(ctx, id, nil)
+	if err != nil {
+		if errors.Unwrap(err) == spec.ErrNotFound {
+			err = nil
+		}
+		return
+	}
+
+	isUser = true
+
+	updated := user.Clone()
+	if err = p.syncService.SyncGroupPropertyForUser(ctx, updated); err != nil {
+		return
+	}
+	if updated.Hash() == user.Hash() {
+		return
+	}
+
+	err = save(ctx, p.userDB, p.filters, user, updated)
+	return
+}
+
+// expandGroup returns the member ids of the group by id, or an empty slice if the group does not exist.
+func (p *Propagator) expandGroup(ctx context.Context, id string) ([]string, error) {
+	group, err := p.groupDB.Get(ctx, id, nil)
+	if err != nil {
+		if errors.Unwrap(err) == spec.ErrNotFound {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	members, err := group.RootProperty().ChildAtIndex(fieldMembers)
+	if err != nil {
+		return nil, err
+	}
+	_ = members.ForEachChild(func(_ int, child prop.Property) error {
+		if id := stringValueOf(child, fieldValue); len(id) > 0 {
+			ids = append(ids, id)
+		}
+		return nil
+	})
+
+	return ids, nil
+}
+
+// save invokes the filters on the updated resource and replaces the ref resource with it in the database.
+func save(ctx context.Context, database db.DB, filters []filter.ByResource, ref *prop.Resource, updated *prop.Resource) error {
+	for _, f := range filters {
+		if err := f.FilterRef(ctx, updated, ref); err != nil {
+			return err
+		}
+	}
+	return database.Replace(ctx, ref, updated)
+}
@@ -0,0 +1,188 @@
+package groupsync
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPropagator(t *testing.T) {
+	s := new(PropagatorTestSuite)
+	suite.Run(t, s)
+}
+
+type PropagatorTestSuite struct {
+	suite.Suite
+	userResourceType  *spec.ResourceType
+	groupResourceType *spec.ResourceType
+}
+
+func (s *PropagatorTestSuite) TestPropagate() {
+	tests := []struct {
+		name   string
+		modify func(t *testing.T, userDB db.DB, groupDB db.DB) *Diff
+		expect func(t *testing.T, userDB db.DB, err error)
+	}{
+		{
+			name: "user joined group nested in another group",
+			modify: func(t *testing.T, userDB db.DB, groupDB db.DB) *Diff {
+				g1 := s.group(t, "g1", "Group One", "u1")
+				require.Nil(t, groupDB.Insert(context.Background(), g1))
+				return Compare(nil, g1)
+			},
+			expect: func(t *testing.T, userDB db.DB, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "g1:direct:Group One,g2:indirect:Group Two", s.summarize(t, userDB, "u1"))
+				assert.Equal(t, "", s.summarize(t, userDB, "u2"))
+			},
+		},
+		{
+			name: "group joined another group",
+			modify: func(t *testing.T, userDB db.DB, groupDB db.DB) *Diff {
+				require.Nil(t, groupDB.Insert(context.Background(), s.group(t, "g1", "Group One", "u1")))
+				g3 := s.group(t, "g3", "Group Three", "g1")
+				require.Nil(t, groupDB.Insert(context.Background(), g3))
+				return Compare(nil, g3)
+			},
+			expect: func(t *testing.T, userDB db.DB, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "g1:direct:Group One,g2:indirect:Group Two,g3:indirect:Group Three", s.summarize(t, userDB, "u1"))
+			},
+		},
+		{
+			name: "user left group",
+			modify: func(t *testing.T, userDB db.DB, groupDB db.DB) *Diff {
+				before := s.group(t, "g1", "Group One", "u1", "u2")
+				require.Nil(t, groupDB.Insert(context.Background(), before))
+				require.Nil(t, NewPropagator(userDB, groupDB).Propagate(context.Background(), Compare(nil, before)))
+
+				after := s.group(t, "g1", "Group One", "u2")
+				require.Nil(t, groupDB.Replace(context.Background(), before, after))
+				return Compare(before, after)
+			},
+			expect: func(t *testing.T, userDB db.DB, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "", s.summarize(t, userDB, "u1"))
+				assert.Equal(t, "g1:direct:Group One,g2:indirect:Group Two", s.summarize(t, userDB, "u2"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			userDB := s.prepareUsers(t)
+			groupDB := db.Memory()
+			require.Nil(t, groupDB.Insert(context.Background(), s.group(t, "g2", "Group Two", "g1")))
+
+			diff := test.modify(t, userDB, groupDB)
+			propagator := NewPropagator(userDB, groupDB)
+			err := propagator.Propagate(context.Background(), diff)
+			test.expect(t, userDB, err)
+
+			// propagating again takes no effect
+			summary := s.summarize(t, userDB, "u1")
+			assert.Nil(t, propagator.Propagate(context.Background(), diff))
+			assert.Equal(t, summary, s.summarize(t, userDB, "u1"))
+		})
+	}
+}
+
+func (s *PropagatorTestSuite) prepareUsers(t *testing.T) db.DB {
+	database := db.Memory()
+	for _, id := range []string{"u1", "u2"} {
+		u := prop.NewResource(s.userResourceType)
+		require.False(t, u.Navigator().Replace(map[string]interface{}{
+			"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":       id,
+			"userName": id,
+		}).HasError())
+		require.Nil(t, database.Insert(context.Background(), u))
+	}
+	return database
+}
+
+func (s *PropagatorTestSuite) group(t *testing.T, id string, displayName string, memberIds ...string) *prop.Resource {
+	members := make([]interface{}, 0)
+	for _, memberId := range memberIds {
+		members = append(members, map[string]interface{}{"value": memberId})
+	}
+	g := prop.NewResource(s.groupResourceType)
+	require.False(t, g.Navigator().Replace(map[string]interface{}{
+		"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+		"id":          id,
+		"displayName": displayName,
+		"members":     members,
+	}).HasError())
+	return g
+}
+
+func (s *PropagatorTestSuite) summarize(t *testing.T, userDB db.DB, id string) string {
+	u, err := userDB.Get(context.Background(), id, nil)
+	require.Nil(t, err)
+	return summarizeGroups(u)
+}
+
+func (s *PropagatorTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/group_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.userResourceType = parsed.(*spec.ResourceType)
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/group_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.groupResourceType = parsed.(*spec.ResourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+}
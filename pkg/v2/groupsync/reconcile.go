@@ -0,0 +1,324 @@
+package groupsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	fieldDisplay     = "display"
+	fieldDisplayName = "displayName"
+	fieldGroups      = "groups"
+	fieldType        = "type"
+)
+
+// Kinds of discrepancy that can be detected by the Reconciler.
+const (
+	// DanglingMember indicates a group member that refers to neither an existing user nor an existing group.
+	DanglingMember DiscrepancyKind = iota
+	// StaleDisplay indicates a group member whose "display" value differs from the "displayName" of the
+	// referenced resource.
+	StaleDisplay
+	// StaleGroups indicates a user whose "groups" attribute does not reflect the actual group memberships.
+	StaleGroups
+)
+
+// DiscrepancyKind describes the nature of a Discrepancy.
+type DiscrepancyKind int
+
+// String returns a human readable name of the discrepancy kind.
+func (k DiscrepancyKind) String() string {
+	switch k {
+	case DanglingMember:
+		return "danglingMember"
+	case StaleDisplay:
+		return "staleDisplay"
+	case StaleGroups:
+		return "staleGroups"
+	default:
+		return "unknown"
+	}
+}
+
+// Discrepancy is a single drift found by the Reconciler.
+type Discrepancy struct {
+	// Kind of the discrepancy.
+	Kind DiscrepancyKind
+	// ResourceID is the id of the resource that holds the drifted data. It is the group id for
+	// DanglingMember and StaleDisplay, and the user id for StaleGroups.
+	ResourceID string
+	// MemberID is the id of the concerned member. It is empty for StaleGroups.
+	MemberID string
+	// Actual is the summary of the current value; Expected is the summary of the value that should have been.
+	Actual   string
+	Expected string
+	// Repaired is true when the discrepancy was fixed and the fix was saved to the database.
+	Repaired bool
+}
+
+// Report is the structured result of a reconciliation pass.
+type Report struct {
+	// GroupsScanned is the number of groups examined.
+	GroupsScanned int
+	// UsersScanned is the number of users examined.
+	UsersScanned int
+	// Discrepancies lists all the drifts found, in the order they were found.
+	Discrepancies []*Discrepancy
+}
+
+// Count returns the number of discrepancies of the given kind.
+func (r *Report) Count(kind DiscrepancyKind) int {
+	n := 0
+	for _, d := range r.Discrepancies {
+		if d.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// CountRepaired returns the number of discrepancies that were repaired.
+func (r *Report) CountRepaired() int {
+	n := 0
+	for _, d := range r.Discrepancies {
+		if d.Repaired {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(d *Discrepancy) {
+	r.Discrepancies = append(r.Discrepancies, d)
+}
+
+// NewReconciler returns a new Reconciler. The optional filters are invoked through FilterRef on every resource
+// before a repaired version of it is saved to the database. This is the place to update meta attributes, so that
+// repaired resources receive a new version.
+func NewReconciler(userDB db.DB, groupDB db.DB, filters ...filter.ByResource) *Reconciler {
+	return &Reconciler{
+		userDB:      userDB,
+		groupDB:     groupDB,
+		filters:     filters,
+		syncService: NewSyncService(groupDB),
+	}
+}
+
+// Reconciler verifies that the group memberships are eventually consistent, and optionally repairs the drift.
+type Reconciler struct {
+	userDB      db.DB
+	groupDB     db.DB
+	filters     []filter.ByResource
+	syncService *SyncService
+}
+
+// Reconcile scans all groups and cross-checks each member against the referenced user or group resource. Members
+// that refer to neither are reported as DanglingMember, members whose "display" does not match the "displayName" of the
+// referenced resource are reported as StaleDisplay. Afterwards, it scans all users and reports those whose "groups"
+// attribute does not reflect the actual (direct and indirect) memberships as StaleGroups.
+//
+// When repair is false, the databases are not modified. When repair is true, dangling members are removed, stale
+// display values are refreshed, and the "groups" attribute of users are re-computed. Groups are repaired before users
+// are checked, so that the re-computed memberships do not include the removed dangling members.
+//
+// The ctx context can be used to cancel the processing. Any error aborts the pass; the report returned alongside
+// contains the discrepancies found until that point.
+func (r *Reconciler) Reconcile(ctx context.Context, repair bool) (*Report, error) {
+	report := new(Report)
+	if err := r.reconcileGroups(ctx, repair, report); err != nil {
+		return report, err
+	}
+	if err := r.reconcileUsers(ctx, repair, report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func (r *Reconciler) reconcileGroups(ctx context.Context, repair bool, report *Report) error {
+	groups, err := r.groupDB.Query(ctx, "id pr", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		report.GroupsScanned++
+
+		found := make([]*Discrepancy, 0)
+		members, err := group.RootProperty().ChildAtIndex(fieldMembers)
+		if err != nil {
+			return err
+		}
+		if err := members.ForEachChild(func(_ int, child prop.Property) error {
+			memberId := stringValueOf(child, fieldValue)
+			if len(memberId) == 0 {
+				return nil
+			}
+
+			referenced, err := r.lookupMember(ctx, memberId)
+			if err != nil {
+				return err
+			}
+			if referenced == nil {
+				found = append(found, &Discrepancy{
+					Kind:       DanglingMember,
+					ResourceID: group.IdOrEmpty(),
+					MemberID:   memberId,
+					Actual:     memberId,
+				})
+				return nil
+			}
+
+			expected := stringValueOf(referenced.RootProperty(), fieldDisplayName)
+			actual := stringValueOf(child, fieldDisplay)
+			if len(expected) > 0 && expected != actual {
+				found = append(found, &Discrepancy{
+					Kind:       StaleDisplay,
+					ResourceID: group.IdOrEmpty(),
+					MemberID:   memberId,
+					Actual:     actual,
+					Expected:   expected,
+				})
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		for _, d := range found {
+			report.add(d)
+		}
+
+		if !repair || len(found) == 0 {
+			continue
+		}
+
+		repaired := group.Clone()
+		for _, d := range found {
+			valuePath := fmt.Sprintf("%s[%s eq %s]", fieldMembers, fieldValue, strconv.Quote(d.MemberID))
+			switch d.Kind {
+			case DanglingMember:
+				err = crud.Delete(repaired, valuePath)
+			case StaleDisplay:
+				err = crud.Replace(repaired, valuePath+"."+fieldDisplay, d.Expected)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := save(ctx, r.groupDB, r.filters, group, repaired); err != nil {
+			return err
+		}
+		for _, d := range found {
+			d.Repaired = true
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) reconcileUsers(ctx context.Context, repair bool, report *Report) error {
+	users, err := r.userDB.Query(ctx, "id pr", nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		report.UsersScanned++
+
+		synced := user.Clone()
+		if err := r.syncService.SyncGroupPropertyForUser(ctx, synced); err != nil {
+			return err
+		}
+
+		actual, expected := summarizeGroups(user), summarizeGroups(synced)
+		if actual == expected {
+			continue
+		}
+
+		d := &Discrepancy{
+			Kind:       StaleGroups,
+			ResourceID: user.IdOrEmpty(),
+			Actual:     actual,
+			Expected:   expected,
+		}
+		report.add(d)
+
+		if !repair {
+			continue
+		}
+		if err := save(ctx, r.userDB, r.filters, user, synced); err != nil {
+			return err
+		}
+		d.Repaired = true
+	}
+
+	return nil
+}
+
+// lookupMember returns the user or group resource referenced by the member id, or nil if neither exists.
+func (r *Reconciler) lookupMember(ctx context.Context, memberId string) (*prop.Resource, error) {
+	for _, database := range []db.DB{r.userDB, r.groupDB} {
+		resource, err := database.Get(ctx, memberId, nil)
+		if err == nil {
+			return resource, nil
+		} else if errors.Unwrap(err) != spec.ErrNotFound {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// summarizeGroups returns a order-independent summary of the "groups" attribute of the user, in the format of
+// comma delimited "<value>:<type>:<display>" entries.
+func summarizeGroups(user *prop.Resource) string {
+	groups, err := user.RootProperty().ChildAtIndex(fieldGroups)
+	if err != nil {
+		return ""
+	}
+
+	entries := make([]string, 0)
+	_ = groups.ForEachChild(func(_ int, child prop.Property) error {
+		if child.IsUnassigned() {
+			return nil
+		}
+		entries = append(entries, strings.Join([]string{
+			stringValueOf(child, fieldValue),
+			stringValueOf(child, fieldType),
+			stringValueOf(child, fieldDisplay),
+		}, ":"))
+		return nil
+	})
+	sort.Strings(entries)
+
+	return strings.Join(entries, ",")
+}
+
+func stringValueOf(property prop.Property, name string) string {
+	child, err := property.ChildAtIndex(name)
+	if err != nil || child == nil || child.IsUnassigned() {
+		return ""
+	}
+	s, _ := child.Raw().(string)
+	return s
+}
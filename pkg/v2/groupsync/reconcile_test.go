@@ -0,0 +1,213 @@
+package groupsync
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReconciler(t *testing.T) {
+	s := new(ReconcilerTestSuite)
+	suite.Run(t, s)
+}
+
+type ReconcilerTestSuite struct {
+	suite.Suite
+	userResourceType  *spec.ResourceType
+	groupResourceType *spec.ResourceType
+}
+
+func (s *ReconcilerTestSuite) TestReconcile() {
+	tests := []struct {
+		name   string
+		repair bool
+		expect func(t *testing.T, userDB db.DB, groupDB db.DB, report *Report, err error)
+	}{
+		{
+			name:   "report only",
+			repair: false,
+			expect: func(t *testing.T, userDB db.DB, groupDB db.DB, report *Report, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 2, report.GroupsScanned)
+				assert.Equal(t, 2, report.UsersScanned)
+				assert.Equal(t, 1, report.Count(DanglingMember))
+				assert.Equal(t, 1, report.Count(StaleDisplay))
+				assert.Equal(t, 2, report.Count(StaleGroups))
+				assert.Equal(t, 0, report.CountRepaired())
+
+				g1, err := groupDB.Get(context.Background(), "g1", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, 3, g1.Navigator().Dot("members").Current().CountChildren())
+
+				u1, err := userDB.Get(context.Background(), "u1", nil)
+				assert.Nil(t, err)
+				assert.True(t, u1.Navigator().Dot("groups").Current().IsUnassigned())
+			},
+		},
+		{
+			name:   "repair",
+			repair: true,
+			expect: func(t *testing.T, userDB db.DB, groupDB db.DB, report *Report, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 4, report.CountRepaired())
+
+				g1, err := groupDB.Get(context.Background(), "g1", nil)
+				assert.Nil(t, err)
+				members := map[string]string{}
+				_ = g1.Navigator().Dot("members").ForEachChild(func(_ int, child prop.Property) error {
+					members[stringValueOf(child, fieldValue)] = stringValueOf(child, fieldDisplay)
+					return nil
+				})
+				assert.Len(t, members, 2)
+				assert.Equal(t, "User One", members["u1"])
+				assert.Equal(t, "User Two", members["u2"])
+
+				u1, err := userDB.Get(context.Background(), "u1", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, "g1:direct:Group One,g2:indirect:Group Two", summarizeGroups(u1))
+
+				again, err := NewReconciler(userDB, groupDB).Reconcile(context.Background(), false)
+				assert.Nil(t, err)
+				assert.Len(t, again.Discrepancies, 0)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			userDB, groupDB := s.prepareDatabases(t)
+			report, err := NewReconciler(userDB, groupDB).Reconcile(context.Background(), test.repair)
+			test.expect(t, userDB, groupDB, report, err)
+		})
+	}
+}
+
+// Prepares users u1 and u2, none of which have the "groups" attribute populated, and groups g1 and g2. Group g1 has
+// members u1, u2 (with stale display) and u3 (dangling). Group g2 has member g1.
+func (s *ReconcilerTestSuite) prepareDatabases(t *testing.T) (userDB db.DB, groupDB db.DB) {
+	userDB, groupDB = db.Memory(), db.Memory()
+	for _, data := range []map[string]interface{}{
+		{
+			"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":          "u1",
+			"userName":    "u1",
+			"displayName": "User One",
+		},
+		{
+			"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":          "u2",
+			"userName":    "u2",
+			"displayName": "User Two",
+		},
+	} {
+		u := prop.NewResource(s.userResourceType)
+		require.False(t, u.Navigator().Replace(data).HasError())
+		require.Nil(t, userDB.Insert(context.Background(), u))
+	}
+	for _, data := range []map[string]interface{}{
+		{
+			"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+			"id":          "g1",
+			"displayName": "Group One",
+			"members": []interface{}{
+				map[string]interface{}{
+					"value":   "u1",
+					"$ref":    "/Users/u1",
+					"display": "User One",
+				},
+				map[string]interface{}{
+					"value":   "u2",
+					"$ref":    "/Users/u2",
+					"display": "Old Name",
+				},
+				map[string]interface{}{
+					"value":   "u3",
+					"$ref":    "/Users/u3",
+					"display": "User Three",
+				},
+			},
+		},
+		{
+			"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+			"id":          "g2",
+			"displayName": "Group Two",
+			"members": []interface{}{
+				map[string]interface{}{
+					"value":   "g1",
+					"$ref":    "/Groups/g1",
+					"display": "Group One",
+				},
+			},
+		},
+	} {
+		g := prop.NewResource(s.groupResourceType)
+		require.False(t, g.Navigator().Replace(data).HasError())
+		require.Nil(t, groupDB.Insert(context.Background(), g))
+	}
+	return
+}
+
+func (s *ReconcilerTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/group_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.userResourceType = parsed.(*spec.ResourceType)
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/group_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.groupResourceType = parsed.(*spec.ResourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+}
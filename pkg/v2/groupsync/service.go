@@ -0,0 +1,90 @@
+package groupsync
+
+import (
+	"context"
+	"github.com/imulab/go-scim/pkg/v2/service"
+)
+
+// CreateService returns a service.Create that wraps the Group create service and, once the group is created, propagates
+// the membership of its members to the "groups" attribute of affected users via the Propagator.
+func CreateService(create service.Create, propagator *Propagator) service.Create {
+	return &groupCreated{service: create, propagator: propagator}
+}
+
+// ReplaceService returns a service.Replace that wraps the Group replace service and, once the group is replaced,
+// propagates the membership changes to the "groups" attribute of affected users via the Propagator.
+func ReplaceService(replace service.Replace, propagator *Propagator) service.Replace {
+	return &groupReplaced{service: replace, propagator: propagator}
+}
+
+// PatchService returns a service.Patch that wraps the Group patch service and, once the group is patched, propagates
+// the membership changes to the "groups" attribute of affected users via the Propagator.
+func PatchService(patch service.Patch, propagator *Propagator) service.Patch {
+	return &groupPatched{service: patch, propagator: propagator}
+}
+
+// DeleteService returns a service.Delete that wraps the Group delete service and, once the group is deleted, removes
+// the membership from the "groups" attribute of affected users via the Propagator.
+func DeleteService(del service.Delete, propagator *Propagator) service.Delete {
+	return &groupDeleted{service: del, propagator: propagator}
+}
+
+type groupCreated struct {
+	service    service.Create
+	propagator *Propagator
+}
+
+func (s *groupCreated) Do(ctx context.Context, req *service.CreateRequest) (resp *service.CreateResponse, err error) {
+	resp, err = s.service.Do(ctx, req)
+	if err != nil {
+		return
+	}
+
+	err = s.propagator.Propagate(ctx, Compare(nil, resp.Resource))
+	return
+}
+
+type groupReplaced struct {
+	service    service.Replace
+	propagator *Propagator
+}
+
+func (s *groupReplaced) Do(ctx context.Context, req *service.ReplaceRequest) (resp *service.ReplaceResponse, err error) {
+	resp, err = s.service.Do(ctx, req)
+	if err != nil || !resp.Replaced {
+		return
+	}
+
+	err = s.propagator.Propagate(ctx, Compare(resp.Ref, resp.Resource))
+	return
+}
+
+type groupPatched struct {
+	service    service.Patch
+	propagator *Propagator
+}
+
+func (s *groupPatched) Do(ctx context.Context, req *service.PatchRequest) (resp *service.PatchResponse, err error) {
+	resp, err = s.service.Do(ctx, req)
+	if err != nil || !resp.Patched {
+		return
+	}
+
+	err = s.propagator.Propagate(ctx, Compare(resp.Ref, resp.Resource))
+	return
+}
+
+type groupDeleted struct {
+	service    service.Delete
+	propagator *Propagator
+}
+
+func (s *groupDeleted) Do(ctx context.Context, req *service.DeleteRequest) (resp *service.DeleteResponse, err error) {
+	resp, err = s.service.Do(ctx, req)
+	if err != nil {
+		return
+	}
+
+	err = s.propagator.Propagate(ctx, Compare(resp.Deleted, nil))
+	return
+}
@@ -23,6 +23,9 @@ type SyncService struct {
 // SyncGroupPropertyForUser updates the user's "groups" property, according to the latest state in Group resources. This
 // method does not save or replace the updated resource with the database. It is up to the caller to do so.
 //
+// The "groups" property is re-computed from scratch each time, and each group appears at most once, with "direct" taking
+// precedence over "indirect". Hence, calling this method repeatedly on the same state yields the same result.
+//
 // Due to nested membership, this method may search the group database multiple times, which may turn out to be a lengthy
 // process. The ctx context can be used to set a timeline or cancel the processing, this method will respect that at
 // appropriate intervals.
@@ -46,8 +49,10 @@ func (s *SyncService) SyncGroupPropertyForUser(ctx context.Context, user *prop.R
 		{member: user.IdOrEmpty(), direct: true},
 	}
 
-	// map to record the completed group ids, so we don't fall into cycles
-	completed := map[string]struct{}{}
+	// map to record the group ids already added to the property, so we neither create duplicate
+	// entries (i.e. a group reachable both directly and indirectly) nor fall into cycles. Because
+	// tasks are processed in breadth first order, direct memberships are always recorded first.
+	recorded := map[string]struct{}{}
 
 	for len(tasks) > 0 {
 		// check if context was closed
@@ -66,6 +71,12 @@ func (s *SyncService) SyncGroupPropertyForUser(ctx context.Context, user *prop.R
 			return err
 		}
 		for _, group := range groups {
+			groupId := group.IdOrEmpty()
+			if _, ok := recorded[groupId]; ok {
+				continue
+			}
+			recorded[groupId] = struct{}{}
+
 			// create new group element and modify the value
 			if err := func() error {
 				index := groupNav.Current().(interface {
@@ -82,18 +93,12 @@ func (s *SyncService) SyncGroupPropertyForUser(ctx context.Context, user *prop.R
 				return err
 			}
 
-			// submit new indirect tasks if not processed before
-			groupId := group.IdOrEmpty()
-			if _, processed := completed[groupId]; !processed {
-				tasks = append(tasks, task{
-					member: groupId,
-					direct: false,
-				})
-			}
+			// submit new indirect task
+			tasks = append(tasks, task{
+				member: groupId,
+				direct: false,
+			})
 		}
-
-		// mark this task as completed
-		completed[t.member] = struct{}{}
 	}
 
 	return nil
@@ -104,6 +104,67 @@ func (s *SyncServiceTestSuite) TestSyncGroupPropertyForUser() {
 				assert.True(t, hasG2)
 			},
 		},
+		{
+			name: "group reachable both directly and indirectly is recorded once as direct",
+			getUser: func(t *testing.T) *prop.Resource {
+				u := prop.NewResource(s.userResourceType)
+				assert.False(t, u.Navigator().Replace(map[string]interface{}{
+					"schemas": []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"id":      "u1",
+				}).HasError())
+				return u
+			},
+			getGroupDB: func(t *testing.T) db.DB {
+				database := db.Memory()
+				for _, data := range []map[string]interface{}{
+					{
+						"schemas": []interface{}{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+						"id":      "g1",
+						"members": []interface{}{
+							map[string]interface{}{
+								"value": "u1",
+							},
+							map[string]interface{}{
+								"value": "g2",
+							},
+						},
+					},
+					{
+						"schemas": []interface{}{"urn:ietf:params:scim:schemas:core:2.0:Group"},
+						"id":      "g2",
+						"members": []interface{}{
+							map[string]interface{}{
+								"value": "u1",
+							},
+							map[string]interface{}{
+								"value": "g1",
+							},
+						},
+					},
+				} {
+					g := prop.NewResource(s.groupResourceType)
+					assert.False(t, g.Navigator().Replace(data).HasError())
+					assert.Nil(t, database.Insert(context.Background(), g))
+				}
+				return database
+			},
+			expect: func(t *testing.T, user *prop.Resource, err error) {
+				assert.Nil(t, err)
+
+				types := map[string]string{}
+				n := 0
+				_ = user.Navigator().Dot("groups").ForEachChild(func(_ int, child prop.Property) error {
+					v, _ := child.ChildAtIndex("value")
+					k, _ := child.ChildAtIndex("type")
+					types[v.Raw().(string)] = k.Raw().(string)
+					n++
+					return nil
+				})
+				assert.Equal(t, 2, n)
+				assert.Equal(t, "direct", types["g1"])
+				assert.Equal(t, "direct", types["g2"])
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,86 @@
+package handlerutil
+
+import (
+	"context"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"net/http"
+	"strings"
+)
+
+type contextKey int
+
+const (
+	resourceTypeContextKey contextKey = iota
+	schemasContextKey
+	locationContextKey
+)
+
+// ResourceTypeContext returns a http.Handler that resolves the resource type of the incoming request by its path, and
+// stores the resource type, along with its schemas, in the request context before invoking next. Downstream handlers
+// may then retrieve them with ResourceTypeFromContext and SchemasFromContext.
+//
+// The resourceTypes maps path prefixes (i.e. "/Users") to the resource types served under them. A prefix matches the
+// request path when it is the same as the path, or is followed by a slash in the path, so that "/Users" matches
+// "/Users", "/Users/foo" and "/Users/.search", but not "/UsersFoo". When more than one prefix matches, the longest one
+// wins. Requests whose path is not matched by any prefix are responded with a spec.ErrNotFound error using WriteError,
+// and next is not invoked.
+func ResourceTypeContext(next http.Handler, resourceTypes map[string]*spec.ResourceType) http.Handler {
+	prefixes := make(map[string]*spec.ResourceType, len(resourceTypes))
+	for prefix, resourceType := range resourceTypes {
+		prefixes[strings.TrimSuffix(prefix, "/")] = resourceType
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		resourceType := resolveResourceType(prefixes, r.URL.Path)
+		if resourceType == nil {
+			_ = WriteError(rw, fmt.Errorf("%w: no resource type is served at '%s'", spec.ErrNotFound, r.URL.Path))
+			return
+		}
+		next.ServeHTTP(rw, r.WithContext(ContextWithResourceType(r.Context(), resourceType)))
+	})
+}
+
+// ContextWithResourceType returns a copy of the context that carries the resource type and its schemas, which are its
+// main schema followed by its schema extensions. It is used by ResourceTypeContext, and can be used directly by
+// callers resolving the resource type in other ways.
+func ContextWithResourceType(ctx context.Context, resourceType *spec.ResourceType) context.Context {
+	schemas := []*spec.Schema{resourceType.Schema()}
+	_ = resourceType.ForEachExtension(func(extension *spec.Schema, _ bool) error {
+		schemas = append(schemas, extension)
+		return nil
+	})
+	ctx = context.WithValue(ctx, resourceTypeContextKey, resourceType)
+	return context.WithValue(ctx, schemasContextKey, schemas)
+}
+
+// ResourceTypeFromContext returns the resource type stored in the context by ResourceTypeContext, or false if the
+// context does not carry one.
+func ResourceTypeFromContext(ctx context.Context) (*spec.ResourceType, bool) {
+	resourceType, ok := ctx.Value(resourceTypeContextKey).(*spec.ResourceType)
+	return resourceType, ok
+}
+
+// SchemasFromContext returns the schemas of the resource type stored in the context by ResourceTypeContext, which are
+// its main schema followed by its schema extensions, or false if the context does not carry a resource type.
+func SchemasFromContext(ctx context.Context) ([]*spec.Schema, bool) {
+	schemas, ok := ctx.Value(schemasContextKey).([]*spec.Schema)
+	return schemas, ok
+}
+
+// resolveResourceType returns the resource type mapped by the longest prefix matching the path, or nil if none matches.
+func resolveResourceType(prefixes map[string]*spec.ResourceType, path string) *spec.ResourceType {
+	var (
+		match   *spec.ResourceType
+		longest = -1
+	)
+	for prefix, resourceType := range prefixes {
+		if !strings.HasPrefix(path, prefix) || len(prefix) <= longest {
+			continue
+		}
+		if len(path) == len(prefix) || path[len(prefix)] == '/' {
+			match, longest = resourceType, len(prefix)
+		}
+	}
+	return match
+}
@@ -0,0 +1,145 @@
+package handlerutil
+
+import (
+	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+)
+
+func TestResourceTypeContext(t *testing.T) {
+	var (
+		userResourceType  = testUserResourceType(t)
+		groupResourceType = new(spec.ResourceType)
+		adminResourceType = new(spec.ResourceType)
+	)
+	{
+		for _, path := range []string{
+			"../../../public/schemas/group_schema.json",
+			"../../../public/schemas/user_enterprise_schema.json",
+		} {
+			raw, err := ioutil.ReadFile(path)
+			require.Nil(t, err)
+			schema := new(spec.Schema)
+			require.Nil(t, json.Unmarshal(raw, schema))
+			spec.Schemas().Register(schema)
+		}
+
+		raw, err := ioutil.ReadFile("../../../public/resource_types/group_resource_type.json")
+		require.Nil(t, err)
+		require.Nil(t, json.Unmarshal(raw, groupResourceType))
+
+		require.Nil(t, json.Unmarshal([]byte(`
+{
+  "id": "Admin",
+  "name": "Admin",
+  "endpoint": "/Users/Admins",
+  "schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+  "schemaExtensions": [
+    {
+      "schema": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+      "required": true
+    }
+  ]
+}
+`), adminResourceType))
+	}
+
+	handler := ResourceTypeContext(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		resourceType, ok := ResourceTypeFromContext(r.Context())
+		require.True(t, ok)
+		schemas, ok := SchemasFromContext(r.Context())
+		require.True(t, ok)
+
+		rw.Header().Set("X-Resource-Type", resourceType.ID())
+		for _, schema := range schemas {
+			rw.Header().Add("X-Schema", schema.ID())
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}), map[string]*spec.ResourceType{
+		"/Users":         userResourceType,
+		"/Users/Admins/": adminResourceType,
+		"/Groups":        groupResourceType,
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		expect func(t *testing.T, rw *httptest.ResponseRecorder)
+	}{
+		{
+			name: "resource type endpoint",
+			path: "/Users",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Equal(t, "User", rw.Header().Get("X-Resource-Type"))
+				assert.Equal(t, []string{"urn:ietf:params:scim:schemas:core:2.0:User"}, rw.Header()[textproto.CanonicalMIMEHeaderKey("X-Schema")])
+			},
+		},
+		{
+			name: "resource endpoint",
+			path: "/Groups/foo",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Equal(t, "Group", rw.Header().Get("X-Resource-Type"))
+			},
+		},
+		{
+			name: "search endpoint",
+			path: "/Users/.search",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Equal(t, "User", rw.Header().Get("X-Resource-Type"))
+			},
+		},
+		{
+			name: "longest prefix wins",
+			path: "/Users/Admins/foo",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Equal(t, "Admin", rw.Header().Get("X-Resource-Type"))
+				assert.Equal(t, []string{
+					"urn:ietf:params:scim:schemas:core:2.0:User",
+					"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+				}, rw.Header()[textproto.CanonicalMIMEHeaderKey("X-Schema")])
+			},
+		},
+		{
+			name: "prefix only matches whole path segments",
+			path: "/UsersFoo",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rw.Code)
+				assert.Contains(t, rw.Body.String(), "notFound")
+				assert.Empty(t, rw.Header().Get("X-Resource-Type"))
+			},
+		},
+		{
+			name: "unmapped path",
+			path: "/Devices/foo",
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNotFound, rw.Code)
+				assert.Contains(t, rw.Body.String(), "/Devices/foo")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, test.path, nil))
+			test.expect(t, rw)
+		})
+	}
+}
+
+func TestResourceTypeFromContext(t *testing.T) {
+	_, ok := ResourceTypeFromContext(httptest.NewRequest(http.MethodGet, "/Users", nil).Context())
+	assert.False(t, ok)
+	_, ok = SchemasFromContext(httptest.NewRequest(http.MethodGet, "/Users", nil).Context())
+	assert.False(t, ok)
+}
@@ -0,0 +1,105 @@
+package handlerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"sync"
+)
+
+// ErrorRenderer renders the body of the error response written by WriteError. It receives the resolved http status,
+// the SCIM error type, the detail message and the extras carried by the *spec.Error, if any. The extras map must
+// not be modified.
+type ErrorRenderer interface {
+	RenderError(status int, scimType string, detail string, extras map[string]interface{}) ([]byte, error)
+}
+
+// ErrorRendererFunc is an adapter to allow the use of ordinary functions as ErrorRenderer.
+type ErrorRendererFunc func(status int, scimType string, detail string, extras map[string]interface{}) ([]byte, error)
+
+func (f ErrorRendererFunc) RenderError(status int, scimType string, detail string, extras map[string]interface{}) ([]byte, error) {
+	return f(status, scimType, detail, extras)
+}
+
+// DefaultErrorRenderer returns the ErrorRenderer that renders the SCIM Error message, as defined in RFC7644
+// Section 3.12. Extras, if any, are rendered as additional fields of the message, unless they collide with the
+// standard fields.
+func DefaultErrorRenderer() ErrorRenderer {
+	return ErrorRendererFunc(renderScimError)
+}
+
+// SetErrorRenderer installs the ErrorRenderer to be used by WriteError. A nil renderer restores DefaultErrorRenderer.
+// It is intended to be called once during application setup.
+func SetErrorRenderer(renderer ErrorRenderer) {
+	errorRendererMu.Lock()
+	defer errorRendererMu.Unlock()
+	errorRenderer = renderer
+}
+
+var (
+	errorRenderer   ErrorRenderer
+	errorRendererMu sync.RWMutex
+)
+
+func currentErrorRenderer() ErrorRenderer {
+	errorRendererMu.RLock()
+	defer errorRendererMu.RUnlock()
+	if errorRenderer == nil {
+		return DefaultErrorRenderer()
+	}
+	return errorRenderer
+}
+
+// resolveError returns the status, type and extras of err if it is a *spec.Error, or of the *spec.Error that caused err,
+// or those of spec.ErrInternal if err was not caused by a *spec.Error.
+func resolveError(err error) (status int, scimType string, extras map[string]interface{}) {
+	if scimError, ok := err.(*spec.Error); ok {
+		return scimError.Status, scimError.Type, scimError.Extras
+	}
+	if scimError, ok := errors.Unwrap(err).(*spec.Error); ok {
+		return scimError.Status, scimError.Type, scimError.Extras
+	}
+	return spec.ErrInternal.Status, spec.ErrInternal.Type, nil
+}
+
+func renderScimError(status int, scimType string, detail string, extras map[string]interface{}) ([]byte, error) {
+	raw, err := json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   int      `json:"status"`
+		ScimType string   `json:"scimType"`
+		Detail   string   `json:"detail"`
+	}{
+		Schemas:  []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+		Status:   status,
+		ScimType: scimType,
+		Detail:   detail,
+	})
+	if err != nil || len(extras) == 0 {
+		return raw, err
+	}
+
+	fields := make(map[string]interface{}, len(extras))
+	for k, v := range extras {
+		switch k {
+		case "schemas", "status", "scimType", "detail":
+		default:
+			fields[k] = v
+		}
+	}
+	if len(fields) == 0 {
+		return raw, nil
+	}
+
+	rawExtras, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// splice the extras into the message: {...standard fields...,...extra fields...}
+	var buf bytes.Buffer
+	buf.Write(raw[:len(raw)-1])
+	buf.WriteByte(',')
+	buf.Write(rawExtras[1:])
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,94 @@
+package handlerutil
+
+import (
+	"context"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"net/http"
+	"strings"
+)
+
+// LocationBuilder computes the Location header of the response for the resource, from the request being responded.
+// It allows the externally visible URL of the resource to differ from the meta.location stored with it, i.e. when the
+// server is behind a reverse proxy, or serves multiple hostnames. An empty result falls back to meta.location.
+type LocationBuilder func(r *http.Request, resource *prop.Resource) string
+
+// LocationContext returns a http.Handler that stores the builder, bound to the incoming request, in the request context
+// before invoking next. This is how a LocationBuilder is configured for the handlers it wraps: the request aware
+// response writers (i.e. WriteResourceToResponseFor) use it to set the Location header, and LocationFromContext
+// exposes it to code only holding the context. A nil builder leaves the request as is, so that the resource's
+// meta.location is used.
+func LocationContext(next http.Handler, builder LocationBuilder) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if builder == nil {
+			next.ServeHTTP(rw, r)
+			return
+		}
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), locationContextKey, locationFunc(func(resource *prop.Resource) string {
+			return builder(r, resource)
+		}))))
+	})
+}
+
+// LocationFromContext returns the location of the resource computed by the LocationBuilder configured with
+// LocationContext for the request of the context. It returns empty string when the context does not carry a
+// LocationBuilder, or when the builder returns empty.
+func LocationFromContext(ctx context.Context, resource *prop.Resource) string {
+	if f, ok := ctx.Value(locationContextKey).(locationFunc); ok {
+		return f(resource)
+	}
+	return ""
+}
+
+// ForwardedLocationBuilder returns a LocationBuilder that forms an absolute URL of the resource from the scheme and host
+// the client used to reach the server, followed by basePath, the resource type endpoint and the resource id. The scheme
+// and host are taken from the X-Forwarded-Proto and X-Forwarded-Host headers set by the reverse proxy, if any, and from
+// the request itself otherwise. As these headers are supplied by the client, the builder shall only be installed when
+// the reverse proxy in front of the server overwrites them.
+func ForwardedLocationBuilder(basePath string) LocationBuilder {
+	basePath = strings.TrimSuffix(basePath, "/")
+	return func(r *http.Request, resource *prop.Resource) string {
+		id := resource.IdOrEmpty()
+		if len(id) == 0 {
+			return ""
+		}
+
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		if proto := firstForwarded(r.Header.Get("X-Forwarded-Proto")); len(proto) > 0 {
+			scheme = proto
+		}
+
+		host := r.Host
+		if forwarded := firstForwarded(r.Header.Get("X-Forwarded-Host")); len(forwarded) > 0 {
+			host = forwarded
+		}
+
+		endpoint := strings.TrimSuffix(resource.ResourceType().Endpoint(), "/")
+		return scheme + "://" + host + basePath + endpoint + "/" + id
+	}
+}
+
+// firstForwarded returns the first of the comma separated values of the X-Forwarded-* header, which is the one set by
+// the proxy closest to the client.
+func firstForwarded(value string) string {
+	if i := strings.IndexByte(value, ','); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(value)
+}
+
+// locationFunc is the LocationBuilder bound to the request, stored in the request context by LocationContext.
+type locationFunc func(resource *prop.Resource) string
+
+// resourceLocation returns the Location header value for the resource, computed by the LocationBuilder configured for
+// the request if any, or the resource's meta.location otherwise. The request may be nil.
+func resourceLocation(r *http.Request, resource *prop.Resource) string {
+	if r != nil {
+		if location := LocationFromContext(r.Context(), resource); len(location) > 0 {
+			return location
+		}
+	}
+	return resource.MetaLocationOrEmpty()
+}
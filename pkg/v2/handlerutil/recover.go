@@ -0,0 +1,102 @@
+package handlerutil
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicLogger is invoked by Recover with the request being served, the recovered panic value and the stack trace of
+// the panicking goroutine. It is the place to log the panic via a structured logger.
+type PanicLogger func(r *http.Request, recovered interface{}, stack []byte)
+
+// Recover returns a http.Handler that invokes next and recovers from any panic during the process. The recovered panic
+// is reported to logger, if not nil, and a spec.ErrInternal error is written to the response using WriteError.
+//
+// If the response status had already been written by the time of the panic (i.e. panic occurred mid-serialization),
+// the error cannot be written anymore and the panic is only reported to logger. Otherwise, headers set by next are
+// discarded so the error response is rendered with the correct Content-Type.
+//
+// Panics with http.ErrAbortHandler are not recovered, as they are deliberately used to abort the response.
+//
+// The http.ResponseWriter passed to next implements http.Flusher, http.Hijacker and http.Pusher, forwarding to the
+// underlying writer. Flush is a no-op, while Hijack and Push return http.ErrNotSupported, when the underlying writer
+// does not support them.
+func Recover(next http.Handler, logger PanicLogger) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		trw := &trackingResponseWriter{ResponseWriter: rw}
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			if logger != nil {
+				logger(r, recovered, debug.Stack())
+			}
+
+			if trw.wroteHeader {
+				return
+			}
+			for k := range rw.Header() {
+				rw.Header().Del(k)
+			}
+			_ = WriteError(rw, fmt.Errorf("%w: unexpected error when serving the request", spec.ErrInternal))
+		}()
+		next.ServeHTTP(trw, r)
+	})
+}
+
+// trackingResponseWriter records whether the response status has been written, or the connection has been hijacked.
+type trackingResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *trackingResponseWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *trackingResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *trackingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+func (w *trackingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, buf, err := h.Hijack()
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return conn, buf, err
+}
+
+func (w *trackingResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+var (
+	_ http.Flusher  = (*trackingResponseWriter)(nil)
+	_ http.Hijacker = (*trackingResponseWriter)(nil)
+	_ http.Pusher   = (*trackingResponseWriter)(nil)
+)
@@ -0,0 +1,118 @@
+package handlerutil
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		expect  func(t *testing.T, rw *httptest.ResponseRecorder, logged interface{}, stack []byte)
+	}{
+		{
+			name: "no panic",
+			handler: func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusNoContent)
+			},
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder, logged interface{}, stack []byte) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Nil(t, logged)
+			},
+		},
+		{
+			name: "panic before response is written",
+			handler: func(rw http.ResponseWriter, r *http.Request) {
+				rw.Header().Set("Content-Type", "text/plain")
+				rw.Header().Set("ETag", "W/\"1\"")
+				panic("boom")
+			},
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder, logged interface{}, stack []byte) {
+				assert.Equal(t, http.StatusInternalServerError, rw.Code)
+				assert.Equal(t, "application/json+scim", rw.Header().Get("Content-Type"))
+				assert.Empty(t, rw.Header().Get("ETag"))
+				assert.JSONEq(t, `
+{
+  "schemas":[
+    "urn:ietf:params:scim:api:messages:2.0:Error"
+  ],
+  "status":500,
+  "scimType":"internal",
+  "detail":"internal: unexpected error when serving the request"
+}
+`, rw.Body.String())
+				assert.Equal(t, "boom", logged)
+				assert.NotEmpty(t, stack)
+			},
+		},
+		{
+			name: "panic after response is written",
+			handler: func(rw http.ResponseWriter, r *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+				_, _ = rw.Write([]byte("{"))
+				panic("boom")
+			},
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder, logged interface{}, stack []byte) {
+				assert.Equal(t, http.StatusOK, rw.Code)
+				assert.Equal(t, "{", rw.Body.String())
+				assert.Equal(t, "boom", logged)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				logged interface{}
+				stack  []byte
+			)
+			handler := Recover(test.handler, func(r *http.Request, recovered interface{}, s []byte) {
+				logged, stack = recovered, s
+			})
+			rw := httptest.NewRecorder()
+			handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/Users/foo", nil))
+			test.expect(t, rw, logged, stack)
+		})
+	}
+}
+
+func TestRecoverForwardsFlush(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		flusher, ok := rw.(http.Flusher)
+		assert.True(t, ok)
+		_, _ = rw.Write([]byte("{"))
+		flusher.Flush()
+		panic("boom")
+	}), nil)
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/Users/foo", nil))
+	assert.True(t, rw.Flushed)
+	assert.Equal(t, "{", rw.Body.String())
+}
+
+func TestRecoverHijackAndPushNotSupported(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _, err := rw.(http.Hijacker).Hijack()
+		assert.Equal(t, http.ErrNotSupported, err)
+		assert.Equal(t, http.ErrNotSupported, rw.(http.Pusher).Push("/Users/bar", nil))
+		rw.WriteHeader(http.StatusNoContent)
+	}), nil)
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/Users/foo", nil))
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+}
+
+func TestRecoverDoesNotSwallowAbortHandler(t *testing.T) {
+	handler := Recover(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}), nil)
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/Users/foo", nil))
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/service"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"strings"
 )
@@ -20,8 +21,12 @@ const (
 	paramCount              = "count"
 	paramAttributes         = "attributes"
 	paramExcludedAttributes = "excludedAttributes"
+	paramLocale             = "locale"
+	paramReplaceScope       = "replaceScope"
 )
 
+const searchRequestSchema = "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+
 // GetRequestProjection returns a nullable *crud.Projection structure that may encapsulate the attributes or excludedAttributes
 // parameters present in the HTTP GET request.
 func GetRequestProjection(request *http.Request) (projection *crud.Projection, err error) {
@@ -65,8 +70,9 @@ func QueryRequestFromGet(request *http.Request) (qr *service.QueryRequest, err e
 
 	if sortBy := request.URL.Query().Get(paramSortBy); len(sortBy) > 0 {
 		qr.Sort = &crud.Sort{
-			By:    sortBy,
-			Order: crud.SortOrder(request.URL.Query().Get(paramSortOrder)),
+			By:     sortBy,
+			Order:  crud.SortOrder(request.URL.Query().Get(paramSortOrder)),
+			Locale: requestLocale(request),
 		}
 	}
 
@@ -91,7 +97,7 @@ func QueryRequestFromGet(request *http.Request) (qr *service.QueryRequest, err e
 				return
 			}
 		} else {
-			qr.Pagination.Count = 0
+			qr.Pagination.Count = crud.CountUnspecified
 		}
 	}
 
@@ -104,7 +110,11 @@ func QueryRequestFromGet(request *http.Request) (qr *service.QueryRequest, err e
 }
 
 // QueryRequestFromPost returns a parsed *service.QueryRequest from *http.Request using HTTP POST method, a closer function
-// to be invoked when the search is finished, and any error during the parsing.
+// to be invoked when the search is finished, and any error during the parsing. The request body is expected to be a
+// SearchRequest message, as defined in RFC 7644 section 3.4.3. Because the filter is carried in the JSON body, it is not
+// subject to URL encoding, which makes this the preferred way to search with filters containing characters awkward in
+// URLs. Like QueryRequestFromGet, at most one of attributes and excludedAttributes may be specified, and a non-negative
+// count is required; a startIndex less than 1 is interpreted as 1.
 func QueryRequestFromPost(request *http.Request) (qr *service.QueryRequest, closer func(), err error) {
 	wip := new(struct {
 		Schemas            []string `json:"schemas"`
@@ -114,27 +124,33 @@ func QueryRequestFromPost(request *http.Request) (qr *service.QueryRequest, clos
 		SortBy             string   `json:"sortBy"`
 		SortOrder          string   `json:"sortOrder"`
 		StartIndex         int      `json:"startIndex"`
-		Count              int      `json:"count"`
+		Count              *int     `json:"count"`
 	})
-	if err = json.NewDecoder(request.Body).Decode(wip); err != nil {
-		return
-	}
 	closer = func() {
 		_ = request.Body.Close()
 	}
+	if err = json.NewDecoder(request.Body).Decode(wip); err != nil {
+		err = fmt.Errorf("%w: invalid search request body", spec.ErrInvalidSyntax)
+		return
+	}
 
-	if len(wip.Schemas) != 1 || wip.Schemas[0] != "urn:ietf:params:scim:api:messages:2.0:SearchRequest" {
+	if len(wip.Schemas) != 1 || !strings.EqualFold(wip.Schemas[0], searchRequestSchema) {
 		err = fmt.Errorf("%w: invalid schema for search request", spec.ErrInvalidSyntax)
 		return
 	}
+	if len(wip.Attributes) > 0 && len(wip.ExcludedAttributes) > 0 {
+		err = fmt.Errorf("%w: only one of attributes and excludedAttributes may be specified", spec.ErrInvalidSyntax)
+		return
+	}
 	qr = &service.QueryRequest{
 		Filter: wip.Filter,
 	}
 
 	if len(wip.SortBy) > 0 {
 		qr.Sort = &crud.Sort{
-			By:    wip.SortBy,
-			Order: crud.SortOrder(wip.SortOrder), // validate it later
+			By:     wip.SortBy,
+			Order:  crud.SortOrder(wip.SortOrder), // validate it later
+			Locale: requestLocale(request),
 		}
 	}
 
@@ -145,27 +161,48 @@ func QueryRequestFromPost(request *http.Request) (qr *service.QueryRequest, clos
 		}
 	}
 
-	if wip.StartIndex > 0 || wip.Count > 0 {
-		if wip.StartIndex == 0 {
+	if wip.StartIndex > 0 || wip.Count != nil {
+		if wip.StartIndex < 1 {
 			wip.StartIndex = 1
 		}
 		qr.Pagination = &crud.Pagination{
 			StartIndex: wip.StartIndex,
-			Count:      wip.Count,
+			Count:      crud.CountUnspecified,
+		}
+		if wip.Count != nil {
+			if *wip.Count < 0 {
+				err = fmt.Errorf("%w: count must be a non-negative integer", spec.ErrInvalidSyntax)
+				return
+			}
+			qr.Pagination.Count = *wip.Count
 		}
 	}
 
 	return
 }
 
+// requestLocale returns the locale hint for collating string sort keys. The "locale" query parameter takes precedence
+// over the Accept-Language header. An empty string is returned if neither is present.
+func requestLocale(request *http.Request) string {
+	if locale := request.URL.Query().Get(paramLocale); len(locale) > 0 {
+		return locale
+	}
+	return request.Header.Get("Accept-Language")
+}
+
 // ReplaceRequest returns a function that will supply a complete built *service.ReplaceRequest when given resourceId,
-// and a closer function which should be called after resource processing is done (preferably using defer).
+// and a closer function which should be called after resource processing is done (preferably using defer). The
+// replacement is a full replacement, unless the client opts in to a scoped replacement with the replaceScope parameter,
+// listing the attributes it was shown in the same format as the attributes parameter: the replacement is then scoped
+// to those attributes, and the rest of the stored resource is preserved. The attributes parameter is not considered,
+// as it specifies the attributes to return in the response (see ReplaceHandler).
 func ReplaceRequest(request *http.Request) (rr func(resourceId string) *service.ReplaceRequest, closer func()) {
 	rr = func(resourceId string) *service.ReplaceRequest {
 		return &service.ReplaceRequest{
 			ResourceID:    resourceId,
 			PayloadSource: request.Body,
 			MatchCriteria: MatchCriteria(request),
+			Scope:         replaceScope(request),
 		}
 	}
 	closer = func() {
@@ -174,6 +211,14 @@ func ReplaceRequest(request *http.Request) (rr func(resourceId string) *service.
 	return
 }
 
+// replaceScope returns the attributes specified in the replaceScope parameter of the request, or nil if not specified.
+func replaceScope(request *http.Request) []string {
+	if attrValue := request.URL.Query().Get(paramReplaceScope); len(attrValue) > 0 {
+		return strings.Split(strings.TrimSpace(attrValue), " ")
+	}
+	return nil
+}
+
 // PatchRequest returns a function that will supply a complete built *service.PatchRequest when given resourceId, and
 // a closer function which should be called after resource processing is done (preferably using defer).
 func PatchRequest(request *http.Request) (pr func(resourceId string) *service.PatchRequest, closer func()) {
@@ -200,6 +245,61 @@ func DeleteRequest(request *http.Request) func(resourceId string) *service.Delet
 	}
 }
 
+// DeleteElementRequest returns a function that will supply a complete built *service.DeleteElementRequest when given
+// resourceId, the path of the multiValued attribute and the key of the element.
+func DeleteElementRequest(request *http.Request) func(resourceId string, attribute string, key string) *service.DeleteElementRequest {
+	return func(resourceId string, attribute string, key string) *service.DeleteElementRequest {
+		return &service.DeleteElementRequest{
+			ResourceID:    resourceId,
+			Attribute:     attribute,
+			Key:           key,
+			MatchCriteria: MatchCriteria(request),
+		}
+	}
+}
+
+// Values of the "return" preference in the Prefer header (RFC 7240), which SCIM clients use to control whether the
+// resource representation is returned in the response to a write operation.
+const (
+	ReturnMinimal        = "minimal"
+	ReturnRepresentation = "representation"
+)
+
+// ReturnPreference returns the value of the "return" preference specified in the Prefer header, which is either
+// ReturnMinimal or ReturnRepresentation. Preference names and values are matched case insensitively, and the first
+// recognized preference wins. If no "return" preference is recognized, it returns empty string.
+func ReturnPreference(request *http.Request) string {
+	for _, prefer := range request.Header[textproto.CanonicalMIMEHeaderKey("Prefer")] {
+		for _, preference := range strings.FieldsFunc(prefer, func(r rune) bool {
+			return r == ',' || r == ';'
+		}) {
+			parts := strings.SplitN(strings.Join(strings.Fields(preference), ""), "=", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "return") {
+				continue
+			}
+			switch value := strings.Trim(parts[1], `"`); {
+			case strings.EqualFold(value, ReturnMinimal):
+				return ReturnMinimal
+			case strings.EqualFold(value, ReturnRepresentation):
+				return ReturnRepresentation
+			}
+		}
+	}
+	return ""
+}
+
+// NoContentRequested returns true if the client has signaled that the resource representation is not needed in the
+// response to a write operation, by specifying "return=minimal" in the Prefer header (RFC 7240).
+func NoContentRequested(request *http.Request) bool {
+	return ReturnPreference(request) == ReturnMinimal
+}
+
+// CreateIfAbsentRequested returns true if the client has requested the resource to be created only if it does not exist,
+// by specifying "If-None-Match: *" without If-Match (RFC 7232), i.e. for a PUT to the location of the resource.
+func CreateIfAbsentRequested(request *http.Request) bool {
+	return len(request.Header.Get("If-Match")) == 0 && strings.TrimSpace(request.Header.Get("If-None-Match")) == "*"
+}
+
 // MatchCriteria returns a function to be supplied as the match criteria argument in replace, patch and delete requests.
 // It checks for If-Match and If-None-Match headers and supports asterisk (*) and comma delimited resource versions.
 // The If-Match header takes precedence over If-None-Match header. If none of the headers are present, it returns a
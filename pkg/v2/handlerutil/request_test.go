@@ -121,6 +121,37 @@ func TestQueryRequestFromGet(t *testing.T) {
 				assert.Equal(t, crud.SortAsc, qr.Sort.Order)
 			},
 		},
+		{
+			name: "query with sort and locale",
+			requestFunc: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.URL.RawQuery = url.Values{
+					paramSortBy: []string{"userName"},
+					paramLocale: []string{"sv-SE"},
+				}.Encode()
+				r.Header.Set("Accept-Language", "en-US")
+				return r
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "sv-SE", qr.Sort.Locale)
+			},
+		},
+		{
+			name: "query with sort and Accept-Language",
+			requestFunc: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.URL.RawQuery = url.Values{
+					paramSortBy: []string{"userName"},
+				}.Encode()
+				r.Header.Set("Accept-Language", "sv-SE,en;q=0.8")
+				return r
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "sv-SE,en;q=0.8", qr.Sort.Locale)
+			},
+		},
 		{
 			name: "query with pagination",
 			requestFunc: func() *http.Request {
@@ -181,6 +212,123 @@ func TestQueryRequestFromPost(t *testing.T) {
 				assert.Equal(t, []string{"id", "meta", "userName"}, qr.Projection.Attributes)
 			},
 		},
+		{
+			name: "query with startIndex only",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+  ],
+  "startIndex": 2
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 2, qr.Pagination.StartIndex)
+				assert.Equal(t, crud.CountUnspecified, qr.Pagination.Count)
+			},
+		},
+		{
+			name: "query with zero count",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+  ],
+  "count": 0
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, qr.Pagination.StartIndex)
+				assert.Equal(t, 0, qr.Pagination.Count)
+			},
+		},
+		{
+			name: "query with filter awkward in urls",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+  ],
+  "filter": "displayName eq \"100% & #1 + \\\"quoted\\\"?\""
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, `displayName eq "100% & #1 + \"quoted\"?"`, qr.Filter)
+				assert.Nil(t, qr.Pagination)
+			},
+		},
+		{
+			name: "query with negative startIndex",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+  ],
+  "startIndex": -1,
+  "count": 10
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 1, qr.Pagination.StartIndex)
+				assert.Equal(t, 10, qr.Pagination.Count)
+			},
+		},
+		{
+			name: "query with both attributes and excludedAttributes",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:SearchRequest"
+  ],
+  "attributes": ["userName"],
+  "excludedAttributes": ["emails"]
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidSyntax, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "query with invalid schema",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+  ]
+}
+`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidSyntax, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "query with malformed body",
+			requestFunc: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/Users/.search", strings.NewReader(`{"schemas": [`))
+			},
+			expect: func(t *testing.T, qr *service.QueryRequest, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidSyntax, errors.Unwrap(err))
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -191,3 +339,164 @@ func TestQueryRequestFromPost(t *testing.T) {
 		})
 	}
 }
+
+func TestReturnPreference(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefer []string
+		expect string
+	}{
+		{
+			name:   "no prefer header",
+			expect: "",
+		},
+		{
+			name:   "return minimal",
+			prefer: []string{"return=minimal"},
+			expect: ReturnMinimal,
+		},
+		{
+			name:   "return representation",
+			prefer: []string{"Return=Representation"},
+			expect: ReturnRepresentation,
+		},
+		{
+			name:   "quoted value among other preferences",
+			prefer: []string{"respond-async, wait=10", `return = "representation"; foo`},
+			expect: ReturnRepresentation,
+		},
+		{
+			name:   "first recognized preference wins",
+			prefer: []string{"return=foo", "return=minimal, return=representation"},
+			expect: ReturnMinimal,
+		},
+		{
+			name:   "unrecognized preference",
+			prefer: []string{"returns=minimal, handling=lenient"},
+			expect: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/Users/foo", nil)
+			for _, prefer := range test.prefer {
+				r.Header.Add("Prefer", prefer)
+			}
+			assert.Equal(t, test.expect, ReturnPreference(r))
+		})
+	}
+}
+
+func TestNoContentRequested(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefer []string
+		expect bool
+	}{
+		{
+			name:   "no prefer header",
+			expect: false,
+		},
+		{
+			name:   "return minimal",
+			prefer: []string{"return=minimal"},
+			expect: true,
+		},
+		{
+			name:   "return minimal among other preferences",
+			prefer: []string{"respond-async, wait=10", "Return = Minimal; foo"},
+			expect: true,
+		},
+		{
+			name:   "return representation",
+			prefer: []string{"return=representation"},
+			expect: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/Users/foo", nil)
+			for _, prefer := range test.prefer {
+				r.Header.Add("Prefer", prefer)
+			}
+			assert.Equal(t, test.expect, NoContentRequested(r))
+		})
+	}
+}
+
+func TestCreateIfAbsentRequested(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		expect  bool
+	}{
+		{
+			name:   "no conditional header",
+			expect: false,
+		},
+		{
+			name:    "if none match asterisk",
+			headers: map[string]string{"If-None-Match": " * "},
+			expect:  true,
+		},
+		{
+			name:    "if none match version",
+			headers: map[string]string{"If-None-Match": `W/"1"`},
+			expect:  false,
+		},
+		{
+			name:    "if match takes precedence",
+			headers: map[string]string{"If-None-Match": "*", "If-Match": `W/"1"`},
+			expect:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/Users/foo", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, test.expect, CreateIfAbsentRequested(r))
+		})
+	}
+}
+
+func TestReplaceRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  url.Values
+		expect []string
+	}{
+		{
+			name: "no replaceScope parameter",
+		},
+		{
+			name:   "replaceScope parameter scopes the replacement",
+			query:  url.Values{paramReplaceScope: []string{"userName emails"}},
+			expect: []string{"userName", "emails"},
+		},
+		{
+			name:  "attributes parameter does not scope the replacement",
+			query: url.Values{paramAttributes: []string{"userName emails"}},
+		},
+		{
+			name:  "excludedAttributes parameter does not scope the replacement",
+			query: url.Values{paramExcludedAttributes: []string{"emails"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/Users/foo", strings.NewReader("{}"))
+			r.URL.RawQuery = test.query.Encode()
+			rr, closer := ReplaceRequest(r)
+			defer closer()
+			req := rr("foo")
+			assert.Equal(t, "foo", req.ResourceID)
+			assert.Equal(t, test.expect, req.Scope)
+		})
+	}
+}
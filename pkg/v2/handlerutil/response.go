@@ -2,40 +2,170 @@ package handlerutil
 
 import (
 	"encoding/json"
-	"errors"
+	"fmt"
 	scimjson "github.com/imulab/go-scim/pkg/v2/json"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/service"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 // WriteResourceToResponse writes the given resource to http.ResponseWriter, respecting the attributes or excludedAttributes
 // specified through options. Any error during the process will be returned.
-// Apart from writing the JSON representation of the resource to body, this method also sets Content-Type header to
-// application/json+scim; sets Location header to resource's meta.location field, if any; and sets ETag header to
-// resource's meta.version field, if any. This method does not set response status, which should be set before calling
+// Apart from writing the JSON representation of the resource to body, this method also sets the headers as
+// WriteResourceHeadersToResponse does. This method does not set response status, which should be set before calling
 // this method.
 func WriteResourceToResponse(rw http.ResponseWriter, resource *prop.Resource, options ...scimjson.Options) error {
+	return WriteResourceToResponseFor(rw, nil, resource, options...)
+}
+
+// WriteResourceToResponseFor writes the given resource as WriteResourceToResponse does, except that the Location header
+// is computed by the LocationBuilder configured for the request with LocationContext, if any.
+func WriteResourceToResponseFor(rw http.ResponseWriter, request *http.Request, resource *prop.Resource, options ...scimjson.Options) error {
 	raw, jsonErr := scimjson.Serialize(resource, options...)
 	if jsonErr != nil {
 		return jsonErr
 	}
 
+	WriteResourceHeadersToResponseFor(rw, request, resource)
+
+	_, writeErr := rw.Write(raw)
+	return writeErr
+}
+
+// WriteResourceHeadersToResponse sets the headers of the response for the given resource without writing the body: it
+// sets Content-Type header to application/json+scim; sets Location header to resource's meta.location field, if any;
+// and sets ETag header to resource's meta.version field, if any. This is intended for HEAD requests, whose response
+// carries the same headers as the equivalent GET. This method does not set response status.
+func WriteResourceHeadersToResponse(rw http.ResponseWriter, resource *prop.Resource) {
+	WriteResourceHeadersToResponseFor(rw, nil, resource)
+}
+
+// WriteResourceHeadersToResponseFor sets the headers as WriteResourceHeadersToResponse does, except that the Location
+// header is computed by the LocationBuilder configured for the request with LocationContext, if any.
+func WriteResourceHeadersToResponseFor(rw http.ResponseWriter, request *http.Request, resource *prop.Resource) {
 	rw.Header().Set("Content-Type", "application/json+scim")
-	if location := resource.MetaLocationOrEmpty(); len(location) > 0 {
+	writeLocationAndETag(rw, request, resource)
+	writeDeprecationWarning(rw, resource)
+}
+
+// WriteNoContentToResponse writes a header only success response for the given resource. It sets Location and ETag
+// headers as WriteResourceHeadersToResponse does. The response status is set to 204 (No Content) and no body is
+// written. This is intended for write operations whose client does not need the resource representation in the response.
+func WriteNoContentToResponse(rw http.ResponseWriter, resource *prop.Resource) {
+	WriteNoContentToResponseFor(rw, nil, resource)
+}
+
+// WriteNoContentToResponseFor writes a header only success response as WriteNoContentToResponse does, except that the
+// Location header is computed by the LocationBuilder configured for the request with LocationContext, if any.
+func WriteNoContentToResponseFor(rw http.ResponseWriter, request *http.Request, resource *prop.Resource) {
+	writeLocationAndETag(rw, request, resource)
+	writeDeprecationWarning(rw, resource)
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// WritePreferredResponse writes the success response of a write operation for the given resource, honoring the
+// "return" preference of the Prefer header (see ReturnPreference). When the client prefers return=minimal, or when
+// noContent is true and the client did not prefer return=representation, the response is header only as in
+// WriteNoContentToResponse: status 200 (OK) is responded as 204 (No Content), while other statuses (i.e. 201 Created)
+// are kept. Otherwise, the resource is written as in WriteResourceToResponse, respecting the options. The
+// Preference-Applied header is set whenever a preference was honored. The Location header is computed as in
+// WriteResourceToResponseFor. Unlike WriteResourceToResponse, this method writes the response status.
+func WritePreferredResponse(rw http.ResponseWriter, request *http.Request, status int, noContent bool, resource *prop.Resource, options ...scimjson.Options) error {
+	preference := ReturnPreference(request)
+	if len(preference) > 0 {
+		rw.Header().Set("Preference-Applied", "return="+preference)
+	}
+
+	if preference == ReturnMinimal || (noContent && preference != ReturnRepresentation) {
+		if status == http.StatusOK {
+			WriteNoContentToResponseFor(rw, request, resource)
+			return nil
+		}
+		writeLocationAndETag(rw, request, resource)
+		writeDeprecationWarning(rw, resource)
+		rw.WriteHeader(status)
+		return nil
+	}
+
+	raw, jsonErr := scimjson.Serialize(resource, options...)
+	if jsonErr != nil {
+		return jsonErr
+	}
+
+	WriteResourceHeadersToResponseFor(rw, request, resource)
+	rw.WriteHeader(status)
+
+	_, writeErr := rw.Write(raw)
+	return writeErr
+}
+
+func writeLocationAndETag(rw http.ResponseWriter, request *http.Request, resource *prop.Resource) {
+	if location := resourceLocation(request, resource); len(location) > 0 {
 		rw.Header().Set("Location", location)
 	}
 	if version := resource.MetaVersionOrEmpty(); len(version) > 0 {
 		rw.Header().Set("ETag", version)
 	}
+}
+
+// writeDeprecationWarning sets the Warning header with the 299 (Miscellaneous Persistent Warning) code of RFC 7234,
+// listing the paths of the attributes marked as deprecated in spec.Deprecations that are assigned in the resources. For
+// write operations, these are the deprecated attributes set by the request or kept from the existing resource. The
+// header is not set if no deprecated attribute is assigned.
+func writeDeprecationWarning(rw http.ResponseWriter, resources ...*prop.Resource) {
+	visitor := &deprecationVisitor{paths: map[string]struct{}{}}
+	for _, resource := range resources {
+		_ = prop.Walk(resource, visitor)
+	}
+	if len(visitor.paths) == 0 {
+		return
+	}
 
-	_, writeErr := rw.Write(raw)
-	return writeErr
+	paths := make([]string, 0, len(visitor.paths))
+	for path := range visitor.paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	rw.Header().Set("Warning", fmt.Sprintf("299 - %s", strconv.Quote("Deprecated attributes: "+strings.Join(paths, ", "))))
+}
+
+// deprecationVisitor collects the paths of assigned properties whose attribute is deprecated.
+type deprecationVisitor struct {
+	paths map[string]struct{}
+}
+
+func (v *deprecationVisitor) EnterContainer(attribute *spec.Attribute, _ string, container prop.Property) error {
+	if container.IsUnassigned() {
+		return prop.SkipChildren
+	}
+	v.collect(attribute)
+	return nil
+}
+
+func (v *deprecationVisitor) LeaveContainer(_ *spec.Attribute, _ string, _ prop.Property) error {
+	return nil
+}
+
+func (v *deprecationVisitor) Value(attribute *spec.Attribute, _ string, property prop.Property) error {
+	if !property.IsUnassigned() {
+		v.collect(attribute)
+	}
+	return nil
+}
+
+func (v *deprecationVisitor) collect(attribute *spec.Attribute) {
+	if attribute.Deprecated() {
+		v.paths[attribute.Path()] = struct{}{}
+	}
 }
 
 // WriteSearchResultToResponse writes the search result to http.ResponseWrite, respecting the attribute or excludedAttributes
-// specified through options. Any error during the process will be returned.
+// specified through options, which are resolved once for all resources. Any error during the process will be returned.
 // This method also sets Content-Type header to application/json+scim. This method does not set response status, which should
 // be set before calling this method.
 func WriteSearchResultToResponse(rw http.ResponseWriter, searchResult *service.QueryResponse, options ...scimjson.Options) error {
@@ -47,54 +177,51 @@ func WriteSearchResultToResponse(rw http.ResponseWriter, searchResult *service.Q
 		Resources:    []json.RawMessage{},
 	}
 
-	for _, resource := range searchResult.Resources {
-		raw, err := scimjson.Serialize(resource, options...)
-		if err != nil {
-			return err
-		}
+	resources := make([]*prop.Resource, 0, len(searchResult.Resources))
+	if err := scimjson.SerializeStream(searchResult.Resources, func(index int, raw []byte) error {
 		render.Resources = append(render.Resources, raw)
+		if r, ok := searchResult.Resources[index].(*prop.Resource); ok {
+			resources = append(resources, r)
+		}
+		return nil
+	}, options...); err != nil {
+		return err
 	}
 
 	rw.Header().Set("Content-Type", "application/json+scim")
+	writeDeprecationWarning(rw, resources...)
 	return json.NewEncoder(rw).Encode(render)
 }
 
 // WriteError writes the error to the http.ResponseWriter. Any error during the process will be returned.
-// If the cause of the error (determined using errors.Unwrap) is a *spec.Error, the cause status and scimType will be
-// used together with the error's message as detail. If the cause is not a *spec.Error, spec.ErrInternal is used instead.
+// If the error is a *spec.Error (i.e. created by spec.NewInvalidValue), or the cause of the error (determined using
+// errors.Unwrap) is a *spec.Error, its status and scimType will be used together with the error's message as detail.
+// Otherwise, spec.ErrInternal is used instead.
 // This method also writes the http status with the error's defined status, and set Content-Type header to application/json+scim.
+// The body is rendered by the ErrorRenderer installed with SetErrorRenderer, which defaults to the SCIM Error message.
 func WriteError(rw http.ResponseWriter, err error) error {
-	var errMsg = struct {
-		Schemas  []string `json:"schemas"`
-		Status   int      `json:"status"`
-		ScimType string   `json:"scimType"`
-		Detail   string   `json:"detail"`
-	}{
-		Schemas: []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
-		Detail:  err.Error(),
-	}
-
-	cause := errors.Unwrap(err)
-	if scimError, ok := cause.(*spec.Error); ok {
-		errMsg.Status = scimError.Status
-		errMsg.ScimType = scimError.Type
-	} else {
-		errMsg.Status = spec.ErrInternal.Status
-		errMsg.ScimType = spec.ErrInternal.Type
-	}
+	status, scimType, extras := resolveError(err)
 
 	rw.Header().Set("Content-Type", "application/json+scim")
-	rw.WriteHeader(errMsg.Status)
+	rw.WriteHeader(status)
 
-	raw, jsonErr := json.Marshal(errMsg)
-	if jsonErr != nil {
-		return jsonErr
+	raw, renderErr := currentErrorRenderer().RenderError(status, scimType, err.Error(), extras)
+	if renderErr != nil {
+		return renderErr
 	}
 
 	_, writeErr := rw.Write(raw)
 	return writeErr
 }
 
+// WriteErrorHeadersToResponse writes the error to the http.ResponseWriter in the same way as WriteError, except that
+// no body is written. This is intended for HEAD requests.
+func WriteErrorHeadersToResponse(rw http.ResponseWriter, err error) {
+	status, _, _ := resolveError(err)
+	rw.Header().Set("Content-Type", "application/json+scim")
+	rw.WriteHeader(status)
+}
+
 // SearchResultRendering is the JSON rendering structure for search results. This is very similar to
 // service.QueryResponse except that resources are pre-rendered to adapt for objects serialized using
 // scim json mechanism or go's json mechanism.
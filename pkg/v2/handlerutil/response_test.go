@@ -1,10 +1,18 @@
 package handlerutil
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
+	scimjson "github.com/imulab/go-scim/pkg/v2/json"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 )
@@ -18,6 +26,23 @@ func TestWriteError(t *testing.T) {
 		{
 			name: "wrapped scim error",
 			err:  fmt.Errorf("%w: valid is invalid", spec.ErrInvalidValue),
+			expect: func(t *testing.T, raw []byte) {
+				assert.Equal(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":400,"scimType":"invalidValue","detail":"invalidValue: valid is invalid"}`, string(raw))
+			},
+		},
+		{
+			name: "constructed scim error",
+			err:  spec.NewInvalidValue("valid is invalid"),
+			expect: func(t *testing.T, raw []byte) {
+				assert.Equal(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":400,"scimType":"invalidValue","detail":"invalidValue: valid is invalid"}`, string(raw))
+			},
+		},
+		{
+			name: "wrapped scim error with extras",
+			err: fmt.Errorf("%w: valid is invalid", spec.ErrInvalidValue.WithExtras(map[string]interface{}{
+				"requestId": "abc",
+				"status":    200,
+			})),
 			expect: func(t *testing.T, raw []byte) {
 				assert.JSONEq(t, `
 {
@@ -26,7 +51,45 @@ func TestWriteError(t *testing.T) {
   ],
   "status": 400,
   "scimType": "invalidValue",
-  "detail": "invalidValue: valid is invalid"
+  "detail": "invalidValue: valid is invalid",
+  "requestId": "abc"
+}
+`, string(raw))
+			},
+		},
+		{
+			name: "uniqueness error with conflict detail",
+			err: fmt.Errorf("%w: value of 'userName' is not unique", spec.ErrUniqueness.WithExtras(map[string]interface{}{
+				"attribute":  "userName",
+				"existingId": "foo",
+			})),
+			expect: func(t *testing.T, raw []byte) {
+				assert.JSONEq(t, `
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:Error"
+  ],
+  "status": 409,
+  "scimType": "uniqueness",
+  "detail": "uniqueness: value of 'userName' is not unique",
+  "attribute": "userName",
+  "existingId": "foo"
+}
+`, string(raw))
+			},
+		},
+		{
+			name: "custom scim error",
+			err:  fmt.Errorf("%w: not allowed", &spec.Error{Status: 403, Type: "forbidden"}),
+			expect: func(t *testing.T, raw []byte) {
+				assert.JSONEq(t, `
+{
+  "schemas": [
+    "urn:ietf:params:scim:api:messages:2.0:Error"
+  ],
+  "status": 403,
+  "scimType": "forbidden",
+  "detail": "forbidden: not allowed"
 }
 `, string(raw))
 			},
@@ -57,3 +120,432 @@ func TestWriteError(t *testing.T) {
 		})
 	}
 }
+
+func TestSetErrorRenderer(t *testing.T) {
+	SetErrorRenderer(ErrorRendererFunc(func(status int, scimType string, detail string, extras map[string]interface{}) ([]byte, error) {
+		return []byte(fmt.Sprintf("%d|%s|%s|%v", status, scimType, detail, extras["docs"])), nil
+	}))
+	defer SetErrorRenderer(nil)
+
+	err := fmt.Errorf("%w: no such user", spec.ErrNotFound.WithExtras(map[string]interface{}{
+		"docs": "https://docs.imulab.io/errors",
+	}))
+	assert.True(t, errors.Is(err, spec.ErrNotFound))
+	assert.Empty(t, spec.ErrNotFound.Extras)
+
+	rw := httptest.NewRecorder()
+	assert.Nil(t, WriteError(rw, err))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.Equal(t, "404|notFound|notFound: no such user|https://docs.imulab.io/errors", rw.Body.String())
+
+	SetErrorRenderer(nil)
+	rw = httptest.NewRecorder()
+	assert.Nil(t, WriteError(rw, err))
+	assert.Equal(t, `{"schemas":["urn:ietf:params:scim:api:messages:2.0:Error"],"status":404,"scimType":"notFound","detail":"notFound: no such user","docs":"https://docs.imulab.io/errors"}`, rw.Body.String())
+}
+
+func TestWriteNoContentToResponse(t *testing.T) {
+	resource := prop.NewResource(testUserResourceType(t))
+	_, err := resource.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"meta": map[string]interface{}{
+			"location": "https://identity.imulab.io/Users/foo",
+			"version":  "W/\"1\"",
+		},
+	})
+	require.Nil(t, err)
+
+	rw := httptest.NewRecorder()
+	WriteNoContentToResponse(rw, resource)
+
+	assert.Equal(t, http.StatusNoContent, rw.Code)
+	assert.Equal(t, "https://identity.imulab.io/Users/foo", rw.Header().Get("Location"))
+	assert.Equal(t, "W/\"1\"", rw.Header().Get("ETag"))
+	assert.Empty(t, rw.Header().Get("Content-Type"))
+	assert.Empty(t, rw.Body.Bytes())
+}
+
+func TestWritePreferredResponse(t *testing.T) {
+	resource := prop.NewResource(testUserResourceType(t))
+	_, err := resource.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"meta": map[string]interface{}{
+			"location": "https://identity.imulab.io/Users/foo",
+			"version":  "W/\"1\"",
+		},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name      string
+		prefer    string
+		status    int
+		noContent bool
+		expect    func(t *testing.T, rw *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "no preference",
+			status: http.StatusOK,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rw.Code)
+				assert.Empty(t, rw.Header().Get("Preference-Applied"))
+				assert.Equal(t, "application/json+scim", rw.Header().Get("Content-Type"))
+				assert.Contains(t, rw.Body.String(), `"userName":"foo"`)
+			},
+		},
+		{
+			name:      "no preference with noContent",
+			status:    http.StatusOK,
+			noContent: true,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Empty(t, rw.Header().Get("Preference-Applied"))
+				assert.Empty(t, rw.Body.Bytes())
+			},
+		},
+		{
+			name:   "return minimal",
+			prefer: "return=minimal",
+			status: http.StatusOK,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusNoContent, rw.Code)
+				assert.Equal(t, "return=minimal", rw.Header().Get("Preference-Applied"))
+				assert.Equal(t, "https://identity.imulab.io/Users/foo", rw.Header().Get("Location"))
+				assert.Equal(t, "W/\"1\"", rw.Header().Get("ETag"))
+				assert.Empty(t, rw.Body.Bytes())
+			},
+		},
+		{
+			name:   "return minimal keeps created status",
+			prefer: "return=minimal",
+			status: http.StatusCreated,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rw.Code)
+				assert.Equal(t, "return=minimal", rw.Header().Get("Preference-Applied"))
+				assert.Equal(t, "https://identity.imulab.io/Users/foo", rw.Header().Get("Location"))
+				assert.Empty(t, rw.Body.Bytes())
+			},
+		},
+		{
+			name:      "return representation overrides noContent",
+			prefer:    "return=representation",
+			status:    http.StatusOK,
+			noContent: true,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusOK, rw.Code)
+				assert.Equal(t, "return=representation", rw.Header().Get("Preference-Applied"))
+				assert.Equal(t, "W/\"1\"", rw.Header().Get("ETag"))
+				assert.Contains(t, rw.Body.String(), `"userName":"foo"`)
+			},
+		},
+		{
+			name:   "return representation with created status",
+			prefer: "return=representation",
+			status: http.StatusCreated,
+			expect: func(t *testing.T, rw *httptest.ResponseRecorder) {
+				assert.Equal(t, http.StatusCreated, rw.Code)
+				assert.Equal(t, "return=representation", rw.Header().Get("Preference-Applied"))
+				assert.Equal(t, "https://identity.imulab.io/Users/foo", rw.Header().Get("Location"))
+				assert.Contains(t, rw.Body.String(), `"userName":"foo"`)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/Users/foo", nil)
+			if len(test.prefer) > 0 {
+				r.Header.Set("Prefer", test.prefer)
+			}
+			rw := httptest.NewRecorder()
+			assert.Nil(t, WritePreferredResponse(rw, r, test.status, test.noContent, resource))
+			test.expect(t, rw)
+		})
+	}
+}
+
+func TestWriteResourceHeadersToResponse(t *testing.T) {
+	resource := prop.NewResource(testUserResourceType(t))
+	_, err := resource.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"meta": map[string]interface{}{
+			"location": "https://identity.imulab.io/Users/foo",
+			"version":  "W/\"1\"",
+		},
+	})
+	require.Nil(t, err)
+
+	get := httptest.NewRecorder()
+	require.Nil(t, WriteResourceToResponse(get, resource))
+
+	head := httptest.NewRecorder()
+	WriteResourceHeadersToResponse(head, resource)
+
+	assert.Equal(t, get.Header(), head.Header())
+	assert.Equal(t, "application/json+scim", head.Header().Get("Content-Type"))
+	assert.Equal(t, "https://identity.imulab.io/Users/foo", head.Header().Get("Location"))
+	assert.Equal(t, "W/\"1\"", head.Header().Get("ETag"))
+	assert.Empty(t, head.Body.Bytes())
+}
+
+func TestDeprecationWarning(t *testing.T) {
+	resourceType := testUserResourceType(t)
+	resourceOf := func(data map[string]interface{}) *prop.Resource {
+		data["schemas"] = []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"}
+		data["meta"] = map[string]interface{}{"version": "W/\"1\""}
+		resource := prop.NewResource(resourceType)
+		_, err := resource.RootProperty().Replace(data)
+		require.Nil(t, err)
+		return resource
+	}
+	deprecated := resourceOf(map[string]interface{}{
+		"id":       "foo",
+		"userName": "foo",
+		"nickName": "foo",
+		"name": map[string]interface{}{
+			"middleName": "F",
+		},
+	})
+	current := resourceOf(map[string]interface{}{
+		"id":       "bar",
+		"userName": "bar",
+	})
+
+	for _, id := range []string{
+		"urn:ietf:params:scim:schemas:core:2.0:User:nickName",
+		"urn:ietf:params:scim:schemas:core:2.0:User:name.middleName",
+		"urn:ietf:params:scim:schemas:core:2.0:User:title",
+	} {
+		spec.Deprecations().Register(id)
+		defer spec.Deprecations().Unregister(id)
+	}
+	const warning = `299 - "Deprecated attributes: name.middleName, nickName"`
+
+	tests := []struct {
+		name   string
+		write  func(rw http.ResponseWriter)
+		expect string
+	}{
+		{
+			name: "resource with deprecated attributes",
+			write: func(rw http.ResponseWriter) {
+				_ = WriteResourceToResponse(rw, deprecated)
+			},
+			expect: warning,
+		},
+		{
+			name: "resource without deprecated attributes",
+			write: func(rw http.ResponseWriter) {
+				_ = WriteResourceToResponse(rw, current)
+			},
+			expect: "",
+		},
+		{
+			name: "no content response",
+			write: func(rw http.ResponseWriter) {
+				WriteNoContentToResponse(rw, deprecated)
+			},
+			expect: warning,
+		},
+		{
+			name: "head response",
+			write: func(rw http.ResponseWriter) {
+				WriteResourceHeadersToResponse(rw, deprecated)
+			},
+			expect: warning,
+		},
+		{
+			name: "search result",
+			write: func(rw http.ResponseWriter) {
+				_ = WriteSearchResultToResponse(rw, &service.QueryResponse{
+					TotalResults: 2,
+					StartIndex:   1,
+					ItemsPerPage: 2,
+					Resources:    []scimjson.Serializable{current, deprecated},
+				})
+			},
+			expect: warning,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			test.write(rw)
+			assert.Equal(t, test.expect, rw.Header().Get("Warning"))
+		})
+	}
+
+	// deprecated attributes are still serialized normally
+	rw := httptest.NewRecorder()
+	require.Nil(t, WriteResourceToResponse(rw, deprecated))
+	assert.Contains(t, rw.Body.String(), `"nickName":"foo"`)
+}
+
+func TestWriteOnlyAttributesAreNotWritten(t *testing.T) {
+	resource := prop.NewResource(testUserResourceType(t))
+	_, err := resource.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"password": "s3cret",
+	})
+	require.Nil(t, err)
+	require.False(t, resource.Navigator().Dot("password").Current().IsUnassigned())
+
+	for _, options := range [][]scimjson.Options{
+		nil,
+		{scimjson.Include("password")},
+		{scimjson.Include("userName", "password")},
+		{scimjson.Include("urn:ietf:params:scim:schemas:core:2.0:User:password")},
+		{scimjson.Exclude("userName")},
+		{scimjson.Exclude("password")},
+	} {
+		rw := httptest.NewRecorder()
+		require.Nil(t, WriteResourceToResponse(rw, resource, options...))
+		assert.NotContains(t, rw.Body.String(), "password")
+		assert.NotContains(t, rw.Body.String(), "s3cret")
+
+		rw = httptest.NewRecorder()
+		require.Nil(t, WriteSearchResultToResponse(rw, &service.QueryResponse{
+			TotalResults: 1,
+			StartIndex:   1,
+			ItemsPerPage: 1,
+			Resources:    []scimjson.Serializable{resource},
+		}, options...))
+		assert.NotContains(t, rw.Body.String(), "password")
+		assert.NotContains(t, rw.Body.String(), "s3cret")
+	}
+}
+
+func TestLocationContext(t *testing.T) {
+	resource := prop.NewResource(testUserResourceType(t))
+	_, err := resource.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"meta": map[string]interface{}{
+			"location": "/Users/foo",
+			"version":  "W/\"1\"",
+		},
+	})
+	require.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		builder LocationBuilder
+		headers map[string]string
+		tls     bool
+		expect  string
+	}{
+		{
+			name:   "stored location without builder",
+			expect: "/Users/foo",
+		},
+		{
+			name:    "forwarded host and proto",
+			builder: ForwardedLocationBuilder("/scim/v2/"),
+			headers: map[string]string{"X-Forwarded-Host": "idp.example.com, proxy.internal", "X-Forwarded-Proto": "https"},
+			expect:  "https://idp.example.com/scim/v2/Users/foo",
+		},
+		{
+			name:    "request host without forwarded headers",
+			builder: ForwardedLocationBuilder(""),
+			expect:  "http://scim.internal:8080/Users/foo",
+		},
+		{
+			name:    "request over tls",
+			builder: ForwardedLocationBuilder(""),
+			tls:     true,
+			expect:  "https://scim.internal:8080/Users/foo",
+		},
+		{
+			name: "empty result falls back to stored location",
+			builder: func(_ *http.Request, _ *prop.Resource) string {
+				return ""
+			},
+			expect: "/Users/foo",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://scim.internal:8080/Users/foo", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			if test.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			var (
+				get       = httptest.NewRecorder()
+				created   = httptest.NewRecorder()
+				noContent = httptest.NewRecorder()
+			)
+			LocationContext(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				require.Nil(t, WriteResourceToResponseFor(get, r, resource))
+				require.Nil(t, WritePreferredResponse(created, r, http.StatusCreated, false, resource))
+				WriteNoContentToResponseFor(noContent, r, resource)
+			}), test.builder).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, test.expect, get.Header().Get("Location"))
+			assert.Equal(t, "W/\"1\"", get.Header().Get("ETag"))
+			assert.Contains(t, get.Body.String(), `"location":"/Users/foo"`)
+			assert.Equal(t, test.expect, created.Header().Get("Location"))
+			assert.Equal(t, test.expect, noContent.Header().Get("Location"))
+		})
+	}
+
+	// the builder only applies to the requests passing through LocationContext
+	r := httptest.NewRequest(http.MethodGet, "http://scim.internal:8080/Users/foo", nil)
+	rw := httptest.NewRecorder()
+	WriteResourceHeadersToResponseFor(rw, r, resource)
+	assert.Equal(t, "/Users/foo", rw.Header().Get("Location"))
+	assert.Empty(t, LocationFromContext(r.Context(), resource))
+
+	LocationContext(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "http://scim.internal:8080/scim/Users/foo", LocationFromContext(r.Context(), resource))
+	}), ForwardedLocationBuilder("/scim")).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestWriteErrorHeadersToResponse(t *testing.T) {
+	err := fmt.Errorf("%w: resource not found", spec.ErrNotFound)
+
+	get := httptest.NewRecorder()
+	require.Nil(t, WriteError(get, err))
+
+	head := httptest.NewRecorder()
+	WriteErrorHeadersToResponse(head, err)
+
+	assert.Equal(t, http.StatusNotFound, head.Code)
+	assert.Equal(t, get.Code, head.Code)
+	assert.Equal(t, get.Header(), head.Header())
+	assert.Empty(t, head.Body.Bytes())
+}
+
+// testUserResourceType registers the core and user schemas and returns the parsed user resource type.
+func testUserResourceType(t *testing.T) *spec.ResourceType {
+	for _, path := range []string{
+		"../../../public/schemas/core_schema.json",
+		"../../../public/schemas/user_schema.json",
+	} {
+		raw, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		schema := new(spec.Schema)
+		require.Nil(t, json.Unmarshal(raw, schema))
+		spec.Schemas().Register(schema)
+	}
+
+	raw, err := ioutil.ReadFile("../../../public/resource_types/user_resource_type.json")
+	require.Nil(t, err)
+	resourceType := new(spec.ResourceType)
+	require.Nil(t, json.Unmarshal(raw, resourceType))
+	return resourceType
+}
@@ -0,0 +1,97 @@
+package json
+
+import (
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+)
+
+// Delta returns Options to restrict JSON serialization to the attributes that have changed since the ref resource.
+// The serialized resource is compared against ref: singular complex properties are compared sub property by sub property,
+// while multiValued and simple properties are compared as a whole. Changed attributes are serialized in full, attributes
+// that have been removed are serialized as null (or empty array, for multiValued ones), and meta is always serialized.
+// Attributes that are returned always are serialized regardless of change. All other SCIM rules for return-ability still
+// apply. If the serialized target is not a resource, this option has no effect.
+func Delta(ref *prop.Resource) Options {
+	return deltaOption{ref: ref}
+}
+
+type deltaOption struct {
+	ref *prop.Resource
+}
+
+func (d deltaOption) apply(s *serializer, serializable Serializable) {
+	resource, ok := serializable.(*prop.Resource)
+	if !ok || d.ref == nil {
+		return
+	}
+
+	s.delta = &delta{
+		changed: []string{"meta"},
+		removed: []string{},
+	}
+	s.delta.compareChildren(d.ref.RootProperty(), resource.RootProperty())
+}
+
+// delta records the lower cased paths of changed attributes.
+type delta struct {
+	// paths of all changed attributes
+	changed []string
+	// paths of changed attributes that have become unassigned; this is a subset of changed.
+	removed []string
+}
+
+func (d *delta) compare(ref prop.Property, property prop.Property) {
+	attr := property.Attribute()
+	if !attr.MultiValued() && attr.Type() == spec.TypeComplex && !property.IsUnassigned() {
+		d.compareChildren(ref, property)
+		return
+	}
+
+	if ref.IsUnassigned() && property.IsUnassigned() {
+		return
+	}
+	if ref.IsUnassigned() == property.IsUnassigned() && ref.Hash() == property.Hash() {
+		return
+	}
+
+	path := strings.ToLower(attr.Path())
+	d.changed = append(d.changed, path)
+	if property.IsUnassigned() {
+		d.removed = append(d.removed, path)
+	}
+}
+
+func (d *delta) compareChildren(ref prop.Property, property prop.Property) {
+	refChildren := make([]prop.Property, 0, ref.CountChildren())
+	_ = ref.ForEachChild(func(_ int, child prop.Property) error {
+		refChildren = append(refChildren, child)
+		return nil
+	})
+	_ = property.ForEachChild(func(index int, child prop.Property) error {
+		if index < len(refChildren) {
+			d.compare(refChildren[index], child)
+		}
+		return nil
+	})
+}
+
+// covers returns true if the lower cased path is a changed attribute, or a parent or a sub attribute of it.
+func (d *delta) covers(path string) bool {
+	for _, changed := range d.changed {
+		if changed == path || strings.HasPrefix(path, changed+".") || strings.HasPrefix(changed, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isRemoved returns true if the lower cased path is a changed attribute that has become unassigned.
+func (d *delta) isRemoved(path string) bool {
+	for _, removed := range d.removed {
+		if removed == path {
+			return true
+		}
+	}
+	return false
+}
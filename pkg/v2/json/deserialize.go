@@ -4,15 +4,19 @@ import (
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 	"unicode"
 	"unicode/utf16"
 	"unicode/utf8"
 )
 
 // Deserialize is the entry point of JSON deserialization. Unmarshal the JSON input bytes into a pre-prepared unassigned
-// structure of Resource.
-func Deserialize(json []byte, resource *prop.Resource) error {
+// structure of Resource. Options that affect the JSON field names (i.e. FieldName) are respected, other options are
+// ignored.
+func Deserialize(json []byte, resource *prop.Resource, options ...Options) error {
 	if err := checkValid(json, &scanner{}); err != nil {
 		return err
 	}
@@ -24,6 +28,7 @@ func Deserialize(json []byte, resource *prop.Resource) error {
 		scan:      scanner{},
 		navigator: resource.Navigator(),
 	}
+	state.applyOptions(options)
 	state.scan.reset()
 
 	// skip the first few spaces
@@ -37,7 +42,8 @@ func Deserialize(json []byte, resource *prop.Resource) error {
 //
 // The allowElementForArray option is provided to allow JSON array element values be provided for a multiValued property
 // so that it will be de-serialized as its element. The result will be a multiValued property containing a single element.
-func DeserializeProperty(json []byte, property prop.Property, allowElementForArray bool) error {
+// Options are respected in the same way as Deserialize.
+func DeserializeProperty(json []byte, property prop.Property, allowElementForArray bool, options ...Options) error {
 	state := &deserializeState{
 		data:      json,
 		off:       0,
@@ -45,6 +51,7 @@ func DeserializeProperty(json []byte, property prop.Property, allowElementForArr
 		scan:      scanner{},
 		navigator: prop.Navigate(property),
 	}
+	state.applyOptions(options)
 	state.scan.reset()
 
 	// Since this function is intended for bytes from json.RawMessage, it is not possible for it to precede with
@@ -101,6 +108,18 @@ type deserializeState struct {
 	opCode    int // last read result
 	scan      scanner
 	navigator prop.Navigator
+	// lower cased JSON field names to their attribute names, non-nil only when names are mapped
+	attributeNames map[string]string
+	// true to reject dateTime values that are not valid xsd:dateTime values
+	strictDateTime bool
+}
+
+func (d *deserializeState) applyOptions(options []Options) {
+	for _, opt := range options {
+		if dopt, ok := opt.(deserializeOptions); ok {
+			dopt.applyDeserialize(d)
+		}
+	}
 }
 
 func (d *deserializeState) errInvalidSyntax(msg string, args ...interface{}) error {
@@ -128,7 +147,11 @@ Skip:
 		}
 	}
 
-	return string(d.data[start+1 : end-1]), nil
+	name := string(d.data[start+1 : end-1])
+	if attrName, ok := d.attributeNames[strings.ToLower(name)]; ok {
+		return attrName, nil
+	}
+	return name, nil
 }
 
 // Parses a top level or embedded JSON object. When parsing a top level object, allowNull shall be false as top level
@@ -280,6 +303,13 @@ elements:
 		}
 	}
 
+	// An explicitly empty array is different from an absent one.
+	if d.navigator.Current().CountChildren() == 0 {
+		if _, err := d.navigator.Current().Delete(); err != nil {
+			return err
+		}
+	}
+
 	// Courtesy: skip any spaces between ']' and the next tokens
 	if d.opCode == scanSkipSpace {
 		d.scanWhile(scanSkipSpace)
@@ -325,6 +355,11 @@ func (d *deserializeState) parseStringProperty() error {
 		return d.errInvalidSyntax("failed to unquote json string for '%s'", p.Attribute().Path())
 	}
 
+	if d.strictDateTime && p.Attribute().Type() == spec.TypeDateTime &&
+		p.Attribute().Mutability() != spec.MutabilityReadOnly && !isXsdDateTime(v) {
+		return fmt.Errorf("%w: value '%s' for '%s' is not a valid xsd:dateTime", spec.ErrInvalidValue, v, p.Attribute().Path())
+	}
+
 	if _, err := d.navigator.Current().Replace(v); err != nil {
 		return err
 	}
@@ -667,3 +702,32 @@ func getu4(s []byte) rune {
 	}
 	return r
 }
+
+// xsd:dateTime lexical form with a mandatory timezone designator. Negative and five or more digit years are not
+// supported, as they cannot be represented by the dateTime property.
+var xsdDateTime = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})T(\d{2}):(\d{2}):(\d{2})(\.\d+)?(Z|[+-](\d{2}):(\d{2}))$`)
+
+// isXsdDateTime returns true if the value is a valid xsd:dateTime value with timezone, whose fields are in range.
+func isXsdDateTime(value string) bool {
+	m := xsdDateTime.FindStringSubmatch(value)
+	if m == nil {
+		return false
+	}
+
+	// numeric value of each group; non-numeric groups (fraction and timezone designator) are left zero.
+	n := make([]int, len(m))
+	for i := 1; i < len(m); i++ {
+		n[i], _ = strconv.Atoi(m[i])
+	}
+	year, month, day, hour, minute, second := n[1], n[2], n[3], n[4], n[5], n[6]
+
+	switch {
+	case year == 0, month < 1, month > 12, day < 1, hour > 23, minute > 59, second > 59:
+		return false
+	case time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() != day:
+		return false // day out of range for the month
+	case m[8] != "Z" && (n[9] > 14 || n[10] > 59 || n[9] == 14 && n[10] > 0):
+		return false // timezone offset out of range
+	}
+	return true
+}
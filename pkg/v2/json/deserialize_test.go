@@ -2,6 +2,7 @@ package json
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"testing"
 )
 
@@ -495,6 +497,74 @@ func (s *JsonDeserializeTestSuite) TestDeserializeProperty() {
 	}
 }
 
+func (s *JsonDeserializeTestSuite) TestStrictDateTime() {
+	tests := []struct {
+		name    string
+		value   string
+		options []Options
+		valid   bool
+	}{
+		{name: "utc", value: "2008-01-23T04:56:22Z", options: []Options{StrictDateTime()}, valid: true},
+		{name: "offset", value: "2008-01-23T04:56:22+08:00", options: []Options{StrictDateTime()}, valid: true},
+		{name: "fractional seconds", value: "2008-01-23T04:56:22.123-05:30", options: []Options{StrictDateTime()}, valid: true},
+		{name: "leap day", value: "2020-02-29T00:00:00Z", options: []Options{StrictDateTime()}, valid: true},
+		{name: "leap second", value: "2016-12-31T23:59:60Z", options: []Options{StrictDateTime()}},
+		{name: "missing T", value: "2008-01-23 04:56:22Z", options: []Options{StrictDateTime()}},
+		{name: "missing timezone", value: "2008-01-23T04:56:22", options: []Options{StrictDateTime()}},
+		{name: "two digit year", value: "08-01-23T04:56:22Z", options: []Options{StrictDateTime()}},
+		{name: "month out of range", value: "2008-13-23T04:56:22Z", options: []Options{StrictDateTime()}},
+		{name: "day out of range", value: "2019-02-29T04:56:22Z", options: []Options{StrictDateTime()}},
+		{name: "end of day midnight", value: "2008-01-23T24:00:00Z", options: []Options{StrictDateTime()}},
+		{name: "offset out of range", value: "2008-01-23T04:56:22+14:30", options: []Options{StrictDateTime()}},
+		{name: "lenient without option", value: "2008-01-23T04:56:22", valid: true},
+		{name: "lenient timezone without option", value: "2008-01-23T04:56:22+08:00", valid: true},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			property := s.propForAttr(t, `
+{
+	"name": "validUntil",
+	"type": "dateTime",
+	"_path": "validUntil"
+}
+`)
+			err := DeserializeProperty([]byte(strconv.Quote(test.value)), property, false, test.options...)
+			if test.valid {
+				assert.Nil(t, err)
+				assert.NotNil(t, property.Raw())
+			} else {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "validUntil")
+			}
+		})
+	}
+}
+
+func (s *JsonDeserializeTestSuite) TestStrictDateTimeRoundTrip() {
+	resource := prop.NewResource(s.resourceType)
+	assert.Nil(s.T(), Deserialize([]byte(`{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "3cc032f5-2361-417f-9e2f-bc80adddf4a3",
+	"userName": "imulab",
+	"meta": {
+		"resourceType": "User",
+		"created": "2019-11-20T13:09:00",
+		"lastModified": "2019-11-20T13:09:00",
+		"version": "W/\"1\""
+	}
+}`), resource))
+
+	raw, err := Serialize(resource)
+	require.Nil(s.T(), err)
+
+	replica := prop.NewResource(s.resourceType)
+	assert.Nil(s.T(), Deserialize(raw, replica, StrictDateTime()))
+	assert.Equal(s.T(), "2019-11-20T13:09:00", replica.Navigator().Dot("meta").Dot("created").Current().Raw())
+	assert.Equal(s.T(), "2019-11-20T13:09:00", replica.Navigator().Dot("meta").Dot("lastModified").Current().Raw())
+}
+
 func (s *JsonDeserializeTestSuite) SetupSuite() {
 	for _, each := range []struct {
 		filepath  string
@@ -11,48 +11,106 @@ func Include(attributes ...string) Options {
 }
 
 // Exclude returns Options to exclude given attributes in JSON serialization. Supplied attributes are still
-// subject to SCIM rules for return-ability.
+// subject to SCIM rules for return-ability. Excluding a sub attribute (i.e. name.familyName) only removes the sub
+// attribute and leaves its siblings in place; for a multiValued complex attribute (i.e. emails.display), the sub
+// attribute is removed from all elements. A container left without any sub attribute to render is omitted.
 func Exclude(attributes ...string) Options {
 	return exclude{attributes: attributes}
 }
 
+// FieldName returns Options to use the given name as the JSON field name of the attributes named attribute, instead
+// of the attribute name itself. For example, FieldName("$ref", "ref") writes and reads the "$ref" sub attributes as
+// "ref". The mapping only affects the JSON representation: attribute paths used in Include, Exclude, filters and
+// patch paths still refer to the attribute name. To round trip, the same options shall be supplied to Serialize and
+// Deserialize. Attribute names are matched case insensitively.
+func FieldName(attribute string, name string) Options {
+	return fieldName{attribute: attribute, name: name}
+}
+
+// StrictDateTime returns Options to reject dateTime values that are not valid xsd:dateTime values, as required by
+// RFC 7643, during JSON de-serialization. A valid value has the form "YYYY-MM-DDThh:mm:ss", optionally followed by
+// fractional seconds, and must be followed by a timezone designator ("Z" or "+hh:mm"/"-hh:mm"). Each field must be in
+// range, hence leap seconds ("23:59:60") and the end of day midnight ("24:00:00") are rejected as well. Offending
+// values are reported as invalidValue, naming the attribute path. ReadOnly attributes are not validated: they are
+// assigned by the server, which renders them without timezone (see spec.ISO8601), so that a resource read from the
+// server can be sent back as is. Without this option, dateTime values are parsed leniently. The option does not affect
+// serialization.
+func StrictDateTime() Options {
+	return strictDateTime{}
+}
+
+// PreserveEmpty returns Options to render complex and multiValued properties that have no assigned value, but were
+// explicitly set (i.e. assigned {} or []) or emptied after being set (i.e. by PATCH remove of each sub attribute), as
+// an empty object or an empty array. By default, such properties are normalized as unassigned and omitted, since some
+// clients reject empty objects. Properties that were never set, as told by their Dirty state, are omitted regardless;
+// the Dirty state is precise on resources de-serialized from JSON, which only sets the attributes present in the JSON.
+// The option does not affect de-serialization.
+func PreserveEmpty() Options {
+	return preserveEmpty{}
+}
+
 // JSON serialization options.
 type Options interface {
 	apply(s *serializer, serializable Serializable)
 }
 
+// Options that also apply to JSON de-serialization. Options that do not implement this interface are ignored
+// by Deserialize and DeserializeProperty.
+type deserializeOptions interface {
+	applyDeserialize(d *deserializeState)
+}
+
 type include struct {
 	attributes []string
 }
 
-func (i include) apply(s *serializer, serializable Serializable) {
-	if s.includes == nil {
-		s.includes = []string{}
-	}
-	for _, path := range i.attributes {
-		if len(path) > 0 {
-			s.includes = append(s.includes, strings.TrimPrefix(
-				strings.ToLower(path),
-				strings.ToLower(serializable.MainSchemaId()+":")),
-			)
-		}
-	}
+func (i include) apply(s *serializer, _ Serializable) {
+	s.includes = append(s.includes, i.attributes...)
 }
 
 type exclude struct {
 	attributes []string
 }
 
-func (e exclude) apply(s *serializer, serializable Serializable) {
-	if s.excludes == nil {
-		s.excludes = []string{}
+func (e exclude) apply(s *serializer, _ Serializable) {
+	s.excludes = append(s.excludes, e.attributes...)
+}
+
+type fieldName struct {
+	attribute string
+	name      string
+}
+
+func (f fieldName) apply(s *serializer, _ Serializable) {
+	if len(f.attribute) == 0 || len(f.name) == 0 {
+		return
 	}
-	for _, path := range e.attributes {
-		if len(path) > 0 {
-			s.excludes = append(s.excludes, strings.TrimPrefix(
-				strings.ToLower(path),
-				strings.ToLower(serializable.MainSchemaId()+":")),
-			)
-		}
+	if s.fieldNames == nil {
+		s.fieldNames = map[string]string{}
 	}
+	s.fieldNames[strings.ToLower(f.attribute)] = f.name
+}
+
+func (f fieldName) applyDeserialize(d *deserializeState) {
+	if len(f.attribute) == 0 || len(f.name) == 0 {
+		return
+	}
+	if d.attributeNames == nil {
+		d.attributeNames = map[string]string{}
+	}
+	d.attributeNames[strings.ToLower(f.name)] = f.attribute
+}
+
+type preserveEmpty struct{}
+
+func (p preserveEmpty) apply(s *serializer, _ Serializable) {
+	s.preserveEmpty = true
+}
+
+type strictDateTime struct{}
+
+func (s strictDateTime) apply(_ *serializer, _ Serializable) {}
+
+func (s strictDateTime) applyDeserialize(d *deserializeState) {
+	d.strictDateTime = true
 }
@@ -0,0 +1,140 @@
+package json
+
+import (
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+	"sync"
+)
+
+// NewProjection compiles the attributes and excludedAttributes request parameters into a Projection, to be supplied
+// to Serialize or SerializeStream with Project. Serializing many resources with the same Projection resolves each
+// attribute path against the requested paths only once, instead of once per resource. An error wrapping
+// spec.ErrInvalidValue is returned when both attributes and excludedAttributes are specified.
+//
+// A Projection is safe for concurrent use, so that it can be shared by the goroutines serving the same request.
+func NewProjection(attributes []string, excludedAttributes []string) (*Projection, error) {
+	p := &Projection{
+		attributes:         nonEmpty(attributes),
+		excludedAttributes: nonEmpty(excludedAttributes),
+	}
+	if len(p.attributes) > 0 && len(p.excludedAttributes) > 0 {
+		return nil, fmt.Errorf("%w: attributes and excludedAttributes are mutually exclusive", spec.ErrInvalidValue)
+	}
+	return p, nil
+}
+
+// Project returns Options to serialize with the compiled projection, in place of Include and Exclude. The projection
+// is still subject to SCIM rules for return-ability, the same as Include and Exclude.
+func Project(projection *Projection) Options {
+	return project{projection: projection}
+}
+
+// Projection is the compiled form of the attributes and excludedAttributes request parameters. Use NewProjection to
+// create one.
+type Projection struct {
+	attributes         []string
+	excludedAttributes []string
+	// guards schemas
+	mu sync.Mutex
+	// requested paths compiled against the main schema ids serialized so far
+	schemas map[string]*compiledPaths
+}
+
+// requested paths compiled against a main schema id
+type compiledPaths struct {
+	// lower cased paths, with the main schema id prefix trimmed
+	includes []string
+	excludes []string
+	// decisions of the attribute paths resolved so far
+	decisions map[string]projected
+}
+
+// decision of an attribute path against the projection
+type projected struct {
+	// the path is requested by, or is the ancestor of a path requested by, attributes
+	included bool
+	// the path is, or is the descendant of a path, excluded by excludedAttributes
+	excluded bool
+}
+
+// isEmpty returns true if the projection neither includes nor excludes any attribute.
+func (p *Projection) isEmpty() bool {
+	return len(p.attributes) == 0 && len(p.excludedAttributes) == 0
+}
+
+// isInclusive returns true if the projection is formed by the attributes, rather than the excludedAttributes.
+func (p *Projection) isInclusive() bool {
+	return len(p.attributes) > 0
+}
+
+// decide returns the decision of the attribute of a resource of the main schema against the projection. Attributes
+// sharing the same path, such as a multiValued attribute and its derived element attribute, share the same decision.
+func (p *Projection) decide(mainSchemaId string, attr *spec.Attribute) projected {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.schemas[mainSchemaId]
+	if !ok {
+		c = &compiledPaths{
+			includes:  normalizePaths(p.attributes, mainSchemaId),
+			excludes:  normalizePaths(p.excludedAttributes, mainSchemaId),
+			decisions: map[string]projected{},
+		}
+		if p.schemas == nil {
+			p.schemas = map[string]*compiledPaths{}
+		}
+		p.schemas[mainSchemaId] = c
+	}
+	if d, ok := c.decisions[attr.Path()]; ok {
+		return d
+	}
+
+	var (
+		d    projected
+		test = strings.ToLower(attr.Path())
+	)
+	for _, include := range c.includes {
+		if spec.PathCovers(include, test) || spec.PathCovers(test, include) {
+			d.included = true
+			break
+		}
+	}
+	for _, exclude := range c.excludes {
+		if spec.PathCovers(exclude, test) {
+			d.excluded = true
+			break
+		}
+	}
+	c.decisions[attr.Path()] = d
+	return d
+}
+
+type project struct {
+	projection *Projection
+}
+
+func (p project) apply(s *serializer, _ Serializable) {
+	s.projection = p.projection
+}
+
+// normalizePaths lower cases the paths and trims the main schema id prefix.
+func normalizePaths(paths []string, mainSchemaId string) []string {
+	prefix := strings.ToLower(mainSchemaId + ":")
+	normalized := make([]string, 0, len(paths))
+	for _, path := range paths {
+		normalized = append(normalized, strings.TrimPrefix(strings.ToLower(path), prefix))
+	}
+	return normalized
+}
+
+// nonEmpty returns the paths without empty ones.
+func nonEmpty(paths []string) []string {
+	filtered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if len(path) > 0 {
+			filtered = append(filtered, path)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,227 @@
+package json
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"sync"
+	"testing"
+)
+
+func (s *JsonSerializeTestSuite) TestSerializeStream() {
+	resources := make([]Serializable, 0, 3)
+	for i := 0; i < 3; i++ {
+		r := prop.NewResource(s.resourceType)
+		_, err := r.RootProperty().Replace(s.resourceData)
+		require.Nil(s.T(), err)
+		require.False(s.T(), r.Navigator().Dot("id").Replace(fmt.Sprintf("user%d", i)).HasError())
+		require.False(s.T(), r.Navigator().Dot("password").Replace("s3cret").HasError())
+		resources = append(resources, r)
+	}
+
+	projection, err := NewProjection([]string{"userName", "name.givenName", "password"}, nil)
+	require.Nil(s.T(), err)
+
+	tests := []struct {
+		name    string
+		options []Options
+		expect  func(t *testing.T, raws []string, err error)
+	}{
+		{
+			name:    "include",
+			options: []Options{Include("userName", "name.givenName", "password")},
+			expect: func(t *testing.T, raws []string, err error) {
+				assert.Nil(t, err)
+				require.Len(t, raws, 3)
+				for i, raw := range raws {
+					assert.JSONEq(t, fmt.Sprintf(`
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "user%d",
+  "userName": "imulab",
+  "name": {
+    "givenName": "Weinan"
+  }
+}`, i), raw)
+				}
+			},
+		},
+		{
+			name:    "exclude with main schema prefix",
+			options: []Options{Exclude("urn:ietf:params:scim:schemas:core:2.0:User:emails", "meta", "name.formatted")},
+			expect: func(t *testing.T, raws []string, err error) {
+				assert.Nil(t, err)
+				require.Len(t, raws, 3)
+				for i, raw := range raws {
+					expect, err := Serialize(resources[i], Exclude("emails", "meta", "name.formatted"))
+					require.Nil(t, err)
+					assert.JSONEq(t, string(expect), raw)
+					assert.NotContains(t, raw, "emails")
+					assert.NotContains(t, raw, "formatted")
+					assert.NotContains(t, raw, "password")
+					assert.Contains(t, raw, "familyName")
+				}
+			},
+		},
+		{
+			name:    "compiled projection",
+			options: []Options{Project(projection)},
+			expect: func(t *testing.T, raws []string, err error) {
+				assert.Nil(t, err)
+				require.Len(t, raws, 3)
+				for i, raw := range raws {
+					expect, err := Serialize(resources[i], Include("userName", "name.givenName"))
+					require.Nil(t, err)
+					assert.JSONEq(t, string(expect), raw)
+				}
+			},
+		},
+		{
+			name:    "compiled projection reused",
+			options: []Options{Project(projection)},
+			expect: func(t *testing.T, raws []string, err error) {
+				assert.Nil(t, err)
+				require.Len(t, raws, 3)
+				assert.NotContains(t, raws[0], "password")
+				assert.Contains(t, raws[0], "givenName")
+				assert.NotContains(t, raws[0], "familyName")
+			},
+		},
+		{
+			name:    "include and exclude are mutually exclusive",
+			options: []Options{Include("userName"), Exclude("emails")},
+			expect: func(t *testing.T, raws []string, err error) {
+				assert.True(t, errors.Is(err, spec.ErrInvalidValue))
+				assert.Empty(t, raws)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			raws := make([]string, 0)
+			err := SerializeStream(resources, func(_ int, raw []byte) error {
+				raws = append(raws, string(raw))
+				return nil
+			}, test.options...)
+			test.expect(t, raws, err)
+		})
+	}
+}
+
+func (s *JsonSerializeTestSuite) TestNewProjection() {
+	_, err := NewProjection([]string{"userName"}, []string{"emails"})
+	assert.True(s.T(), errors.Is(err, spec.ErrInvalidValue))
+
+	projection, err := NewProjection([]string{""}, nil)
+	assert.Nil(s.T(), err)
+
+	r := prop.NewResource(s.resourceType)
+	_, err = r.RootProperty().Replace(s.resourceData)
+	require.Nil(s.T(), err)
+
+	raw, err := Serialize(r, Project(projection))
+	assert.Nil(s.T(), err)
+	expect, err := Serialize(r)
+	assert.Nil(s.T(), err)
+	assert.JSONEq(s.T(), string(expect), string(raw))
+}
+
+func (s *JsonSerializeTestSuite) TestProjectionConcurrentUse() {
+	r := prop.NewResource(s.resourceType)
+	_, err := r.RootProperty().Replace(s.resourceData)
+	require.Nil(s.T(), err)
+
+	projection, err := NewProjection([]string{"userName", "name.givenName"}, nil)
+	require.Nil(s.T(), err)
+	expect, err := Serialize(r, Include("userName", "name.givenName"))
+	require.Nil(s.T(), err)
+
+	var wg sync.WaitGroup
+	raws := make([][]byte, 8)
+	errs := make([]error, 8)
+	for i := range raws {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			raws[i], errs[i] = Serialize(r, Project(projection))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range raws {
+		assert.Nil(s.T(), errs[i])
+		assert.JSONEq(s.T(), string(expect), string(raws[i]))
+	}
+}
+
+func BenchmarkSerializeStream(b *testing.B) {
+	resourceType := loadUserResourceType(b)
+	resources := make([]Serializable, 0, 10000)
+	for i := 0; i < 10000; i++ {
+		r := prop.NewResource(resourceType)
+		require.False(b, r.Navigator().Replace(map[string]interface{}{
+			"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":       fmt.Sprintf("%04d", i),
+			"userName": fmt.Sprintf("user%04d", i),
+			"active":   i%2 == 1,
+			"name": map[string]interface{}{
+				"givenName":  fmt.Sprintf("Given%d", i),
+				"familyName": fmt.Sprintf("Family%d", i%10),
+			},
+			"emails": []interface{}{
+				map[string]interface{}{
+					"value": fmt.Sprintf("user%04d@foo.com", i),
+					"type":  "work",
+				},
+			},
+		}).HasError())
+		resources = append(resources, r)
+	}
+	attributes := []string{"userName", "name.familyName", "emails.value", "urn:ietf:params:scim:schemas:core:2.0:User:active"}
+
+	b.Run("per resource", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, r := range resources {
+				if _, err := Serialize(r, Include(attributes...)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := SerializeStream(resources, func(_ int, _ []byte) error {
+				return nil
+			}, Include(attributes...)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func loadUserResourceType(t testing.TB) *spec.ResourceType {
+	for _, path := range []string{
+		"../../../public/schemas/core_schema.json",
+		"../../../public/schemas/user_schema.json",
+	} {
+		raw, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		schema := new(spec.Schema)
+		require.Nil(t, json.Unmarshal(raw, schema))
+		spec.Schemas().Register(schema)
+	}
+
+	raw, err := ioutil.ReadFile("../../../public/resource_types/user_resource_type.json")
+	require.Nil(t, err)
+	resourceType := new(spec.ResourceType)
+	require.Nil(t, json.Unmarshal(raw, resourceType))
+	return resourceType
+}
@@ -20,7 +20,11 @@ type Serializable interface {
 }
 
 // Serialize the given resource to JSON bytes. The serialization process subjects to the request attributes and
-// excludedAttributes from options, and the SCIM return-ability rules.
+// excludedAttributes from options, and the SCIM return-ability rules. Attributes with writeOnly mutability, including
+// sub attributes of complex attributes, are never serialized, even when explicitly requested through attributes.
+// Unassigned properties are omitted: a complex property without any assigned sub property and a multiValued property
+// without any element are treated as unassigned, unless PreserveEmpty is supplied. Elements of multiValued properties
+// are serialized in the order they are held, which, for a resource de-serialized from JSON, is the order of the input.
 func Serialize(serializable Serializable, options ...Options) ([]byte, error) {
 	s := serializer{
 		Buffer:   bytes.Buffer{},
@@ -33,8 +37,26 @@ func Serialize(serializable Serializable, options ...Options) ([]byte, error) {
 		opt.apply(&s, serializable)
 	}
 
-	if len(s.includes) > 0 && len(s.excludes) > 0 {
-		return nil, fmt.Errorf("%w: attributes and excludedAttributes are mutually exclusive", spec.ErrInvalidValue)
+	if len(s.includes) > 0 || len(s.excludes) > 0 {
+		var (
+			attributes         = s.includes
+			excludedAttributes = s.excludes
+			err                error
+		)
+		if s.projection != nil {
+			attributes = append(append([]string{}, s.projection.attributes...), attributes...)
+			excludedAttributes = append(append([]string{}, s.projection.excludedAttributes...), excludedAttributes...)
+		}
+		if s.projection, err = NewProjection(attributes, excludedAttributes); err != nil {
+			return nil, err
+		}
+	}
+	if s.projection != nil {
+		if s.projection.isEmpty() {
+			s.projection = nil
+		} else {
+			s.mainSchemaId = serializable.MainSchemaId()
+		}
 	}
 
 	if err := serializable.Visit(&s); err != nil {
@@ -44,6 +66,49 @@ func Serialize(serializable Serializable, options ...Options) ([]byte, error) {
 	return s.Bytes(), nil
 }
 
+// SerializeStream serializes the serializables one after another with the same options, and invokes callback with the
+// index and the JSON bytes of each, in order. It stops at the first error returned by serialization or callback. The
+// JSON bytes are owned by the callback.
+//
+// Include and Exclude options are compiled into a single Projection before serialization starts, so that attribute
+// paths are resolved once for the whole batch rather than once per serializable. A Projection built by NewProjection
+// may also be supplied with Project, so that it can be shared with other calls serving the same request.
+func SerializeStream(serializables []Serializable, callback func(index int, raw []byte) error, options ...Options) error {
+	var (
+		attributes         []string
+		excludedAttributes []string
+		streamOptions      = make([]Options, 0, len(options)+1)
+	)
+	for _, opt := range options {
+		switch o := opt.(type) {
+		case include:
+			attributes = append(attributes, o.attributes...)
+		case exclude:
+			excludedAttributes = append(excludedAttributes, o.attributes...)
+		default:
+			streamOptions = append(streamOptions, opt)
+		}
+	}
+	if len(attributes) > 0 || len(excludedAttributes) > 0 {
+		projection, err := NewProjection(attributes, excludedAttributes)
+		if err != nil {
+			return err
+		}
+		streamOptions = append(streamOptions, Project(projection))
+	}
+
+	for i, serializable := range serializables {
+		raw, err := Serialize(serializable, streamOptions...)
+		if err != nil {
+			return err
+		}
+		if err := callback(i, raw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 const (
 	containerObject container = iota
 	containerArray
@@ -62,18 +127,29 @@ type (
 	// json serializer state
 	serializer struct {
 		bytes.Buffer
+		// paths from Include and Exclude, compiled into projection before serialization
 		includes []string
 		excludes []string
-		stack    []*frame
-		scratch  [64]byte
+		// non-nil only when serialization is restricted by attributes or excludedAttributes
+		projection *Projection
+		// main schema id of the serializable, which the projection decides against
+		mainSchemaId string
+		stack        []*frame
+		scratch      [64]byte
+		// non-nil only when serialization is restricted to the changed attributes
+		delta *delta
+		// lower cased attribute names to their JSON field names, non-nil only when names are mapped
+		fieldNames map[string]string
+		// true to render explicitly set complex and multiValued properties without assigned values as {} and []
+		preserveEmpty bool
 	}
 )
 
 func (s *serializer) ShouldVisit(property prop.Property) bool {
 	attr := property.Attribute()
 
-	// Write only properties are never returned. It is usually coupled
-	// with returned=never, but we will check it to make sure.
+	// Write only properties are never returned, not even when requested. It is
+	// usually coupled with returned=never, but we will check it to make sure.
 	if attr.Mutability() == spec.MutabilityWriteOnly {
 		return false
 	}
@@ -84,44 +160,53 @@ func (s *serializer) ShouldVisit(property prop.Property) bool {
 	case spec.ReturnedNever:
 		return false
 	case spec.ReturnedDefault:
-		if len(s.includes) == 0 && len(s.excludes) == 0 {
-			return !property.IsUnassigned()
-		} else {
-			test := strings.ToLower(property.Attribute().Path())
-			if len(s.includes) > 0 {
-				for _, include := range s.includes {
-					if include == test || strings.HasPrefix(include, test+".") || strings.HasPrefix(test, include+".") {
-						return !property.IsUnassigned()
-					}
-				}
-				return false
-			} else if len(s.excludes) > 0 {
-				for _, exclude := range s.excludes {
-					if exclude == test || strings.HasPrefix(test, exclude+".") {
-						return false
-					}
-				}
-				return !property.IsUnassigned()
-			} else {
-				panic("impossible: either includeFamily or excludeFamily")
-			}
+		if s.delta != nil && !s.delta.covers(strings.ToLower(attr.Path())) {
+			return false
+		}
+		if s.projection == nil {
+			return s.isAssignedOrRemoved(property)
+		}
+		if s.projection.isInclusive() {
+			return s.projection.decide(s.mainSchemaId, attr).included && s.isAssignedOrRemoved(property)
 		}
+		if s.projection.decide(s.mainSchemaId, attr).excluded {
+			return false
+		}
+		return s.isAssignedOrRemoved(property) && s.hasVisibleChild(property)
 	case spec.ReturnedRequest:
-		if len(s.includes) > 0 {
-			test := strings.ToLower(property.Attribute().Path())
-			for _, include := range s.includes {
-				if include == test || strings.HasPrefix(include, test+".") || strings.HasPrefix(test, include+".") {
-					return true
-				}
-			}
+		if s.delta != nil && !s.delta.covers(strings.ToLower(attr.Path())) {
 			return false
 		}
-		return false
+		return s.projection != nil && s.projection.isInclusive() && s.projection.decide(s.mainSchemaId, attr).included
 	default:
 		panic("invalid returned-ability")
 	}
 }
 
+// hasVisibleChild returns true if the property is not an assigned container, or if at least one of its children is to be
+// visited. When all sub attributes of a complex property (or of all elements of a multiValued property) are excluded,
+// it prevents the container from being rendered as an empty object or array.
+func (s *serializer) hasVisibleChild(property prop.Property) bool {
+	attr := property.Attribute()
+	if property.IsUnassigned() || (!attr.MultiValued() && attr.Type() != spec.TypeComplex) {
+		return true
+	}
+	return property.FindChild(s.ShouldVisit) != nil
+}
+
+// isAssignedOrRemoved returns true if the property is assigned, or when serializing changed attributes, if the property
+// has become unassigned, so the removal can be rendered. When empty containers are preserved, an unassigned complex or
+// multiValued property that was explicitly set is also considered, so it can be rendered as {} or [].
+func (s *serializer) isAssignedOrRemoved(property prop.Property) bool {
+	if !property.IsUnassigned() {
+		return true
+	}
+	if s.preserveEmpty && property.Dirty() && (property.Attribute().MultiValued() || property.Attribute().Type() == spec.TypeComplex) {
+		return true
+	}
+	return s.delta != nil && s.delta.isRemoved(strings.ToLower(property.Attribute().Path()))
+}
+
 func (s *serializer) Visit(property prop.Property) error {
 	if s.current().index > 0 {
 		_ = s.WriteByte(',')
@@ -137,6 +222,7 @@ func (s *serializer) Visit(property prop.Property) error {
 
 	if property.IsUnassigned() {
 		s.appendNull()
+		s.current().index++
 		return nil
 	}
 
@@ -187,7 +273,11 @@ func (s *serializer) EndChildren(container prop.Property) {
 
 func (s *serializer) appendPropertyName(attribute *spec.Attribute) {
 	_ = s.WriteByte('"')
-	_, _ = s.WriteString(attribute.Name())
+	if name, ok := s.fieldNames[strings.ToLower(attribute.Name())]; ok {
+		_, _ = s.WriteString(name)
+	} else {
+		_, _ = s.WriteString(attribute.Name())
+	}
 	_ = s.WriteByte('"')
 	_ = s.WriteByte(':')
 }
@@ -277,27 +367,12 @@ func (s *serializer) appendFloat(value float64) {
 		panic(fmt.Errorf("%w: invalid decimal in json serialization", spec.ErrInvalidValue))
 	}
 
-	// Convert as if by ES6 number to string conversion.
-	// This matches most other JSON generators.
-	// See golang.org/issue/6384 and golang.org/issue/14135.
-	// Like fmt %g, but the exponent cutoffs are different
-	// and exponents themselves are not padded to two digits.
-	b := s.scratch[:0]
-	abs := math.Abs(value)
-	format := byte('f')
-	if abs != 0 {
-		if abs < 1e-6 || abs >= 1e21 {
-			format = 'e'
-		}
-	}
-	b = strconv.AppendFloat(b, value, format, -1, 64)
-	if format == 'e' {
-		// clean up e-09 to e-9
-		n := len(b)
-		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
-			b[n-2] = b[n-1]
-			b = b[:n-1]
-		}
+	// Decimals are always rendered in fixed notation, as RFC 7643 defines the decimal type to be a real number with at
+	// least one digit to the left and right of the period. Exponent notation is never used, and the precision of -1
+	// picks the smallest number of digits that represents the value exactly, so there is no trailing noise either.
+	b := strconv.AppendFloat(s.scratch[:0], value, 'f', -1, 64)
+	if bytes.IndexByte(b, '.') < 0 {
+		b = append(b, '.', '0')
 	}
 	_, _ = s.Write(b)
 }
@@ -184,6 +184,115 @@ func (s *JsonSerializeTestSuite) TestSerialize() {
       }
    ]
 }
+`
+				assert.JSONEq(t, expect, string(raw))
+			},
+		},
+		{
+			name: "delta attributes",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				_, err := r.RootProperty().Replace(s.resourceData)
+				assert.Nil(t, err)
+				return r
+			},
+			options: []Options{
+				Delta(s.deltaRef()),
+			},
+			expect: func(t *testing.T, raw []byte, err error) {
+				assert.Nil(t, err)
+				expect := `
+{
+   "schemas":[
+      "urn:ietf:params:scim:schemas:core:2.0:User"
+   ],
+   "id":"3cc032f5-2361-417f-9e2f-bc80adddf4a3",
+   "meta":{
+      "resourceType":"User",
+      "created":"2019-11-20T13:09:00",
+      "lastModified":"2019-11-20T13:09:00",
+      "location":"https://identity.imulab.io/Users/3cc032f5-2361-417f-9e2f-bc80adddf4a3",
+      "version":"W/\"1\""
+   },
+   "name":{
+      "givenName":"Weinan"
+   },
+   "nickName":null,
+   "phoneNumbers":[
+      {
+         "value":"123-45678",
+         "type":"work",
+         "primary":true,
+         "display":"123-45678"
+      },
+      {
+         "value":"123-45679",
+         "type":"work",
+         "display":"123-45679"
+      }
+   ]
+}
+`
+				assert.JSONEq(t, expect, string(raw))
+			},
+		},
+		{
+			name: "delta attributes with include",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				_, err := r.RootProperty().Replace(s.resourceData)
+				assert.Nil(t, err)
+				return r
+			},
+			options: []Options{
+				Include("name"),
+				Delta(s.deltaRef()),
+			},
+			expect: func(t *testing.T, raw []byte, err error) {
+				assert.Nil(t, err)
+				expect := `
+{
+   "schemas":[
+      "urn:ietf:params:scim:schemas:core:2.0:User"
+   ],
+   "id":"3cc032f5-2361-417f-9e2f-bc80adddf4a3",
+   "name":{
+      "givenName":"Weinan"
+   }
+}
+`
+				assert.JSONEq(t, expect, string(raw))
+			},
+		},
+		{
+			name: "empty string is serialized while unset is omitted",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Dot("id").Replace("foo").HasError())
+				assert.False(t, r.Navigator().Dot("nickName").Replace("").HasError())
+				assert.False(t, r.Navigator().Dot("name").Dot("givenName").Replace("").HasError())
+				assert.False(t, r.Navigator().Dot("x509Certificates").Add(map[string]interface{}{
+					"value": "",
+				}).HasError())
+				return r
+			},
+			options: []Options{},
+			expect: func(t *testing.T, raw []byte, err error) {
+				assert.Nil(t, err)
+				expect := `
+{
+   "schemas":[],
+   "id":"foo",
+   "nickName":"",
+   "name":{
+      "givenName":""
+   },
+   "x509Certificates":[
+      {
+         "value":""
+      }
+   ]
+}
 `
 				assert.JSONEq(t, expect, string(raw))
 			},
@@ -295,3 +404,403 @@ func (s *JsonSerializeTestSuite) SetupSuite() {
 		},
 	}
 }
+
+// deltaRef returns the reference state of the test resource before it was changed to its current state: a different
+// name.givenName, an additional nickName and no phoneNumbers.
+func (s *JsonSerializeTestSuite) deltaRef() *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	_, err := r.RootProperty().Replace(s.resourceData)
+	require.Nil(s.T(), err)
+	require.False(s.T(), r.Navigator().Dot("name").Dot("givenName").Replace("David").HasError())
+	require.False(s.T(), r.Navigator().Dot("nickName").Replace("imulab").HasError())
+	require.False(s.T(), r.Navigator().Dot("phoneNumbers").Delete().HasError())
+	return r
+}
+
+func (s *JsonSerializeTestSuite) TestRoundTripEmptyValues() {
+	const payload = `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","nickName":"","profileUrl":"","x509Certificates":[{"value":""}]}`
+
+	r := prop.NewResource(s.resourceType)
+	require.Nil(s.T(), Deserialize([]byte(payload), r))
+	assert.False(s.T(), r.Navigator().Dot("nickName").Current().IsUnassigned())
+	assert.True(s.T(), r.Navigator().Dot("displayName").Current().IsUnassigned())
+
+	raw, err := Serialize(r)
+	assert.Nil(s.T(), err)
+	assert.JSONEq(s.T(), payload, string(raw))
+
+	roundTrip := prop.NewResource(s.resourceType)
+	require.Nil(s.T(), Deserialize(raw, roundTrip))
+	assert.Equal(s.T(), r.Hash(), roundTrip.Hash())
+}
+
+func (s *JsonSerializeTestSuite) TestRoundTripElementOrder() {
+	const payload = `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","roles":[{"value":"e"},{"value":"a"},{"value":"d"},{"value":"b"},{"value":"c"}]}`
+
+	r := prop.NewResource(s.resourceType)
+	require.Nil(s.T(), Deserialize([]byte(payload), r))
+
+	raw, err := Serialize(r)
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), payload, string(raw))
+
+	// roles is annotated with @Ordered, hence a reordering is a change.
+	reordered := prop.NewResource(s.resourceType)
+	require.Nil(s.T(), Deserialize([]byte(`{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","roles":[{"value":"a"},{"value":"e"},{"value":"d"},{"value":"b"},{"value":"c"}]}`), reordered))
+	assert.NotEqual(s.T(), r.Hash(), reordered.Hash())
+}
+
+func (s *JsonSerializeTestSuite) TestRoundTripFieldName() {
+	const payload = `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","groups":[{"value":"bar","ref":"https://identity.imulab.io/Groups/bar"}]}`
+	options := []Options{FieldName("$ref", "ref")}
+
+	r := prop.NewResource(s.resourceType)
+	require.Nil(s.T(), Deserialize([]byte(payload), r, options...))
+	assert.Equal(s.T(), "https://identity.imulab.io/Groups/bar", r.Navigator().Dot("groups").At(0).Dot("$ref").Current().Raw())
+
+	raw, err := Serialize(r, options...)
+	assert.Nil(s.T(), err)
+	assert.JSONEq(s.T(), payload, string(raw))
+
+	raw, err = Serialize(r, append(options, Include("groups.$ref"))...)
+	assert.Nil(s.T(), err)
+	assert.JSONEq(s.T(), `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","groups":[{"ref":"https://identity.imulab.io/Groups/bar"}]}`, string(raw))
+
+	raw, err = Serialize(r)
+	assert.Nil(s.T(), err)
+	assert.JSONEq(s.T(), `{"schemas":["urn:ietf:params:scim:schemas:core:2.0:User"],"id":"foo","groups":[{"value":"bar","$ref":"https://identity.imulab.io/Groups/bar"}]}`, string(raw))
+}
+
+func (s *JsonSerializeTestSuite) TestEmptyContainers() {
+	resourceFunc := func(t *testing.T) *prop.Resource {
+		raw, err := json.Marshal(s.resourceData)
+		require.Nil(t, err)
+		r := prop.NewResource(s.resourceType)
+		require.Nil(t, Deserialize(raw, r))
+		for _, subAttribute := range []string{"formatted", "familyName", "givenName", "honorificPrefix"} {
+			require.False(t, r.Navigator().Dot("name").Dot(subAttribute).Delete().HasError())
+		}
+		require.False(t, r.Navigator().Dot("phoneNumbers").Delete().HasError())
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		options []Options
+		expect  func(t *testing.T, data map[string]interface{})
+	}{
+		{
+			name: "emptied containers are omitted by default",
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.NotContains(t, data, "name")
+				assert.NotContains(t, data, "phoneNumbers")
+				assert.NotContains(t, data, "addresses")
+				assert.Contains(t, data, "emails")
+			},
+		},
+		{
+			name:    "emptied containers are preserved",
+			options: []Options{PreserveEmpty()},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{}, data["name"])
+				assert.Equal(t, []interface{}{}, data["phoneNumbers"])
+				assert.NotContains(t, data, "addresses")
+				assert.Contains(t, data, "emails")
+			},
+		},
+		{
+			name:    "emptied containers are preserved when included",
+			options: []Options{PreserveEmpty(), Include("name")},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{}, data["name"])
+				assert.NotContains(t, data, "phoneNumbers")
+			},
+		},
+		{
+			name:    "emptied containers are omitted when included",
+			options: []Options{Include("name", "phoneNumbers")},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.NotContains(t, data, "name")
+				assert.NotContains(t, data, "phoneNumbers")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			raw, err := Serialize(resourceFunc(t), test.options...)
+			require.Nil(t, err)
+			var data map[string]interface{}
+			require.Nil(t, json.Unmarshal(raw, &data))
+			test.expect(t, data)
+		})
+	}
+}
+
+func (s *JsonSerializeTestSuite) TestExcludeSubAttributes() {
+	tests := []struct {
+		name    string
+		exclude []string
+		expect  func(t *testing.T, data map[string]interface{})
+	}{
+		{
+			name:    "nested sub attribute",
+			exclude: []string{"name.familyName"},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.Equal(t, map[string]interface{}{
+					"formatted":       "Mr. Weinan Qiu",
+					"givenName":       "Weinan",
+					"honorificPrefix": "Mr.",
+				}, data["name"])
+				assert.Equal(t, "imulab", data["userName"])
+			},
+		},
+		{
+			name:    "nested sub attribute prefixed with schema id",
+			exclude: []string{"urn:ietf:params:scim:schemas:core:2.0:User:NAME.FamilyName"},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.NotContains(t, data["name"], "familyName")
+				assert.Contains(t, data["name"], "givenName")
+			},
+		},
+		{
+			name:    "sub attribute of all elements of multiValued attribute",
+			exclude: []string{"emails.display"},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				emails := data["emails"].([]interface{})
+				assert.Len(t, emails, 2)
+				for _, email := range emails {
+					assert.NotContains(t, email, "display")
+					assert.Contains(t, email, "value")
+				}
+				for _, phoneNumber := range data["phoneNumbers"].([]interface{}) {
+					assert.Contains(t, phoneNumber, "display")
+				}
+			},
+		},
+		{
+			name:    "container without any remaining sub attribute is omitted",
+			exclude: []string{"name.formatted", "name.familyName", "name.givenName", "name.honorificPrefix", "emails.value", "emails.type", "emails.primary", "emails.display"},
+			expect: func(t *testing.T, data map[string]interface{}) {
+				assert.NotContains(t, data, "name")
+				assert.NotContains(t, data, "emails")
+				assert.Contains(t, data, "phoneNumbers")
+			},
+		},
+	}
+
+	r := prop.NewResource(s.resourceType)
+	_, err := r.RootProperty().Replace(s.resourceData)
+	require.Nil(s.T(), err)
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			raw, err := Serialize(r, Exclude(test.exclude...))
+			require.Nil(t, err)
+
+			var data map[string]interface{}
+			require.Nil(t, json.Unmarshal(raw, &data))
+			test.expect(t, data)
+		})
+	}
+}
+
+func (s *JsonSerializeTestSuite) TestProjectExtensionSubAttributes() {
+	raw, err := ioutil.ReadFile("../../../public/schemas/user_enterprise_schema.json")
+	require.Nil(s.T(), err)
+	schema := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal(raw, schema))
+	spec.Schemas().Register(schema)
+
+	resourceType := new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "User",
+  "name": "User",
+  "endpoint": "/Users",
+  "schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+  "schemaExtensions": [
+    {
+      "schema": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User",
+      "required": false
+    }
+  ]
+}
+`), resourceType))
+
+	r := prop.NewResource(resourceType)
+	_, err = r.RootProperty().Replace(map[string]interface{}{
+		"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"},
+		"id":       "foo",
+		"userName": "foo",
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": map[string]interface{}{
+			"employeeNumber": "11250",
+			"manager": map[string]interface{}{
+				"value":       "bar",
+				"displayName": "Bar",
+			},
+		},
+	})
+	require.Nil(s.T(), err)
+
+	const urn = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	for _, test := range []struct {
+		option Options
+		expect string
+	}{
+		{
+			option: Exclude(urn + ":manager.displayName"),
+			expect: `{"employeeNumber":"11250","manager":{"value":"bar"}}`,
+		},
+		{
+			option: Exclude(urn+":employeeNumber", urn+":manager"),
+			expect: ``,
+		},
+		{
+			option: Include(urn + ":manager.value"),
+			expect: `{"manager":{"value":"bar"}}`,
+		},
+		{
+			option: Include(urn),
+			expect: `{"employeeNumber":"11250","manager":{"value":"bar","displayName":"Bar"}}`,
+		},
+	} {
+		raw, err := Serialize(r, test.option)
+		require.Nil(s.T(), err)
+
+		var data map[string]json.RawMessage
+		require.Nil(s.T(), json.Unmarshal(raw, &data))
+		if len(test.expect) == 0 {
+			assert.NotContains(s.T(), data, urn)
+		} else {
+			assert.JSONEq(s.T(), test.expect, string(data[urn]))
+		}
+	}
+}
+
+func (s *JsonSerializeTestSuite) TestWriteOnlySubAttributes() {
+	schema := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "urn:test:WriteOnly",
+  "name": "WriteOnly",
+  "attributes": [
+    {
+      "id": "urn:test:WriteOnly:credential",
+      "name": "credential",
+      "type": "complex",
+      "_path": "credential",
+      "_index": 100,
+      "subAttributes": [
+        {
+          "id": "urn:test:WriteOnly:credential.username",
+          "name": "username",
+          "type": "string",
+          "_path": "credential.username",
+          "_index": 0
+        },
+        {
+          "id": "urn:test:WriteOnly:credential.secret",
+          "name": "secret",
+          "type": "string",
+          "mutability": "writeOnly",
+          "returned": "default",
+          "_path": "credential.secret",
+          "_index": 1
+        }
+      ]
+    },
+    {
+      "id": "urn:test:WriteOnly:tokens",
+      "name": "tokens",
+      "type": "complex",
+      "multiValued": true,
+      "_path": "tokens",
+      "_index": 101,
+      "subAttributes": [
+        {
+          "id": "urn:test:WriteOnly:tokens.type",
+          "name": "type",
+          "type": "string",
+          "_path": "tokens.type",
+          "_index": 0
+        },
+        {
+          "id": "urn:test:WriteOnly:tokens.value",
+          "name": "value",
+          "type": "string",
+          "mutability": "writeOnly",
+          "returned": "always",
+          "_path": "tokens.value",
+          "_index": 1
+        }
+      ]
+    }
+  ]
+}
+`), schema))
+	spec.Schemas().Register(schema)
+
+	resourceType := new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(`{"id": "WriteOnly", "name": "WriteOnly", "schema": "urn:test:WriteOnly"}`), resourceType))
+
+	r := prop.NewResource(resourceType)
+	_, err := r.RootProperty().Replace(map[string]interface{}{
+		"schemas": []interface{}{"urn:test:WriteOnly"},
+		"id":      "foo",
+		"credential": map[string]interface{}{
+			"username": "foo",
+			"secret":   "s3cret",
+		},
+		"tokens": []interface{}{
+			map[string]interface{}{"type": "api", "value": "t0ken"},
+		},
+	})
+	require.Nil(s.T(), err)
+
+	const expect = `{"schemas":["urn:test:WriteOnly"],"id":"foo","credential":{"username":"foo"},"tokens":[{"type":"api"}]}`
+	for _, options := range [][]Options{
+		nil,
+		{Include("credential.secret", "tokens.value")},
+		{Include("credential", "tokens")},
+		{Exclude("credential.username")},
+	} {
+		raw, err := Serialize(r, options...)
+		assert.Nil(s.T(), err)
+		assert.NotContains(s.T(), string(raw), "secret")
+		assert.NotContains(s.T(), string(raw), "t0ken")
+		if options == nil {
+			assert.JSONEq(s.T(), expect, string(raw))
+		}
+	}
+}
+
+func TestSerializeDecimal(t *testing.T) {
+	tests := []struct {
+		value  float64
+		expect string
+	}{
+		{value: 0, expect: "0.0"},
+		{value: 5, expect: "5.0"},
+		{value: -5, expect: "-5.0"},
+		{value: 123.123, expect: "123.123"},
+		{value: 1000000.5, expect: "1000000.5"},
+		{value: 0.0000001, expect: "0.0000001"},
+		{value: -0.000000000123, expect: "-0.000000000123"},
+		{value: 1e21, expect: "1000000000000000000000.0"},
+		{value: 1.0000000000000002, expect: "1.0000000000000002"},
+		{value: 9007199254740993, expect: "9007199254740992.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expect, func(t *testing.T) {
+			s := serializer{}
+			s.appendFloat(test.value)
+			assert.Equal(t, test.expect, s.String())
+
+			var parsed float64
+			require.Nil(t, json.Unmarshal(s.Bytes(), &parsed))
+			assert.Equal(t, test.value, parsed)
+		})
+	}
+}
@@ -55,7 +55,7 @@ func (p *binaryProperty) Raw() interface{} {
 }
 
 func (p *binaryProperty) IsUnassigned() bool {
-	return len(p.value) == 0
+	return p.value == nil
 }
 
 func (p *binaryProperty) Dirty() bool {
@@ -67,6 +67,10 @@ func (p *binaryProperty) Hash() uint64 {
 }
 
 func (p *binaryProperty) computeHash() {
+	if p.value == nil {
+		p.hash = 0
+		return
+	}
 	h := fnv.New64a()
 	_, err := h.Write(p.value)
 	if err != nil {
@@ -88,12 +92,15 @@ func (p *binaryProperty) Matches(another Property) bool {
 func (p *binaryProperty) Clone() Property {
 	c := binaryProperty{
 		attr:        p.attr,
-		value:       make([]byte, len(p.value), len(p.value)),
+		value:       nil,
 		hash:        p.hash,
 		dirty:       p.dirty,
 		subscribers: p.subscribers,
 	}
-	copy(c.value, p.value)
+	if p.value != nil {
+		c.value = make([]byte, len(p.value), len(p.value))
+		copy(c.value, p.value)
+	}
 	return &c
 }
 
@@ -120,9 +127,13 @@ func (p *binaryProperty) Replace(value interface{}) (*Event, error) {
 	}
 
 	p.dirty = true
-	if p.byteArrayEquals(p.value, b64) {
+	if p.value != nil && p.byteArrayEquals(p.value, b64) {
 		return nil, nil
 	}
+	if b64 == nil {
+		// an explicitly assigned empty value is different from an unassigned value
+		b64 = []byte{}
+	}
 
 	ev := Event{typ: EventAssigned, source: p, pre: p.Raw()}
 	p.value = b64
@@ -132,7 +143,7 @@ func (p *binaryProperty) Replace(value interface{}) (*Event, error) {
 
 func (p *binaryProperty) Delete() (*Event, error) {
 	p.dirty = true
-	if len(p.value) == 0 {
+	if p.value == nil {
 		return nil, nil
 	}
 
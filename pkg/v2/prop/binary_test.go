@@ -164,6 +164,17 @@ func (s *BinaryPropertyTestSuite) TestUnassigned() {
 				assert.False(t, unassigned)
 			},
 		},
+		{
+			name: "assigned empty value returns false",
+			attr: s.standardAttr,
+			getValue: func() *string {
+				b := ""
+				return &b
+			},
+			expect: func(t *testing.T, unassigned bool) {
+				assert.False(t, unassigned)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -214,9 +214,8 @@ func (p *complexProperty) Replace(value interface{}) (*Event, error) {
 }
 
 func (p *complexProperty) Delete() (*Event, error) {
-	if p.IsUnassigned() {
-		return nil, nil
-	}
+	// deleting an unassigned complex property still marks its sub properties dirty
+	wasUnassigned := p.IsUnassigned()
 
 	for _, sp := range p.subProps {
 		if _, err := sp.Delete(); err != nil {
@@ -224,6 +223,9 @@ func (p *complexProperty) Delete() (*Event, error) {
 		}
 	}
 
+	if wasUnassigned {
+		return nil, nil
+	}
 	return EventUnassigned.NewFrom(p, nil), nil
 }
 
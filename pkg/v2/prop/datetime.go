@@ -167,12 +167,24 @@ func (p *dateTimeProperty) mustToISO8601() string {
 	return (*(p.value)).Format(spec.ISO8601)
 }
 
+// Layouts accepted when parsing dateTime values, in the order they are attempted. Parsing is lenient: besides the
+// ISO8601 layout in which the values are rendered, timezone designators and fractional seconds are accepted. Values
+// carrying a timezone are converted to UTC, as the rendered layout does not carry one. Deployments requiring strict
+// xsd:dateTime conformance shall validate the input before parsing (i.e. with the json.StrictDateTime option).
+var dateTimeLayouts = []string{
+	spec.ISO8601,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05",
+}
+
 func (p *dateTimeProperty) fromISO8601(value string) (time.Time, error) {
-	t, err := time.Parse(spec.ISO8601, value)
-	if err != nil {
-		return time.Time{}, fmt.Errorf("%w, value for '%s' does not conform to ISO8601", spec.ErrInvalidValue, p.attr.Path())
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
 	}
-	return t, nil
+	return time.Time{}, fmt.Errorf("%w, value for '%s' does not conform to ISO8601", spec.ErrInvalidValue, p.attr.Path())
 }
 
 func (p *dateTimeProperty) EqualsTo(value interface{}) bool {
@@ -123,6 +123,17 @@ func (s *DateTimePropertyTestSuite) TestRaw() {
 				assert.Equal(t, "2020-01-16T07:30:00", raw)
 			},
 		},
+		{
+			name: "assigned with timezone returns string in UTC",
+			attr: s.standardAttr,
+			getValue: func() *string {
+				d := "2020-01-16T15:30:00.5+08:00"
+				return &d
+			},
+			expect: func(t *testing.T, raw interface{}) {
+				assert.Equal(t, "2020-01-16T07:30:00", raw)
+			},
+		},
 	}
 
 	for _, test := range tests {
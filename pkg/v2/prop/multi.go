@@ -3,6 +3,7 @@ package prop
 import (
 	"encoding/binary"
 	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/annotation"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"hash/fnv"
 )
@@ -76,7 +77,10 @@ func (p *multiValuedProperty) Hash() uint64 {
 		return 0
 	}
 
-	var hashes []uint64
+	var (
+		hashes     []uint64
+		_, ordered = p.attr.Annotation(annotation.Ordered)
+	)
 	_ = p.ForEachChild(func(index int, child Property) error {
 		if child.IsUnassigned() {
 			return nil
@@ -87,8 +91,9 @@ func (p *multiValuedProperty) Hash() uint64 {
 		// the same elements in different orders can be recognized as
 		// the same, as they compute the same hash. We use insertion
 		// sort here as we don't expect a large number of elements.
+		// Attributes annotated with @Ordered keep the element order.
 		hashes = append(hashes, child.Hash())
-		for i := len(hashes) - 1; i > 0; i-- {
+		for i := len(hashes) - 1; i > 0 && !ordered; i-- {
 			if hashes[i-1] > hashes[i] {
 				hashes[i-1], hashes[i] = hashes[i], hashes[i-1]
 			}
@@ -199,12 +204,12 @@ func (p *multiValuedProperty) Replace(value interface{}) (*Event, error) {
 }
 
 func (p *multiValuedProperty) Delete() (*Event, error) {
+	p.dirty = true
 	if p.IsUnassigned() {
 		return nil, nil
 	}
 
 	ev := Event{typ: EventUnassigned, source: p, pre: p.Raw()}
-	p.dirty = true
 	p.elements = make([]Property, 0)
 	return &ev, nil
 }
@@ -25,6 +25,7 @@ type MultiValuedPropertyTestSuite struct {
 	PropertyTestSuite
 	OperatorTestSuite
 	standardAttr *spec.Attribute
+	orderedAttr  *spec.Attribute
 }
 
 func (s *MultiValuedPropertyTestSuite) SetupSuite() {
@@ -36,6 +37,18 @@ func (s *MultiValuedPropertyTestSuite) SetupSuite() {
   "multiValued": true,
   "_path": "schemas",
   "_index": 0
+}`))
+	s.orderedAttr = s.mustAttribute(s.T(), strings.NewReader(`
+{
+  "id": "priorities",
+  "name": "priorities",
+  "type": "string",
+  "multiValued": true,
+  "_path": "priorities",
+  "_index": 1,
+  "_annotations": {
+    "@Ordered": {}
+  }
 }`))
 }
 
@@ -209,6 +222,30 @@ func (s *MultiValuedPropertyTestSuite) TestMatches() {
 				assert.True(t, match)
 			},
 		},
+		{
+			name: "ordered property of same value in different order does not match",
+			getA: func(t *testing.T) Property {
+				return NewMultiOf(s.orderedAttr, []interface{}{"A", "B"})
+			},
+			getB: func(t *testing.T) Property {
+				return NewMultiOf(s.orderedAttr, []interface{}{"B", "A"})
+			},
+			expect: func(t *testing.T, match bool) {
+				assert.False(t, match)
+			},
+		},
+		{
+			name: "ordered property of same value in same order matches",
+			getA: func(t *testing.T) Property {
+				return NewMultiOf(s.orderedAttr, []interface{}{"A", "B"})
+			},
+			getB: func(t *testing.T) Property {
+				return NewMultiOf(s.orderedAttr, []interface{}{"A", "B"})
+			},
+			expect: func(t *testing.T, match bool) {
+				assert.True(t, match)
+			},
+		},
 		{
 			name: "assigned property of same attribute but different value does not match",
 			getA: func(t *testing.T) Property {
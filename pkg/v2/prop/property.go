@@ -49,7 +49,8 @@ type Property interface {
 	// 	For properties carrying singular non-complex attributes, attributes and values are compared.
 	// 	For complex properties, two complex properties match if and only if all their identity sub properties match.
 	// 	For multiValued properties, match only happens when they have the same number of element properties
-	//	and the element properties all match correspondingly.
+	//	and the element properties all match correspondingly. Element order is not considered, unless the
+	//	attribute is annotated with @Ordered.
 	// Two unassigned properties with the same attribute matches each other.
 	Matches(another Property) bool
 	// Clone return an exact clone of the property. The cloned property may share the same instance of attribute and
@@ -58,7 +59,8 @@ type Property interface {
 	// Add a value to the property and emit an event describing the change.
 	// If the value already exists, no change will be made and the emitted event is nil. Otherwise, the value will
 	// be added to the underlying data structure and mark the value dirty. For simple properties, calling this
-	// method equates to calling Replace.
+	// method equates to calling Replace. For multiValued properties, new elements are appended after the existing
+	// elements, in the order they were given.
 	Add(value interface{}) (*Event, error)
 	// Replace value of this property and emit an event describing the change.
 	// If the value equals to the current value, no change will be made and the emitted event is nil. Otherwise,
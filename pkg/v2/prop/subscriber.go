@@ -147,8 +147,9 @@ func (s *ExclusivePrimarySubscriber) Notify(publisher Property, events *Events)
 		dev, err := nav.Current().Delete()
 		if err != nil {
 			return err
+		} else if dev != nil {
+			events.Append(dev)
 		}
-		events.Append(dev)
 
 		return nil
 	})
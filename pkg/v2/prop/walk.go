@@ -0,0 +1,80 @@
+package prop
+
+import (
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/annotation"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strconv"
+)
+
+// SkipChildren is used as a return value from WalkVisitor.EnterContainer to indicate that the children of the container
+// shall not be walked. It is not returned as an error by Walk, and LeaveContainer is not invoked for the container.
+var SkipChildren = errors.New("skip children")
+
+// WalkVisitor defines behaviour for implementations to react to a Walk over the property tree of a resource. Unlike
+// Visitor, every callback receives the attribute of the property and its path within the resource, so implementations
+// do not have to keep track of the traversal state themselves.
+//
+// The path is formed by the attribute names from the top level down to the property, delimited by period ("."). Elements
+// of a multiValued property are denoted by their index in brackets, and attributes of a schema extension are prefixed by
+// the extension's schema URN and a colon, i.e. "emails[0].value", "name.givenName",
+// "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value".
+//
+// Any non-nil error, other than SkipChildren from EnterContainer, aborts the rest of the walk and is returned by Walk.
+type WalkVisitor interface {
+	// EnterContainer is invoked on a complex or multiValued property, before any of its children are walked.
+	EnterContainer(attribute *spec.Attribute, path string, container Property) error
+	// LeaveContainer is invoked on a complex or multiValued property, after all of its children are walked.
+	LeaveContainer(attribute *spec.Attribute, path string, container Property) error
+	// Value is invoked on a singular simple property, including the simple elements of a multiValued property.
+	Value(attribute *spec.Attribute, path string, property Property) error
+}
+
+// Walk walks the property tree of the resource in depth-first order, invoking the callbacks of the visitor. The walk
+// makes the following guarantees on the order of invocations:
+//
+// The top level properties, and the sub properties of a complex property, are walked in the order their attributes are
+// defined in the schema, with the main schema attributes preceding those of the schema extensions. Elements of a
+// multiValued property are walked in the order they are stored. EnterContainer on a container is invoked before any
+// callback on its children, and LeaveContainer after the callbacks on all of its children have returned.
+//
+// Unassigned properties are walked as well, so that implementations can decide how to treat them, typically by checking
+// Property.IsUnassigned. The root property of the resource itself is not walked.
+func Walk(resource *Resource, visitor WalkVisitor) error {
+	return resource.RootProperty().ForEachChild(func(_ int, child Property) error {
+		return walk(child, child.Attribute().Name(), visitor)
+	})
+}
+
+func walk(property Property, path string, visitor WalkVisitor) error {
+	attr := property.Attribute()
+	if !attr.MultiValued() && attr.Type() != spec.TypeComplex {
+		return visitor.Value(attr, path, property)
+	}
+
+	if err := visitor.EnterContainer(attr, path, property); err != nil {
+		if err == SkipChildren {
+			return nil
+		}
+		return err
+	}
+
+	if err := property.ForEachChild(func(index int, child Property) error {
+		return walk(child, childPath(property, path, index, child), visitor)
+	}); err != nil {
+		return err
+	}
+
+	return visitor.LeaveContainer(attr, path, property)
+}
+
+// childPath returns the path of the child property, given the path of its container.
+func childPath(container Property, path string, index int, child Property) string {
+	if container.Attribute().MultiValued() {
+		return path + "[" + strconv.Itoa(index) + "]"
+	}
+	if _, ok := container.Attribute().Annotation(annotation.SchemaExtensionRoot); ok {
+		return path + ":" + child.Attribute().Name()
+	}
+	return path + "." + child.Attribute().Name()
+}
@@ -0,0 +1,255 @@
+package prop
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	var (
+		coreSchema      = new(spec.Schema)
+		mainSchema      = new(spec.Schema)
+		extensionSchema = new(spec.Schema)
+		resourceType    = new(spec.ResourceType)
+	)
+	{
+		require.Nil(t, json.Unmarshal([]byte(`
+{
+  "id": "core",
+  "name": "core",
+  "attributes": [
+    {
+      "id": "schemas",
+      "name": "schemas",
+      "type": "string",
+      "multiValued": true,
+      "_path": "schemas",
+      "_annotations": {
+        "@AutoCompact": {}
+      }
+    }
+  ]
+}
+`), coreSchema))
+		spec.Schemas().Register(coreSchema)
+
+		require.Nil(t, json.Unmarshal([]byte(`
+{
+  "id": "walk",
+  "name": "walk",
+  "attributes": [
+    {
+      "id": "userName",
+      "name": "userName",
+      "type": "string",
+      "_path": "userName",
+      "_index": 0
+    },
+    {
+      "id": "name",
+      "name": "name",
+      "type": "complex",
+      "_path": "name",
+      "_index": 1,
+      "subAttributes": [
+        {
+          "id": "name.givenName",
+          "name": "givenName",
+          "type": "string",
+          "_path": "name.givenName",
+          "_index": 0
+        },
+        {
+          "id": "name.familyName",
+          "name": "familyName",
+          "type": "string",
+          "_path": "name.familyName",
+          "_index": 1
+        }
+      ]
+    },
+    {
+      "id": "emails",
+      "name": "emails",
+      "type": "complex",
+      "multiValued": true,
+      "_path": "emails",
+      "_index": 2,
+      "subAttributes": [
+        {
+          "id": "emails.value",
+          "name": "value",
+          "type": "string",
+          "_path": "emails.value",
+          "_index": 0
+        }
+      ]
+    }
+  ]
+}
+`), mainSchema))
+		spec.Schemas().Register(mainSchema)
+
+		require.Nil(t, json.Unmarshal([]byte(`
+{
+  "id": "urn:walk:extension",
+  "name": "extension",
+  "attributes": [
+    {
+      "id": "urn:walk:extension:text",
+      "name": "text",
+      "type": "string",
+      "_path": "text",
+      "_index": 0
+    }
+  ]
+}
+`), extensionSchema))
+		spec.Schemas().Register(extensionSchema)
+
+		require.Nil(t, json.Unmarshal([]byte(`
+{
+  "id": "Walk",
+  "name": "Walk",
+  "schema": "walk",
+  "schemaExtensions": [
+    {
+      "schema": "urn:walk:extension",
+      "required": false
+    }
+  ]
+}
+`), resourceType))
+	}
+
+	resourceFunc := func(t *testing.T) *Resource {
+		r := NewResource(resourceType)
+		require.False(t, r.Navigator().Replace(map[string]interface{}{
+			"schemas":  []interface{}{"walk", "urn:walk:extension"},
+			"userName": "foo",
+			"name": map[string]interface{}{
+				"givenName": "David",
+			},
+			"emails": []interface{}{
+				map[string]interface{}{"value": "foo@example.com"},
+				map[string]interface{}{"value": "bar@example.com"},
+			},
+			"urn:walk:extension": map[string]interface{}{
+				"text": "hello",
+			},
+		}).HasError())
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		visitor   *recordingWalkVisitor
+		expect    []string
+		expectErr error
+	}{
+		{
+			name:    "walk all properties",
+			visitor: &recordingWalkVisitor{},
+			expect: []string{
+				"enter schemas",
+				"value schemas[0] walk",
+				"value schemas[1] urn:walk:extension",
+				"leave schemas",
+				"value userName foo",
+				"enter name",
+				"value name.givenName David",
+				"value name.familyName <nil>",
+				"leave name",
+				"enter emails",
+				"enter emails[0]",
+				"value emails[0].value foo@example.com",
+				"leave emails[0]",
+				"enter emails[1]",
+				"value emails[1].value bar@example.com",
+				"leave emails[1]",
+				"leave emails",
+				"enter urn:walk:extension",
+				"value urn:walk:extension:text hello",
+				"leave urn:walk:extension",
+			},
+		},
+		{
+			name:    "skip children of container",
+			visitor: &recordingWalkVisitor{skip: "emails"},
+			expect: []string{
+				"enter schemas",
+				"value schemas[0] walk",
+				"value schemas[1] urn:walk:extension",
+				"leave schemas",
+				"value userName foo",
+				"enter name",
+				"value name.givenName David",
+				"value name.familyName <nil>",
+				"leave name",
+				"enter emails",
+				"enter urn:walk:extension",
+				"value urn:walk:extension:text hello",
+				"leave urn:walk:extension",
+			},
+		},
+		{
+			name:    "error aborts walk",
+			visitor: &recordingWalkVisitor{fail: "name.givenName"},
+			expect: []string{
+				"enter schemas",
+				"value schemas[0] walk",
+				"value schemas[1] urn:walk:extension",
+				"leave schemas",
+				"value userName foo",
+				"enter name",
+				"value name.givenName David",
+			},
+			expectErr: errWalkTest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Walk(resourceFunc(t), test.visitor)
+			assert.Equal(t, test.expectErr, err)
+			assert.Equal(t, test.expect, test.visitor.events)
+		})
+	}
+}
+
+var errWalkTest = errors.New("walk test")
+
+type recordingWalkVisitor struct {
+	skip   string
+	fail   string
+	events []string
+}
+
+func (v *recordingWalkVisitor) EnterContainer(_ *spec.Attribute, path string, _ Property) error {
+	v.events = append(v.events, "enter "+path)
+	if path == v.skip {
+		return SkipChildren
+	}
+	return nil
+}
+
+func (v *recordingWalkVisitor) LeaveContainer(_ *spec.Attribute, path string, _ Property) error {
+	v.events = append(v.events, "leave "+path)
+	return nil
+}
+
+func (v *recordingWalkVisitor) Value(attribute *spec.Attribute, path string, property Property) error {
+	raw := "<nil>"
+	if !property.IsUnassigned() {
+		raw = property.Raw().(string)
+	}
+	v.events = append(v.events, "value "+path+" "+raw)
+	if path == v.fail || attribute.Path() == v.fail {
+		return errWalkTest
+	}
+	return nil
+}
@@ -13,11 +13,42 @@ import (
 )
 
 // Create returns a create resource service.
-func CreateService(resourceType *spec.ResourceType, database db.DB, filters []filter.ByResource) Create {
-	return &createService{
+//
+// The id of the new resource is always assigned by the service using the IdGenerator (UUIDGenerator by default, see
+// WithIdGenerator), before any filter is run, so that it is in place for the uniqueness check and the meta generation.
+// An id supplied by the client is ignored by default, or rejected with a spec.ErrMutability error when RejectClientId
+// is set. The assigned id is kept throughout the filters: should a filter alter it (i.e. the @ReadOnly filter resetting
+// it and the @UUID filter generating a new one), it is restored before the next filter runs. Other attributes,
+// including externalId, are left to the filters.
+//
+// When the request carries a ResourceID, the resource is created with that id instead (i.e. PUT with If-None-Match: *
+// to create the resource at a known location); an id in the payload equal to it is then not considered client supplied.
+// The creation relies on db.DB to fail with spec.ErrConflict when a resource with the id already exists.
+func CreateService(resourceType *spec.ResourceType, database db.DB, filters []filter.ByResource, options ...CreateOption) Create {
+	s := &createService{
 		resourceType: resourceType,
 		filters:      filters,
 		database:     database,
+		idGenerator:  UUIDGenerator(),
+	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// WithIdGenerator returns a CreateOption to generate the id of the new resources with the generator.
+func WithIdGenerator(generator IdGenerator) CreateOption {
+	return func(s *createService) {
+		s.idGenerator = generator
+	}
+}
+
+// RejectClientId returns a CreateOption to reject the creation request when the client supplies an id, instead of
+// ignoring it.
+func RejectClientId() CreateOption {
+	return func(s *createService) {
+		s.rejectClientId = true
 	}
 }
 
@@ -26,9 +57,12 @@ type (
 	Create interface {
 		Do(ctx context.Context, req *CreateRequest) (resp *CreateResponse, err error)
 	}
+	// Option to customize the create resource service
+	CreateOption func(s *createService)
 	// Create resource request
 	CreateRequest struct {
 		PayloadSource io.Reader // reader source to read resource payload from
+		ResourceID    string    // optional id to create the resource with, instead of a generated id
 	}
 	// Create resource response
 	CreateResponse struct {
@@ -37,9 +71,11 @@ type (
 )
 
 type createService struct {
-	resourceType *spec.ResourceType
-	filters      []filter.ByResource
-	database     db.DB
+	resourceType   *spec.ResourceType
+	filters        []filter.ByResource
+	database       db.DB
+	idGenerator    IdGenerator
+	rejectClientId bool
 }
 
 func (s *createService) Do(ctx context.Context, req *CreateRequest) (resp *CreateResponse, err error) {
@@ -48,10 +84,18 @@ func (s *createService) Do(ctx context.Context, req *CreateRequest) (resp *Creat
 		return
 	}
 
+	id, err := s.assignId(resource, req.ResourceID)
+	if err != nil {
+		return
+	}
+
 	for _, f := range s.filters {
 		if err = f.Filter(ctx, resource); err != nil {
 			return
 		}
+		if err = s.restoreId(resource, id); err != nil {
+			return
+		}
 	}
 
 	if err = s.database.Insert(ctx, resource); err != nil {
@@ -62,6 +106,42 @@ func (s *createService) Do(ctx context.Context, req *CreateRequest) (resp *Creat
 	return
 }
 
+// assignId replaces any client supplied id with the requested or generated one, or rejects the client supplied id. It
+// returns the assigned id.
+func (s *createService) assignId(resource *prop.Resource, requested string) (string, error) {
+	nav := resource.Navigator().Dot("id")
+	if nav.HasError() {
+		return "", nav.Error()
+	}
+
+	if !nav.Current().IsUnassigned() && s.rejectClientId {
+		if len(requested) == 0 || nav.Current().Raw() != requested {
+			return "", fmt.Errorf("%w: id is assigned by the server and cannot be supplied", spec.ErrMutability)
+		}
+	}
+
+	id := requested
+	if len(id) == 0 {
+		generated, err := s.idGenerator.Generate(resource.ResourceType())
+		if err != nil {
+			return "", err
+		}
+		if len(generated) == 0 {
+			return "", fmt.Errorf("%w: generated id is empty", spec.ErrInternal)
+		}
+		id = generated
+	}
+	return id, nav.Replace(id).Error()
+}
+
+// restoreId replaces the id of the resource with the assigned id, if a filter has altered it.
+func (s *createService) restoreId(resource *prop.Resource, id string) error {
+	if resource.IdOrEmpty() == id {
+		return nil
+	}
+	return resource.Navigator().Dot("id").Replace(id).Error()
+}
+
 func (s *createService) parseResource(req *CreateRequest) (*prop.Resource, error) {
 	if req == nil || req.PayloadSource == nil {
 		return nil, fmt.Errorf("%w: no payload for create service", spec.ErrInternal)
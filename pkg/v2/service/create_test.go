@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/annotation"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/service/filter"
@@ -28,18 +30,38 @@ type CreateServiceTestSuite struct {
 }
 
 func (s *CreateServiceTestSuite) TestDo() {
-	defaultSetup := func(t *testing.T) Create {
-		memoryDB := db.Memory()
-		return CreateService(s.resourceType, memoryDB, []filter.ByResource{
-			filter.ByPropertyToByResource(
-				filter.ReadOnlyFilter(),
-				filter.UUIDFilter(),
-				filter.BCryptFilter(),
-			),
-			filter.MetaFilter(),
-			filter.ByPropertyToByResource(filter.ValidationFilter(memoryDB)),
-		})
+	setupWith := func(options ...CreateOption) func(t *testing.T) Create {
+		return func(t *testing.T) Create {
+			memoryDB := db.Memory()
+			return CreateService(s.resourceType, memoryDB, []filter.ByResource{
+				filter.ByPropertyToByResource(
+					filter.ReadOnlyFilter(),
+					filter.UUIDFilter(),
+					filter.BCryptFilter(),
+				),
+				filter.MetaFilter(),
+				filter.ByPropertyToByResource(filter.ValidationFilter(memoryDB)),
+			}, options...)
+		}
 	}
+	defaultSetup := setupWith()
+	// database of the test case that needs to inspect the stored resource
+	var storage db.DB
+	clientPayload := `
+{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User"
+  ],
+  "id": "foobar",
+  "externalId": "ext-foobar",
+  "userName": "foo",
+  "emails": [
+    {
+      "value": "foo@bar.com"
+    }
+  ]
+}
+`
 
 	tests := []struct {
 		name       string
@@ -156,6 +178,170 @@ func (s *CreateServiceTestSuite) TestDo() {
 				assert.NotEqual(t, "foobar", resp.Resource.Navigator().Dot("id").Current().Raw())
 			},
 		},
+		{
+			name: "id is generated by the injected generator",
+			setup: func(t *testing.T) Create {
+				memoryDB := db.Memory()
+				n := 0
+				generator := IdGeneratorFunc(func(resourceType *spec.ResourceType) (string, error) {
+					n++
+					return fmt.Sprintf("%s-%04d", resourceType.Name(), n), nil
+				})
+				storage = memoryDB
+				return CreateService(s.resourceType, memoryDB, []filter.ByResource{
+					filter.ByPropertyToByResource(
+						filter.ReadOnlyFilter(),
+						filter.UUIDFilter(),
+					),
+					filter.MetaFilter(),
+					filter.ByPropertyToByResource(filter.ValidationFilter(memoryDB)),
+				}, WithIdGenerator(generator))
+			},
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload)}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "User-0001", resp.Resource.IdOrEmpty())
+				assert.True(t, strings.HasSuffix(resp.Resource.MetaLocationOrEmpty(), "/Users/User-0001"))
+
+				stored, err := storage.Get(context.Background(), "User-0001", nil)
+				assert.Nil(t, err)
+				assert.Equal(t, resp.Resource.MetaLocationOrEmpty(), stored.MetaLocationOrEmpty())
+			},
+		},
+		{
+			name: "injected id survives the readOnly reset of id",
+			setup: func(t *testing.T) Create {
+				idAttr := prop.NewResource(s.resourceType).Navigator().Dot("id").Current().Attribute()
+				params, ok := idAttr.Annotation(annotation.ReadOnly)
+				require.True(t, ok)
+				require.Equal(t, true, params["reset"])
+
+				storage = db.Memory()
+				return CreateService(s.resourceType, storage, []filter.ByResource{
+					filter.ByPropertyToByResource(
+						filter.ReadOnlyFilter(),
+						filter.UUIDFilter(),
+					),
+					filter.MetaFilter(),
+				}, WithIdGenerator(fixedIdGenerator("generated")))
+			},
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload)}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "generated", resp.Resource.IdOrEmpty())
+				assert.True(t, strings.HasSuffix(resp.Resource.MetaLocationOrEmpty(), "/Users/generated"))
+
+				stored, err := storage.Get(context.Background(), "generated", nil)
+				require.Nil(t, err)
+				assert.Equal(t, "ext-foobar", stored.Navigator().Dot("externalId").Current().Raw())
+			},
+		},
+		{
+			name: "generator error fails the creation",
+			setup: setupWith(WithIdGenerator(IdGeneratorFunc(func(_ *spec.ResourceType) (string, error) {
+				return "", fmt.Errorf("%w: id sequence exhausted", spec.ErrInternal)
+			}))),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload)}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInternal, errors.Unwrap(err))
+			},
+		},
+		{
+			name:  "client supplied id is ignored and externalId is preserved",
+			setup: setupWith(WithIdGenerator(fixedIdGenerator("generated"))),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload)}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "generated", resp.Resource.IdOrEmpty())
+				assert.Equal(t, "ext-foobar", resp.Resource.Navigator().Dot("externalId").Current().Raw())
+				assert.Contains(t, resp.Resource.MetaLocationOrEmpty(), "generated")
+			},
+		},
+		{
+			name:  "client supplied id is rejected",
+			setup: setupWith(RejectClientId()),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload)}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrMutability, errors.Unwrap(err))
+			},
+		},
+		{
+			name:  "id is generated when client supplies none in reject mode",
+			setup: setupWith(RejectClientId(), WithIdGenerator(fixedIdGenerator("generated"))),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(strings.Replace(clientPayload, `"id": "foobar",`, "", 1))}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "generated", resp.Resource.IdOrEmpty())
+				assert.Equal(t, "ext-foobar", resp.Resource.Navigator().Dot("externalId").Current().Raw())
+			},
+		},
+		{
+			name:  "requested id is used instead of client supplied id",
+			setup: setupWith(WithIdGenerator(fixedIdGenerator("generated"))),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload), ResourceID: "requested"}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "requested", resp.Resource.IdOrEmpty())
+				assert.True(t, strings.HasSuffix(resp.Resource.MetaLocationOrEmpty(), "/Users/requested"))
+			},
+		},
+		{
+			name:  "client supplied id equal to requested id is accepted in reject mode",
+			setup: setupWith(RejectClientId()),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload), ResourceID: "foobar"}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "foobar", resp.Resource.IdOrEmpty())
+			},
+		},
+		{
+			name:  "client supplied id different from requested id is rejected in reject mode",
+			setup: setupWith(RejectClientId()),
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload), ResourceID: "requested"}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrMutability, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "requested id of an existing resource is a conflict",
+			setup: func(t *testing.T) Create {
+				service := defaultSetup(t)
+				_, err := service.Do(context.Background(), &CreateRequest{
+					PayloadSource: strings.NewReader(strings.Replace(clientPayload, `"foo"`, `"bar"`, 1)),
+					ResourceID:    "requested",
+				})
+				require.Nil(t, err)
+				return service
+			},
+			getRequest: func() *CreateRequest {
+				return &CreateRequest{PayloadSource: strings.NewReader(clientPayload), ResourceID: "requested"}
+			},
+			expect: func(t *testing.T, resp *CreateResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrConflict, errors.Unwrap(err))
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -167,6 +353,12 @@ func (s *CreateServiceTestSuite) TestDo() {
 	}
 }
 
+func fixedIdGenerator(id string) IdGenerator {
+	return IdGeneratorFunc(func(_ *spec.ResourceType) (string, error) {
+		return id, nil
+	})
+}
+
 func (s *CreateServiceTestSuite) SetupSuite() {
 	for _, each := range []struct {
 		filepath  string
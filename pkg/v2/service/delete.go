@@ -8,7 +8,9 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/spec"
 )
 
-// DeleteService returns a delete resource service
+// DeleteService returns a delete resource service. When ETag is supported, the resource is only deleted if it meets
+// the MatchCriteria of the request (i.e. the If-Match header). The checked resource is handed to the database, which
+// shall only delete it if its version has not changed since, and return spec.ErrConflict otherwise.
 func DeleteService(config *spec.ServiceProviderConfig, database db.DB) Delete {
 	return &deleteService{
 		Database: database,
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+)
+
+// name of the sub attribute whose value is used as the key of an element
+const elementKey = "value"
+
+// DeleteElementService returns a service to delete a single element of a multiValued complex attribute, such as the
+// email in DELETE /Users/{id}/emails/{value}. It is a convenience layer on top of the patch service: the element is
+// removed by a PATCH remove operation with the value path <attribute>[value eq "<key>"], hence all patch filters apply
+// and group memberships are propagated just like any other PATCH.
+//
+// The element key is matched against the "value" sub attribute of the elements, respecting its caseExact setting.
+// Attributes without a "value" sub attribute cannot be addressed this way. When no element matches the key, a
+// spec.ErrNotFound error is returned. When more than one element matches the key (i.e. the same email value with
+// different types), the request is rejected as ambiguous, and the client should send a PATCH instead.
+func DeleteElementService(database db.DB, patch Patch) DeleteElement {
+	return &deleteElementService{
+		database: database,
+		patch:    patch,
+	}
+}
+
+type (
+	// Delete multiValued element service
+	DeleteElement interface {
+		Do(ctx context.Context, req *DeleteElementRequest) (resp *DeleteElementResponse, err error)
+	}
+	// Delete multiValued element request
+	DeleteElementRequest struct {
+		ResourceID    string                             // id of the resource whose element is to be deleted
+		Attribute     string                             // path of the multiValued complex attribute (i.e. emails)
+		Key           string                             // value of the "value" sub attribute of the element to delete
+		MatchCriteria func(resource *prop.Resource) bool // extra criteria the resource has to meet in order to be patched
+	}
+	// Delete multiValued element response
+	DeleteElementResponse struct {
+		Resource *prop.Resource // the resource after the element was deleted
+	}
+)
+
+type deleteElementService struct {
+	database db.DB
+	patch    Patch
+}
+
+func (s *deleteElementService) Do(ctx context.Context, req *DeleteElementRequest) (resp *DeleteElementResponse, err error) {
+	// The key is embedded as a string literal in the value path, which has no means to escape quotes.
+	if len(req.Key) == 0 || strings.ContainsAny(req.Key, `"\`) {
+		err = fmt.Errorf("%w: element key '%s' cannot be addressed", spec.ErrInvalidValue, req.Key)
+		return
+	}
+
+	resource, err := s.database.Get(ctx, req.ResourceID, nil)
+	if err != nil {
+		return
+	}
+
+	n, err := s.countElements(resource, req)
+	if err != nil {
+		return
+	}
+	switch {
+	case n == 0:
+		err = fmt.Errorf("%w: no element of '%s' by '%s'", spec.ErrNotFound, req.Attribute, req.Key)
+		return
+	case n > 1:
+		err = fmt.Errorf("%w: '%s' matches more than one element of '%s'", spec.ErrInvalidPath, req.Key, req.Attribute)
+		return
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		"Operations": []map[string]string{
+			{"op": "remove", "path": fmt.Sprintf(`%s[%s eq "%s"]`, req.Attribute, elementKey, req.Key)},
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	patchResp, err := s.patch.Do(ctx, &PatchRequest{
+		ResourceID:    req.ResourceID,
+		MatchCriteria: req.MatchCriteria,
+		PayloadSource: bytes.NewReader(payload),
+	})
+	if err != nil {
+		return
+	}
+	// the element was removed by another request since it was counted
+	if !patchResp.Patched {
+		err = fmt.Errorf("%w: no element of '%s' by '%s'", spec.ErrNotFound, req.Attribute, req.Key)
+		return
+	}
+
+	resp = &DeleteElementResponse{Resource: patchResp.Resource}
+	return
+}
+
+// countElements returns the number of elements of the attribute whose key equals to the request key.
+func (s *deleteElementService) countElements(resource *prop.Resource, req *DeleteElementRequest) (int, error) {
+	attr, ok := resource.ResourceType().AttributeByPath(req.Attribute)
+	if !ok || !attr.MultiValued() || attr.Type() != spec.TypeComplex || attr.SubAttributeForName(elementKey) == nil {
+		return 0, fmt.Errorf("%w: '%s' is not a multiValued attribute addressable by %s", spec.ErrInvalidPath, req.Attribute, elementKey)
+	}
+
+	head, err := expr.CompilePath(req.Attribute)
+	if err != nil {
+		return 0, err
+	}
+	if strings.EqualFold(head.Token(), resource.ResourceType().Schema().ID()) {
+		head = head.Next()
+	}
+
+	nav := resource.Navigator()
+	for ; head != nil; head = head.Next() {
+		if nav.Dot(head.Token()); nav.HasError() {
+			return 0, nav.Error()
+		}
+	}
+
+	n := 0
+	_ = nav.Current().ForEachChild(func(_ int, child prop.Property) error {
+		key, err := child.ChildAtIndex(elementKey)
+		if err != nil {
+			return nil
+		}
+		if eq, ok := key.(prop.EqCapable); ok && eq.EqualsTo(req.Key) {
+			n++
+		}
+		return nil
+	})
+	return n, nil
+}
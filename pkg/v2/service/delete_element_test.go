@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestDeleteElementService(t *testing.T) {
+	s := new(DeleteElementServiceTestSuite)
+	suite.Run(t, s)
+}
+
+type DeleteElementServiceTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+	config       *spec.ServiceProviderConfig
+}
+
+func (s *DeleteElementServiceTestSuite) TestDo() {
+	tests := []struct {
+		name    string
+		request *DeleteElementRequest
+		expect  func(t *testing.T, resp *DeleteElementResponse, err error)
+	}{
+		{
+			name: "delete element by value",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "emails",
+				Key:        "foo@home.com",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.Nil(t, err)
+				emails := resp.Resource.Navigator().Dot("emails").Current()
+				assert.Equal(t, 2, emails.CountChildren())
+				assert.Equal(t, "foo@work.com", resp.Resource.Navigator().Dot("emails").At(0).Dot("value").Current().Raw())
+			},
+		},
+		{
+			name: "delete element by value of caseExact=false attribute",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "urn:ietf:params:scim:schemas:core:2.0:User:emails",
+				Key:        "FOO@HOME.COM",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 2, resp.Resource.Navigator().Dot("emails").Current().CountChildren())
+			},
+		},
+		{
+			name: "no such element",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "emails",
+				Key:        "bar@home.com",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrNotFound, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "ambiguous key",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "emails",
+				Key:        "foo@work.com",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidPath, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "attribute is not multiValued",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "userName",
+				Key:        "foo",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidPath, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "key that cannot be addressed",
+			request: &DeleteElementRequest{
+				ResourceID: "foo",
+				Attribute:  "emails",
+				Key:        `foo"@home.com`,
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "resource not found",
+			request: &DeleteElementRequest{
+				ResourceID: "bar",
+				Attribute:  "emails",
+				Key:        "foo@home.com",
+			},
+			expect: func(t *testing.T, resp *DeleteElementResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrNotFound, errors.Unwrap(err))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			database := db.Memory()
+			require.Nil(t, database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+				"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":       "foo",
+				"userName": "foo",
+				"meta": map[string]interface{}{
+					"version": "W/\"1\"",
+				},
+				"emails": []interface{}{
+					map[string]interface{}{
+						"value": "foo@work.com",
+						"type":  "work",
+					},
+					map[string]interface{}{
+						"value": "foo@home.com",
+						"type":  "home",
+					},
+					map[string]interface{}{
+						"value": "foo@work.com",
+						"type":  "other",
+					},
+				},
+			})))
+
+			service := DeleteElementService(database, PatchService(s.config, database, nil, []filter.ByResource{
+				filter.MetaFilter(),
+			}))
+			resp, err := service.Do(context.TODO(), test.request)
+			test.expect(t, resp, err)
+		})
+	}
+}
+
+func (s *DeleteElementServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.Nil(t, r.Navigator().Replace(data).Error())
+	return r
+}
+
+func (s *DeleteElementServiceTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+
+	s.config = new(spec.ServiceProviderConfig)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "patch": {
+    "supported": true
+  }
+}
+`), s.config))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -66,6 +68,111 @@ func (s *DeleteServiceTestSuite) TestDo() {
 				assert.Equal(t, spec.ErrNotFound, errors.Unwrap(err))
 			},
 		},
+		{
+			name: "delete when version matches",
+			setup: func(t *testing.T) Delete {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"id": "foobar",
+					"meta": map[string]interface{}{
+						"version": "W/\"1\"",
+					},
+				}))
+				require.Nil(t, err)
+				return DeleteService(s.etagConfig(), database)
+			},
+			getRequest: func() *DeleteRequest {
+				return &DeleteRequest{
+					ResourceID:    "foobar",
+					MatchCriteria: s.ifMatch("W/\"0\", W/\"1\""),
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "delete with asterisk when resource exists",
+			setup: func(t *testing.T) Delete {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"id": "foobar",
+					"meta": map[string]interface{}{
+						"version": "W/\"1\"",
+					},
+				}))
+				require.Nil(t, err)
+				return DeleteService(s.etagConfig(), database)
+			},
+			getRequest: func() *DeleteRequest {
+				return &DeleteRequest{
+					ResourceID:    "foobar",
+					MatchCriteria: s.ifMatch("*"),
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "delete when version does not match",
+			setup: func(t *testing.T) Delete {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"id": "foobar",
+					"meta": map[string]interface{}{
+						"version": "W/\"2\"",
+					},
+				}))
+				require.Nil(t, err)
+				return DeleteService(s.etagConfig(), database)
+			},
+			getRequest: func() *DeleteRequest {
+				return &DeleteRequest{
+					ResourceID:    "foobar",
+					MatchCriteria: s.ifMatch("W/\"1\""),
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrConflict, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "delete when resource is modified after pre condition check",
+			setup: func(t *testing.T) Delete {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"id": "foobar",
+					"meta": map[string]interface{}{
+						"version": "W/\"1\"",
+					},
+				}))
+				require.Nil(t, err)
+				return DeleteService(s.etagConfig(), &modifyAfterGetDatabase{
+					DB: database,
+					modify: func(ref *prop.Resource) {
+						modified := s.resourceOf(t, map[string]interface{}{
+							"id": "foobar",
+							"meta": map[string]interface{}{
+								"version": "W/\"2\"",
+							},
+						})
+						require.Nil(t, database.Replace(context.TODO(), ref, modified))
+					},
+				})
+			},
+			getRequest: func() *DeleteRequest {
+				return &DeleteRequest{
+					ResourceID:    "foobar",
+					MatchCriteria: s.ifMatch("W/\"1\""),
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrConflict, errors.Unwrap(err))
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -77,6 +184,41 @@ func (s *DeleteServiceTestSuite) TestDo() {
 	}
 }
 
+func (s *DeleteServiceTestSuite) etagConfig() *spec.ServiceProviderConfig {
+	config := new(spec.ServiceProviderConfig)
+	config.ETag.Supported = true
+	return config
+}
+
+// ifMatch returns match criteria that accepts one of the comma delimited versions, or any version if asterisk.
+func (s *DeleteServiceTestSuite) ifMatch(versions string) func(resource *prop.Resource) bool {
+	return func(resource *prop.Resource) bool {
+		if versions == "*" {
+			return true
+		}
+		for _, version := range strings.Split(versions, ",") {
+			if strings.TrimSpace(version) == resource.MetaVersionOrEmpty() {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// modifyAfterGetDatabase simulates a concurrent modification by invoking modify right after every successful Get.
+type modifyAfterGetDatabase struct {
+	db.DB
+	modify func(ref *prop.Resource)
+}
+
+func (d *modifyAfterGetDatabase) Get(ctx context.Context, id string, projection *crud.Projection) (*prop.Resource, error) {
+	r, err := d.DB.Get(ctx, id, projection)
+	if err == nil {
+		d.modify(r)
+	}
+	return r, err
+}
+
 func (s *DeleteServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
 	r := prop.NewResource(s.resourceType)
 	require.Nil(t, r.Navigator().Replace(data).Error())
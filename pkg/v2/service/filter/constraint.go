@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/crud/expr"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"strings"
+)
+
+// Constraint is a cross-attribute constraint that cannot be expressed natively by SCIM schemas. Constraints are
+// registered with ValidationFilter and are evaluated once per resource.
+type Constraint interface {
+	// Paths returns the attribute paths involved in this constraint. The first path anchors the constraint: it is
+	// evaluated when the validation filter visits the top level property that contains the first path.
+	Paths() []string
+	// Check evaluates the constraint against the resource whose main schema id is mainSchemaId. The root argument
+	// is the root property of the resource. A spec.ErrInvalidValue error is returned if the constraint is violated.
+	Check(mainSchemaId string, root prop.Property) error
+}
+
+// RequiredIfPresent returns a Constraint that requires all of the required attributes to be present when the
+// attribute at path is present.
+func RequiredIfPresent(path string, required ...string) Constraint {
+	return &requiredIfPresentConstraint{path: path, required: required}
+}
+
+// MutuallyExclusive returns a Constraint that allows at most one of the attributes to be present.
+func MutuallyExclusive(paths ...string) Constraint {
+	return &mutuallyExclusiveConstraint{paths: paths}
+}
+
+// AtLeastOneOf returns a Constraint that requires at least one of the attributes to be present.
+func AtLeastOneOf(paths ...string) Constraint {
+	return &atLeastOneOfConstraint{paths: paths}
+}
+
+type requiredIfPresentConstraint struct {
+	path     string
+	required []string
+}
+
+func (c *requiredIfPresentConstraint) Paths() []string {
+	return append([]string{c.path}, c.required...)
+}
+
+func (c *requiredIfPresentConstraint) Check(mainSchemaId string, root prop.Property) error {
+	if ok, err := isPresent(mainSchemaId, root, c.path); err != nil || !ok {
+		return err
+	}
+	for _, required := range c.required {
+		if ok, err := isPresent(mainSchemaId, root, required); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("%w: '%s' is required when '%s' is present", spec.ErrInvalidValue, required, c.path)
+		}
+	}
+	return nil
+}
+
+type mutuallyExclusiveConstraint struct {
+	paths []string
+}
+
+func (c *mutuallyExclusiveConstraint) Paths() []string {
+	return c.paths
+}
+
+func (c *mutuallyExclusiveConstraint) Check(mainSchemaId string, root prop.Property) error {
+	present := make([]string, 0)
+	for _, path := range c.paths {
+		if ok, err := isPresent(mainSchemaId, root, path); err != nil {
+			return err
+		} else if ok {
+			present = append(present, path)
+		}
+	}
+	if len(present) > 1 {
+		return fmt.Errorf("%w: '%s' are mutually exclusive", spec.ErrInvalidValue, strings.Join(present, "', '"))
+	}
+	return nil
+}
+
+type atLeastOneOfConstraint struct {
+	paths []string
+}
+
+func (c *atLeastOneOfConstraint) Paths() []string {
+	return c.paths
+}
+
+func (c *atLeastOneOfConstraint) Check(mainSchemaId string, root prop.Property) error {
+	for _, path := range c.paths {
+		if ok, err := isPresent(mainSchemaId, root, path); err != nil || ok {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: at least one of '%s' is required", spec.ErrInvalidValue, strings.Join(c.paths, "', '"))
+}
+
+// isPresent returns true if the property at path is assigned. When the path runs through a multiValued property, the
+// property is present if it is assigned in any of the elements.
+func isPresent(mainSchemaId string, root prop.Property, path string) (bool, error) {
+	head, err := compileConstraintPath(mainSchemaId, path)
+	if err != nil {
+		return false, err
+	}
+	return isPresentAlong(root, head), nil
+}
+
+func isPresentAlong(property prop.Property, step *expr.Expression) bool {
+	nav := prop.Navigate(property)
+	for ; step != nil; step = step.Next() {
+		if nav.Current().Attribute().MultiValued() {
+			return nav.Current().FindChild(func(child prop.Property) bool {
+				return isPresentAlong(child, step)
+			}) != nil
+		}
+		if nav.Dot(step.Token()); nav.HasError() {
+			return false
+		}
+	}
+	return !nav.Current().IsUnassigned()
+}
+
+// constraintAnchors reports whether the top level property is the one containing the first path of the constraint.
+func constraintAnchors(mainSchemaId string, constraint Constraint, property prop.Property) (bool, error) {
+	paths := constraint.Paths()
+	if len(paths) == 0 {
+		return false, nil
+	}
+	head, err := compileConstraintPath(mainSchemaId, paths[0])
+	if err != nil {
+		return false, err
+	}
+	return head != nil && property.Attribute().GoesBy(head.Token()), nil
+}
+
+func compileConstraintPath(mainSchemaId string, path string) (*expr.Expression, error) {
+	head, err := expr.CompilePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// schema id is a URN, whose comparison is case insensitive
+	if head != nil && strings.EqualFold(head.Token(), mainSchemaId) {
+		head = head.Next()
+	}
+	return head, nil
+}
@@ -0,0 +1,211 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestConstraint(t *testing.T) {
+	s := new(ConstraintTestSuite)
+	suite.Run(t, s)
+}
+
+type ConstraintTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *ConstraintTestSuite) TestValidationFilterWithConstraints() {
+	tests := []struct {
+		name        string
+		constraints []Constraint
+		data        map[string]interface{}
+		expect      func(t *testing.T, err error)
+	}{
+		{
+			name:        "required attribute present when anchor is present",
+			constraints: []Constraint{RequiredIfPresent("name.givenName", "name.familyName")},
+			data: map[string]interface{}{
+				"name": map[string]interface{}{
+					"givenName":  "Weinan",
+					"familyName": "Qiu",
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "required attribute not checked when anchor is absent",
+			constraints: []Constraint{RequiredIfPresent("name.givenName", "name.familyName")},
+			data:        map[string]interface{}{},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "required attribute absent when anchor is present",
+			constraints: []Constraint{RequiredIfPresent("name.givenName", "name.familyName")},
+			data: map[string]interface{}{
+				"name": map[string]interface{}{
+					"givenName": "Weinan",
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "name.familyName")
+				assert.Contains(t, err.Error(), "name.givenName")
+			},
+		},
+		{
+			name:        "only one of the mutually exclusive attributes present",
+			constraints: []Constraint{MutuallyExclusive("nickName", "displayName")},
+			data: map[string]interface{}{
+				"nickName": "imulab",
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "all of the mutually exclusive attributes present",
+			constraints: []Constraint{MutuallyExclusive("nickName", "displayName")},
+			data: map[string]interface{}{
+				"nickName":    "imulab",
+				"displayName": "Weinan",
+			},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "nickName")
+				assert.Contains(t, err.Error(), "displayName")
+			},
+		},
+		{
+			name:        "one of the attributes present in a multiValued attribute",
+			constraints: []Constraint{AtLeastOneOf("phoneNumbers.value", "ims.value")},
+			data: map[string]interface{}{
+				"phoneNumbers": []interface{}{
+					map[string]interface{}{
+						"type": "work",
+					},
+					map[string]interface{}{
+						"value": "123-45678",
+					},
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:        "none of the attributes present",
+			constraints: []Constraint{AtLeastOneOf("phoneNumbers.value", "ims.value")},
+			data: map[string]interface{}{
+				"phoneNumbers": []interface{}{
+					map[string]interface{}{
+						"type": "work",
+					},
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "phoneNumbers.value")
+				assert.Contains(t, err.Error(), "ims.value")
+			},
+		},
+		{
+			name:        "constraint path with main schema namespace and mixed case",
+			constraints: []Constraint{AtLeastOneOf("urn:ietf:params:scim:schemas:core:2.0:User:Name.GivenName")},
+			data: map[string]interface{}{
+				"name": map[string]interface{}{
+					"givenName": "Weinan",
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":       "C37527A1-B60F-4E30-8FD9-162A1740BDB6",
+				"userName": "imulab",
+				"emails": []interface{}{
+					map[string]interface{}{
+						"value": "imulab@foo.com",
+					},
+				},
+			}
+			for k, v := range test.data {
+				data[k] = v
+			}
+
+			resource := prop.NewResource(s.resourceType)
+			require.False(t, resource.Navigator().Replace(data).HasError())
+
+			err := Visit(context.Background(), resource, ValidationFilter(db.Memory(), test.constraints...))
+			test.expect(t, err)
+		})
+	}
+}
+
+func (s *ConstraintTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+}
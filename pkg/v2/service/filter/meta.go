@@ -102,8 +102,7 @@ func (f metaFilter) assignLocation(nav prop.Navigator, resource *prop.Resource)
 		return fmt.Errorf("%w: empty id", spec.ErrInternal)
 	}
 
-	location := strings.TrimSuffix(resource.ResourceType().Endpoint(), "/") + "/" + id
-	return nav.Replace(location).Error()
+	return nav.Replace(endpointLocation(resource)).Error()
 }
 
 func (f metaFilter) assignNewVersion(nav prop.Navigator, resource *prop.Resource) error {
@@ -128,3 +127,68 @@ func (f metaFilter) assignNewVersion(nav prop.Navigator, resource *prop.Resource
 
 	return nav.Replace(fmt.Sprintf("W/\"%x\"", sum)).Error()
 }
+
+// MetaReconcileFilter returns a ByResource filter that corrects meta.resourceType and meta.location of resources read
+// from the database, so that responses carry consistent meta regardless of how the resources got into the database
+// (i.e. by a data import, or before a change of the base URL). It is intended for the Get and Query services (see
+// service.GetWithFilters and service.QueryWithFilters), which hand it copies of the stored resources.
+//
+// The meta.resourceType is set to the id of the resource type of the resource. The meta.location is set to the result
+// of location, which is given the context of the request, i.e. handlerutil.LocationFromContext to apply the
+// LocationBuilder configured for the request. When location is nil or returns empty, the resource type endpoint
+// followed by the resource id is used, which is what MetaFilter assigns on creation. Values that are already correct
+// are left untouched, so that the filter is idempotent and costs a comparison on resources stored with the correct
+// meta. The meta.version is never altered. The meta.location of a resource without id is left as is.
+func MetaReconcileFilter(location func(ctx context.Context, resource *prop.Resource) string) ByResource {
+	return metaReconcileFilter{location: location}
+}
+
+type metaReconcileFilter struct {
+	location func(ctx context.Context, resource *prop.Resource) string
+}
+
+func (f metaReconcileFilter) Filter(ctx context.Context, resource *prop.Resource) error {
+	nav := resource.Navigator()
+	if nav.Dot("meta").HasError() {
+		return nav.Error()
+	}
+
+	if err := f.reconcile(nav, "resourceType", resource.ResourceType().ID()); err != nil {
+		return err
+	}
+	if len(resource.IdOrEmpty()) == 0 {
+		return nil
+	}
+	return f.reconcile(nav, "location", f.locationOf(ctx, resource))
+}
+
+func (f metaReconcileFilter) FilterRef(ctx context.Context, resource *prop.Resource, _ *prop.Resource) error {
+	return f.Filter(ctx, resource)
+}
+
+func (f metaReconcileFilter) locationOf(ctx context.Context, resource *prop.Resource) string {
+	if f.location != nil {
+		if location := f.location(ctx, resource); len(location) > 0 {
+			return location
+		}
+	}
+	return endpointLocation(resource)
+}
+
+// reconcile replaces the named sub property of meta with the value, unless it already holds the value.
+func (f metaReconcileFilter) reconcile(nav prop.Navigator, name string, value string) error {
+	if nav.Dot(name).HasError() {
+		return nav.Error()
+	}
+	defer nav.Retract()
+
+	if current, ok := nav.Current().Raw().(string); ok && current == value {
+		return nil
+	}
+	return nav.Replace(value).Error()
+}
+
+// endpointLocation returns the resource type endpoint followed by the resource id.
+func endpointLocation(resource *prop.Resource) string {
+	return strings.TrimSuffix(resource.ResourceType().Endpoint(), "/") + "/" + resource.IdOrEmpty()
+}
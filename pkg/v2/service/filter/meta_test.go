@@ -166,6 +166,90 @@ func (s *MetaFilterTestSuite) TestMetaFilter() {
 	}
 }
 
+func (s *MetaFilterTestSuite) TestMetaReconcileFilter() {
+	tests := []struct {
+		name        string
+		location    func(ctx context.Context, resource *prop.Resource) string
+		getResource func(t *testing.T) *prop.Resource
+		expect      func(t *testing.T, resource *prop.Resource, err error)
+	}{
+		{
+			name: "fill in absent meta",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Replace(map[string]interface{}{
+					"id":       "c37527a1-b60f-4e30-8fd9-162a1740bdb6",
+					"userName": "foobar",
+				}).HasError())
+				return r
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "User", resource.Navigator().Dot("meta").Dot("resourceType").Current().Raw())
+				assert.Equal(t, "/Users/c37527a1-b60f-4e30-8fd9-162a1740bdb6", resource.MetaLocationOrEmpty())
+				assert.True(t, resource.Navigator().Dot("meta").Dot("version").Current().IsUnassigned())
+			},
+		},
+		{
+			name: "correct stale meta",
+			location: func(ctx context.Context, resource *prop.Resource) string {
+				return "https://identity.imulab.io/Users/" + resource.IdOrEmpty()
+			},
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Replace(map[string]interface{}{
+					"id": "c37527a1-b60f-4e30-8fd9-162a1740bdb6",
+					"meta": map[string]interface{}{
+						"resourceType": "Person",
+						"created":      "2020-01-19T15:15:00",
+						"lastModified": "2020-01-19T15:15:00",
+						"location":     "https://old.imulab.io/Users/c37527a1-b60f-4e30-8fd9-162a1740bdb6",
+						"version":      "W\"1\"",
+					},
+					"userName": "foobar",
+				}).HasError())
+				return r
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "User", resource.Navigator().Dot("meta").Dot("resourceType").Current().Raw())
+				assert.Equal(t, "https://identity.imulab.io/Users/c37527a1-b60f-4e30-8fd9-162a1740bdb6", resource.MetaLocationOrEmpty())
+				assert.Equal(t, "2020-01-19T15:15:00", resource.Navigator().Dot("meta").Dot("lastModified").Current().Raw())
+				assert.Equal(t, "W\"1\"", resource.MetaVersionOrEmpty())
+			},
+		},
+		{
+			name: "leave location of resource without id",
+			getResource: func(t *testing.T) *prop.Resource {
+				r := prop.NewResource(s.resourceType)
+				assert.False(t, r.Navigator().Replace(map[string]interface{}{
+					"userName": "foobar",
+				}).HasError())
+				return r
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "User", resource.Navigator().Dot("meta").Dot("resourceType").Current().Raw())
+				assert.Empty(t, resource.MetaLocationOrEmpty())
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			filter := MetaReconcileFilter(test.location)
+			resource := test.getResource(t)
+			err := filter.Filter(context.Background(), resource)
+			test.expect(t, resource, err)
+
+			// reconciling again makes no difference
+			hash := resource.Hash()
+			assert.Nil(t, filter.Filter(context.Background(), resource))
+			assert.Equal(t, hash, resource.Hash())
+		})
+	}
+}
+
 func (s *MetaFilterTestSuite) SetupSuite() {
 	for _, each := range []struct {
 		filepath  string
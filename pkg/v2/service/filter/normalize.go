@@ -0,0 +1,120 @@
+package filter
+
+import (
+	"context"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"golang.org/x/text/cases"
+)
+
+// NormalizeFilter returns a ByProperty filter that replaces the value of singular string properties, whose attribute
+// is caseExact=false and uniqueness=server or uniqueness=global (i.e. userName), with its canonical form computed by
+// the normalizer (CaseFoldNormalizer if nil), so that uniqueness checks and lookups compare the same representation
+// regardless of the database's support for case insensitive comparison. It shall be placed before ValidationFilter.
+// Clients are shown the canonical form: a user created with userName "Foo" is returned with userName "foo".
+func NormalizeFilter(normalizer Normalizer) ByProperty {
+	if normalizer == nil {
+		normalizer = CaseFoldNormalizer()
+	}
+	return &normalizePropertyFilter{normalizer: normalizer}
+}
+
+// NormalizeFilterPreservingCase returns a ByProperty filter like NormalizeFilter, except that values keep the casing
+// originally supplied for display, and only a replacement differing from the reference value in case alone is reverted
+// to the reference value. It relies on the database comparing caseExact=false values case insensitively.
+func NormalizeFilterPreservingCase(normalizer Normalizer) ByProperty {
+	if normalizer == nil {
+		normalizer = CaseFoldNormalizer()
+	}
+	return &normalizePropertyFilter{normalizer: normalizer, preserveCase: true}
+}
+
+type (
+	// Normalizer computes the canonical form of the value of a caseExact=false attribute. Values that only differ in
+	// case shall result in the same canonical form, and a canonical form shall be its own canonical form.
+	Normalizer interface {
+		Normalize(attribute *spec.Attribute, value string) string
+	}
+	// Adapter to allow the use of ordinary functions as Normalizer.
+	NormalizerFunc func(attribute *spec.Attribute, value string) string
+)
+
+// Normalize calls f(attribute, value).
+func (f NormalizerFunc) Normalize(attribute *spec.Attribute, value string) string {
+	return f(attribute, value)
+}
+
+// CaseFoldNormalizer returns a Normalizer that computes the canonical form using Unicode case folding, which is
+// mostly lowercase, but also maps characters that have no lowercase variant (i.e. "ß" is folded to "ss").
+func CaseFoldNormalizer() Normalizer {
+	return NormalizerFunc(func(_ *spec.Attribute, value string) string {
+		// cases.Caser is stateful and cannot be shared across goroutines.
+		return cases.Fold().String(value)
+	})
+}
+
+type normalizePropertyFilter struct {
+	normalizer   Normalizer
+	preserveCase bool
+}
+
+func (f *normalizePropertyFilter) Supports(attribute *spec.Attribute) bool {
+	if attribute.MultiValued() || attribute.Type() != spec.TypeString || attribute.CaseExact() {
+		return false
+	}
+	return attribute.Uniqueness() == spec.UniquenessServer || attribute.Uniqueness() == spec.UniquenessGlobal
+}
+
+func (f *normalizePropertyFilter) Filter(_ context.Context, _ *spec.ResourceType, nav prop.Navigator) error {
+	if f.preserveCase {
+		return nil
+	}
+	return f.normalize(nav, nil)
+}
+
+func (f *normalizePropertyFilter) FilterRef(_ context.Context, _ *spec.ResourceType, nav prop.Navigator, refNav prop.Navigator) error {
+	// When normalized, the reference value was normalized when stored, hence the canonical form of a value that only
+	// differs in case is equal to the reference value.
+	if !f.preserveCase {
+		return f.normalize(nav, nil)
+	}
+	if refNav == nil {
+		return nil
+	}
+	return f.normalize(nav, refNav.Current())
+}
+
+// normalize replaces the value of the property with its canonical form, or, if the reference property is given, with
+// the reference value when the canonical forms of both are the same. An unassigned or out of sync reference property
+// holds no string value, and leaves the property untouched.
+func (f *normalizePropertyFilter) normalize(nav prop.Navigator, ref prop.Property) error {
+	if nav.HasError() {
+		return nav.Error()
+	}
+
+	if nav.Current().IsUnassigned() {
+		return nil
+	}
+
+	property := nav.Current()
+	value := property.Raw().(string)
+	canonical := f.normalizer.Normalize(property.Attribute(), value)
+	if ref != nil {
+		refValue, ok := ref.Raw().(string)
+		if !ok || f.normalizer.Normalize(property.Attribute(), refValue) != canonical {
+			return nil
+		}
+		canonical = refValue
+	}
+	if canonical == value {
+		return nil
+	}
+
+	// Replacing with a value that only differs in case is a no-op for caseExact=false properties, as the two values
+	// are considered equal. Hence, the value is deleted first. Like BCryptFilter, the replacement is strictly local.
+	if _, err := property.Delete(); err != nil {
+		return err
+	}
+	_, err := property.Replace(canonical)
+	return err
+}
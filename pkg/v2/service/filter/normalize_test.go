@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeFilter(t *testing.T) {
+	s := new(NormalizeFilterTestSuite)
+	suite.Run(t, s)
+}
+
+type NormalizeFilterTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+}
+
+func (s *NormalizeFilterTestSuite) TestFilter() {
+	tests := []struct {
+		name       string
+		normalizer Normalizer
+		data       map[string]interface{}
+		expect     func(t *testing.T, resource *prop.Resource, err error)
+	}{
+		{
+			name: "unique caseExact=false attribute is case folded",
+			data: map[string]interface{}{
+				"userName":    "Foo.Straße",
+				"displayName": "Foo Bar",
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "foo.strasse", resource.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, "Foo Bar", resource.Navigator().Dot("displayName").Current().Raw())
+			},
+		},
+		{
+			name: "non-unique attribute is not normalized",
+			data: map[string]interface{}{
+				"id":         "Foo",
+				"externalId": "FOO",
+				"nickName":   "Foo",
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "FOO", resource.Navigator().Dot("externalId").Current().Raw())
+				assert.Equal(t, "Foo", resource.Navigator().Dot("nickName").Current().Raw())
+			},
+		},
+		{
+			name: "unassigned attribute is untouched",
+			data: map[string]interface{}{
+				"displayName": "Foo",
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resource.Navigator().Dot("userName").Current().IsUnassigned())
+			},
+		},
+		{
+			name: "custom normalizer",
+			normalizer: NormalizerFunc(func(_ *spec.Attribute, value string) string {
+				return strings.ToUpper(value)
+			}),
+			data: map[string]interface{}{
+				"userName": "foo",
+			},
+			expect: func(t *testing.T, resource *prop.Resource, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "FOO", resource.Navigator().Dot("userName").Current().Raw())
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			resource := s.resourceOf(t, test.data)
+			err := Visit(context.Background(), resource, NormalizeFilter(test.normalizer))
+			test.expect(t, resource, err)
+		})
+	}
+}
+
+func (s *NormalizeFilterTestSuite) TestUniqueness() {
+	tests := []struct {
+		name      string
+		userName  string
+		normalize bool
+		expect    func(t *testing.T, err error)
+	}{
+		{
+			name:      "value differing in case is not unique",
+			userName:  "FOO.BAR",
+			normalize: true,
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.True(t, errors.Is(err, spec.ErrUniqueness))
+			},
+		},
+		{
+			name:      "value equal after case folding is not unique",
+			userName:  "Foo.Straße",
+			normalize: true,
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.True(t, errors.Is(err, spec.ErrUniqueness))
+			},
+		},
+		{
+			name:     "value equal after case folding is unique without normalization",
+			userName: "Foo.Straße",
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name:      "different value is unique",
+			userName:  "Foo.Baz",
+			normalize: true,
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			database := db.Memory()
+			for _, userName := range []string{"foo.bar", "foo.strasse"} {
+				require.Nil(t, database.Insert(context.Background(), s.resourceOf(t, map[string]interface{}{
+					"id":       userName,
+					"userName": userName,
+				})))
+			}
+
+			filters := []ByProperty{ValidationFilter(database)}
+			if test.normalize {
+				filters = append([]ByProperty{NormalizeFilter(nil)}, filters...)
+			}
+
+			resource := s.resourceOf(t, map[string]interface{}{
+				"id":       "new",
+				"userName": test.userName,
+			})
+			test.expect(t, Visit(context.Background(), resource, filters...))
+		})
+	}
+}
+
+func (s *NormalizeFilterTestSuite) TestFilterRef() {
+	database := db.Memory()
+	ref := s.resourceOf(s.T(), map[string]interface{}{
+		"id":       "foo",
+		"userName": "foo",
+	})
+	require.Nil(s.T(), database.Insert(context.Background(), ref))
+
+	resource := s.resourceOf(s.T(), map[string]interface{}{
+		"id":       "foo",
+		"userName": "FOO",
+	})
+	err := VisitWithRef(context.Background(), resource, ref, NormalizeFilter(nil), ValidationFilter(database))
+	assert.Nil(s.T(), err)
+	assert.Equal(s.T(), "foo", resource.Navigator().Dot("userName").Current().Raw())
+	assert.Equal(s.T(), ref.Hash(), resource.Hash())
+}
+
+func (s *NormalizeFilterTestSuite) TestPreservingCase() {
+	ref := s.resourceOf(s.T(), map[string]interface{}{
+		"id":       "foo",
+		"userName": "Foo.Bar",
+	})
+
+	tests := []struct {
+		name     string
+		userName string
+		expect   string
+	}{
+		{name: "value differing in case alone is reverted", userName: "FOO.BAR", expect: "Foo.Bar"},
+		{name: "different value is kept as supplied", userName: "Foo.Baz", expect: "Foo.Baz"},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			resource := s.resourceOf(t, map[string]interface{}{
+				"id":       "foo",
+				"userName": test.userName,
+			})
+			err := VisitWithRef(context.Background(), resource, ref, NormalizeFilterPreservingCase(nil))
+			assert.Nil(t, err)
+			assert.Equal(t, test.expect, resource.Navigator().Dot("userName").Current().Raw())
+		})
+	}
+
+	s.T().Run("created value is kept as supplied", func(t *testing.T) {
+		resource := s.resourceOf(t, map[string]interface{}{"userName": "Foo.Bar"})
+		assert.Nil(t, Visit(context.Background(), resource, NormalizeFilterPreservingCase(nil)))
+		assert.Equal(t, "Foo.Bar", resource.Navigator().Dot("userName").Current().Raw())
+	})
+}
+
+func (s *NormalizeFilterTestSuite) resourceOf(t *testing.T, data map[string]interface{}) *prop.Resource {
+	data["schemas"] = []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"}
+	data["emails"] = []interface{}{map[string]interface{}{"value": "foo@bar.com"}}
+	resource := prop.NewResource(s.resourceType)
+	require.False(t, resource.Navigator().Replace(data).HasError())
+	return resource
+}
+
+func (s *NormalizeFilterTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+}
@@ -4,15 +4,17 @@ import (
 	"context"
 	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/annotation"
+	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/spec"
+	"math"
 	"strconv"
 	"strings"
 )
 
 // ValidationFilter returns a ByProperty that performs validation on each property. The validation carried out are
-// required check, canonical check, mutability check and uniqueness check.
+// required check, canonical check, cardinality check, mutability check, uniqueness check and constraint check.
 //
 // The required check fails when attribute is required but property is unassigned.
 //
@@ -20,6 +22,10 @@ import (
 // defined should be treated as the only valid values of holding property, and the property value is not among
 // the canonicalValues.
 //
+// The cardinality check fails when @MaxCardinality is annotated with a multiValued attribute, and the number of assigned
+// elements in the property exceeds the "max" parameter of the annotation. Because the check is performed on the final
+// state of the property, it also catches PATCH operations that would have exceeded the limit when merged.
+//
 // The mutability check only fails when attribute is immutable, and the property value differs from the reference
 // property value, if one exists. It does not check for readOnly attributes because the logic is largely handled
 // by ReadOnlyFilter.
@@ -27,22 +33,36 @@ import (
 // The uniqueness check fails when the property value already exists in the database. It formulates the query
 // (id ne <id>) and (<path> eq <value>), where <id> is the resource id, <path> is the unique attribute path, and
 // <value> is the property value. The database returns the number of records matching this filter. If the count is
-// greater than 0, the check fails. Note this check only handles the uniqueness=server case.
+// greater than 0, the check fails with spec.ErrUniqueness, carrying the conflicting attribute path as the "attribute"
+// extra, so that clients are able to tell which attribute conflicted. Note this check only handles the
+// uniqueness=server case. If database is nil, the uniqueness check is skipped.
+//
+// The constraint check fails when any of the cross-attribute constraints is violated. Each constraint is evaluated
+// once per resource, when the top level property containing the first path of the constraint is visited.
 //
 // Error is returned to caller if any of these check fails.
-func ValidationFilter(database db.DB) ByProperty {
-	return &validationPropertyFilter{database: database}
+func ValidationFilter(database db.DB, constraints ...Constraint) ByProperty {
+	return &validationPropertyFilter{database: database, constraints: constraints}
+}
+
+// ValidationFilterRevealingConflicts returns a ByProperty that performs the same validation as ValidationFilter, except
+// that a failed uniqueness check additionally carries the id of the existing resource holding the conflicting value as
+// the "existingId" extra. It is a separate opt-in, as the id reveals data of another resource to the client.
+func ValidationFilterRevealingConflicts(database db.DB, constraints ...Constraint) ByProperty {
+	return &validationPropertyFilter{database: database, constraints: constraints, revealConflicts: true}
 }
 
 type validationPropertyFilter struct {
-	database db.DB
+	database        db.DB
+	constraints     []Constraint
+	revealConflicts bool
 }
 
 func (f *validationPropertyFilter) Supports(_ *spec.Attribute) bool {
 	return true
 }
 
-func (f *validationPropertyFilter) Filter(ctx context.Context, _ *spec.ResourceType, nav prop.Navigator) error {
+func (f *validationPropertyFilter) Filter(ctx context.Context, resourceType *spec.ResourceType, nav prop.Navigator) error {
 	if nav.HasError() {
 		return nav.Error()
 	}
@@ -54,14 +74,20 @@ func (f *validationPropertyFilter) Filter(ctx context.Context, _ *spec.ResourceT
 	if err := f.validateCanonical(property); err != nil {
 		return err
 	}
+	if err := f.validateMaxCardinality(property); err != nil {
+		return err
+	}
 	if err := f.validateUniqueness(ctx, nav); err != nil {
 		return err
 	}
+	if err := f.validateConstraints(resourceType, nav); err != nil {
+		return err
+	}
 
 	return nil
 }
 
-func (f *validationPropertyFilter) FilterRef(ctx context.Context, _ *spec.ResourceType, nav prop.Navigator, refNav prop.Navigator) error {
+func (f *validationPropertyFilter) FilterRef(ctx context.Context, resourceType *spec.ResourceType, nav prop.Navigator, refNav prop.Navigator) error {
 	if nav.HasError() {
 		return nav.Error()
 	}
@@ -72,12 +98,18 @@ func (f *validationPropertyFilter) FilterRef(ctx context.Context, _ *spec.Resour
 	if err := f.validateCanonical(nav.Current()); err != nil {
 		return err
 	}
+	if err := f.validateMaxCardinality(nav.Current()); err != nil {
+		return err
+	}
 	if err := f.validateMutability(nav.Current(), refNav.Current()); err != nil {
 		return err
 	}
 	if err := f.validateUniqueness(ctx, nav); err != nil {
 		return err
 	}
+	if err := f.validateConstraints(resourceType, nav); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -120,6 +152,61 @@ func (f *validationPropertyFilter) validateCanonical(property prop.Property) err
 	return nil
 }
 
+func (f *validationPropertyFilter) validateMaxCardinality(property prop.Property) error {
+	if !property.Attribute().MultiValued() {
+		return nil
+	}
+
+	params, ok := property.Attribute().Annotation(annotation.MaxCardinality)
+	if !ok {
+		return nil
+	}
+
+	max, ok := cardinalityOf(params["max"])
+	if !ok {
+		return fmt.Errorf("%w: invalid max parameter of @MaxCardinality on '%s'", spec.ErrInternal, property.Attribute().Path())
+	}
+
+	n := 0
+	_ = property.ForEachChild(func(_ int, child prop.Property) error {
+		if !child.IsUnassigned() {
+			n++
+		}
+		return nil
+	})
+	if n > max {
+		return fmt.Errorf("%w: '%s' cannot have more than %d values", spec.ErrInvalidValue, property.Attribute().Path(), max)
+	}
+
+	return nil
+}
+
+// cardinalityOf returns the non-negative whole number represented by the annotation parameter value, which could be a
+// number decoded from JSON (i.e. 10, 10.0 or 1e1), an integer, or a string of a number.
+func cardinalityOf(value interface{}) (int, bool) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case int:
+		f = float64(v)
+	case int64:
+		f = float64(v)
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		f = parsed
+	default:
+		return 0, false
+	}
+	if f < 0 || f != math.Trunc(f) || f > math.MaxInt32 {
+		return 0, false
+	}
+	return int(f), true
+}
+
 func (f *validationPropertyFilter) validateMutability(property prop.Property, ref prop.Property) error {
 	if ref == nil || IsOutOfSync(ref) {
 		return nil
@@ -146,7 +233,7 @@ func (f *validationPropertyFilter) validateUniqueness(ctx context.Context, nav p
 		return nil
 	}
 
-	if property.IsUnassigned() {
+	if property.IsUnassigned() || f.database == nil {
 		return nil
 	}
 
@@ -166,11 +253,53 @@ func (f *validationPropertyFilter) validateUniqueness(ctx context.Context, nav p
 		property.Attribute().Path(),
 		strconv.Quote(fmt.Sprintf("%v", property.Raw())),
 	)
-	n, err := f.database.Count(ctx, filter)
+	if !f.revealConflicts {
+		n, err := f.database.Count(ctx, filter)
+		if err != nil {
+			return err
+		} else if n > 0 {
+			return f.uniquenessError(property, nil)
+		}
+		return nil
+	}
+
+	conflicts, err := f.database.Query(ctx, filter, nil, &crud.Pagination{StartIndex: 1, Count: 1}, nil)
 	if err != nil {
 		return err
-	} else if n > 0 {
-		return fmt.Errorf("%w: value of '%s' is not unique", spec.ErrInvalidValue, property.Attribute().Path())
+	} else if len(conflicts) > 0 {
+		return f.uniquenessError(property, conflicts[0])
+	}
+
+	return nil
+}
+
+// uniquenessError returns the spec.ErrUniqueness error for the property, carrying the conflicting attribute path and,
+// when conflict is not nil, the id of the conflicting resource as extras.
+func (f *validationPropertyFilter) uniquenessError(property prop.Property, conflict *prop.Resource) error {
+	path := property.Attribute().Path()
+	extras := map[string]interface{}{"attribute": path}
+	if conflict != nil {
+		extras["existingId"] = conflict.IdOrEmpty()
+	}
+	return fmt.Errorf("%w: value of '%s' is not unique", spec.ErrUniqueness.WithExtras(extras), path)
+}
+
+func (f *validationPropertyFilter) validateConstraints(resourceType *spec.ResourceType, nav prop.Navigator) error {
+	// only top level properties, whose container is the resource root, anchor constraints
+	if len(f.constraints) == 0 || nav.Depth() != 2 {
+		return nil
+	}
+
+	mainSchemaId := resourceType.Schema().ID()
+	for _, constraint := range f.constraints {
+		if ok, err := constraintAnchors(mainSchemaId, constraint, nav.Current()); err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		if err := constraint.Check(mainSchemaId, nav.Source()); err != nil {
+			return err
+		}
 	}
 
 	return nil
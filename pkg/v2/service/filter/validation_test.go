@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/db"
@@ -152,6 +153,100 @@ func TestValidationFilter(t *testing.T) {
 				assert.Nil(t, err)
 			},
 		},
+		{
+			name: "multiValued property within max cardinality passes",
+			attrJson: `
+{
+  "id": "photos",
+  "name": "photos",
+  "_path": "photos",
+  "type": "string",
+  "multiValued": true,
+  "_annotations": {
+    "@MaxCardinality": {
+      "max": 2
+    }
+  }
+}
+`,
+			getProperty: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				p := prop.NewProperty(attr)
+				_, err := p.Replace([]interface{}{"A", "B"})
+				assert.Nil(t, err)
+				return prop.Navigate(p)
+			},
+			getReference: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				return nil
+			},
+			getDB: func() db.DB { return nil },
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
+		{
+			name: "multiValued property exceeding max cardinality fails",
+			attrJson: `
+{
+  "id": "photos",
+  "name": "photos",
+  "_path": "photos",
+  "type": "string",
+  "multiValued": true,
+  "_annotations": {
+    "@MaxCardinality": {
+      "max": 2
+    }
+  }
+}
+`,
+			getProperty: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				p := prop.NewProperty(attr)
+				_, err := p.Replace([]interface{}{"A", "B", "C"})
+				assert.Nil(t, err)
+				return prop.Navigate(p)
+			},
+			getReference: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				return nil
+			},
+			getDB: func() db.DB { return nil },
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "multiValued property exceeding max cardinality after merging added values fails",
+			attrJson: `
+{
+  "id": "photos",
+  "name": "photos",
+  "_path": "photos",
+  "type": "string",
+  "multiValued": true,
+  "_annotations": {
+    "@MaxCardinality": {
+      "max": 2
+    }
+  }
+}
+`,
+			getProperty: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				p := prop.NewProperty(attr)
+				_, err := p.Replace([]interface{}{"A", "B"})
+				assert.Nil(t, err)
+				_, err = p.Add([]interface{}{"C"})
+				assert.Nil(t, err)
+				return prop.Navigate(p)
+			},
+			getReference: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				return nil
+			},
+			getDB: func() db.DB { return nil },
+			expect: func(t *testing.T, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
 		{
 			name: "immutable property fails check when value different with reference",
 			attrJson: `
@@ -202,7 +297,8 @@ func TestValidationFilter(t *testing.T) {
 			},
 			expect: func(t *testing.T, err error) {
 				assert.NotNil(t, err)
-				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+				assert.True(t, errors.Is(err, spec.ErrUniqueness))
+				assert.Equal(t, map[string]interface{}{"attribute": "userName"}, errors.Unwrap(err).(*spec.Error).Extras)
 			},
 		},
 		{
@@ -228,6 +324,27 @@ func TestValidationFilter(t *testing.T) {
 				assert.Nil(t, err)
 			},
 		},
+		{
+			name:     "uniqueness check is skipped without database",
+			attrJson: `{}`,
+			getProperty: func(t *testing.T, _ *spec.Attribute) prop.Navigator {
+				resourceType := getResourceType()
+				nav := prop.NewResource(resourceType).Navigator()
+				assert.False(t, nav.Replace(map[string]interface{}{
+					"id":       "return_1_please",
+					"userName": "foobar",
+				}).HasError())
+
+				return nav.Dot("userName")
+			},
+			getReference: func(t *testing.T, attr *spec.Attribute) prop.Navigator {
+				return nil
+			},
+			getDB: func() db.DB { return nil },
+			expect: func(t *testing.T, err error) {
+				assert.Nil(t, err)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -251,6 +368,94 @@ func TestValidationFilter(t *testing.T) {
 	}
 }
 
+func TestValidationFilterRevealingConflicts(t *testing.T) {
+	for _, path := range []string{
+		"../../../../public/schemas/core_schema.json",
+		"../../../../public/schemas/user_schema.json",
+	} {
+		raw, err := ioutil.ReadFile(path)
+		require.Nil(t, err)
+		schema := new(spec.Schema)
+		require.Nil(t, json.Unmarshal(raw, schema))
+		spec.Schemas().Register(schema)
+	}
+	raw, err := ioutil.ReadFile("../../../../public/resource_types/user_resource_type.json")
+	require.Nil(t, err)
+	resourceType := new(spec.ResourceType)
+	require.Nil(t, json.Unmarshal(raw, resourceType))
+
+	resourceOf := func(t *testing.T, id string) *prop.Resource {
+		r := prop.NewResource(resourceType)
+		require.False(t, r.Navigator().Replace(map[string]interface{}{
+			"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+			"id":       id,
+			"userName": "foobar",
+		}).HasError())
+		return r
+	}
+
+	database := db.Memory()
+	require.Nil(t, database.Insert(context.Background(), resourceOf(t, "existing")))
+
+	tests := []struct {
+		name   string
+		filter ByProperty
+		expect map[string]interface{}
+	}{
+		{
+			name:   "conflicting id is not revealed by default",
+			filter: ValidationFilter(database),
+			expect: map[string]interface{}{"attribute": "userName"},
+		},
+		{
+			name:   "conflicting id is revealed",
+			filter: ValidationFilterRevealingConflicts(database),
+			expect: map[string]interface{}{"attribute": "userName", "existingId": "existing"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.filter.Filter(context.Background(), resourceType, resourceOf(t, "new").Navigator().Dot("userName"))
+			assert.True(t, errors.Is(err, spec.ErrUniqueness))
+			assert.Contains(t, err.Error(), "userName")
+			assert.Equal(t, test.expect, errors.Unwrap(err).(*spec.Error).Extras)
+
+			assert.Nil(t, test.filter.Filter(context.Background(), resourceType, resourceOf(t, "existing").Navigator().Dot("userName")))
+		})
+	}
+}
+
+func TestCardinalityOf(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		expect int
+		ok     bool
+	}{
+		{value: float64(10), expect: 10, ok: true},
+		{value: 1e1, expect: 10, ok: true},
+		{value: 10, expect: 10, ok: true},
+		{value: int64(10), expect: 10, ok: true},
+		{value: "10", expect: 10, ok: true},
+		{value: "10.0", expect: 10, ok: true},
+		{value: "1e1", expect: 10, ok: true},
+		{value: 0, expect: 0, ok: true},
+		{value: 10.5, ok: false},
+		{value: -1, ok: false},
+		{value: "ten", ok: false},
+		{value: nil, ok: false},
+		{value: true, ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%T %v", test.value, test.value), func(t *testing.T) {
+			n, ok := cardinalityOf(test.value)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expect, n)
+		})
+	}
+}
+
 type uniquenessTestMockDatabase struct {
 	mock.Mock
 }
@@ -280,6 +485,10 @@ func (d *uniquenessTestMockDatabase) Get(_ context.Context, _ string, _ *crud.Pr
 	return nil, nil
 }
 
+func (d *uniquenessTestMockDatabase) GetByAttribute(_ context.Context, _ string, _ interface{}) (*prop.Resource, error) {
+	return nil, nil
+}
+
 func (d *uniquenessTestMockDatabase) Replace(_ context.Context, _ *prop.Resource, _ *prop.Resource) error {
 	return nil
 }
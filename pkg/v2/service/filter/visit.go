@@ -6,8 +6,8 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/spec"
 )
 
-// Visit performs a DFS visit on the resource and sequentially invokes the ByProperty filters on each visited property
-// in the resource. Any visit or filtering error is returned.
+// Visit performs a DFS walk (see prop.Walk) on the resource and sequentially invokes the ByProperty filters on each
+// visited property in the resource. Any visit or filtering error is returned.
 func Visit(ctx context.Context, resource *prop.Resource, filters ...ByProperty) error {
 	n := flexNavigator{stack: []prop.Property{resource.RootProperty()}}
 	v := syncVisitor{
@@ -24,7 +24,7 @@ func Visit(ctx context.Context, resource *prop.Resource, filters ...ByProperty)
 			return nil
 		},
 	}
-	return resource.Visit(&v)
+	return prop.Walk(resource, &v)
 }
 
 // VisitWithRef performs a DFS visit on the resource and sequentially invokes the ByProperty filters on each visited
@@ -52,20 +52,36 @@ func VisitWithRef(ctx context.Context, resource *prop.Resource, ref *prop.Resour
 			return nil
 		},
 	}
-	return resource.Visit(&v)
+	return prop.Walk(resource, &v)
 }
 
+// syncVisitor walks the resource (see prop.Walk) with the resource navigator, having the reference navigator follow
+// along, and invokes visitFunc on every property walked.
 type syncVisitor struct {
 	resourceNav  *flexNavigator // flex navigator to be used in active mode
 	referenceNav *flexNavigator // flex navigator to be used in passive (follow-along) mode
 	visitFunc    func(resourceNav prop.Navigator, referenceNav prop.Navigator) error
 }
 
-func (v *syncVisitor) ShouldVisit(_ prop.Property) bool {
-	return true
+func (v *syncVisitor) EnterContainer(_ *spec.Attribute, _ string, container prop.Property) error {
+	v.push(container)
+	return v.visitFunc(v.resourceNav, v.referenceNav)
+}
+
+func (v *syncVisitor) LeaveContainer(_ *spec.Attribute, _ string, _ prop.Property) error {
+	v.retract()
+	return nil
+}
+
+func (v *syncVisitor) Value(_ *spec.Attribute, _ string, property prop.Property) error {
+	v.push(property)
+	defer v.retract()
+	return v.visitFunc(v.resourceNav, v.referenceNav)
 }
 
-func (v *syncVisitor) Visit(property prop.Property) error {
+// push focuses the resource navigator on the property, and the reference navigator on its counterpart: the element
+// matching the property if the container is multiValued, or the sub property by the same name otherwise.
+func (v *syncVisitor) push(property prop.Property) {
 	v.resourceNav.Push(property)
 	if v.referenceNav != nil {
 		if container := v.resourceNav.Last(); container != nil {
@@ -78,23 +94,9 @@ func (v *syncVisitor) Visit(property prop.Property) error {
 			}
 		}
 	}
-
-	// Simple properties retract at the end of Visit, container properties retract in EndChildren
-	if !property.Attribute().MultiValued() && property.Attribute().Type() != spec.TypeComplex {
-		defer func() {
-			v.resourceNav.Retract()
-			if v.referenceNav != nil {
-				v.referenceNav.Retract()
-			}
-		}()
-	}
-
-	return v.visitFunc(v.resourceNav, v.referenceNav)
 }
 
-func (v *syncVisitor) BeginChildren(_ prop.Property) {}
-
-func (v *syncVisitor) EndChildren(_ prop.Property) {
+func (v *syncVisitor) retract() {
 	v.resourceNav.Retract()
 	if v.referenceNav != nil {
 		v.referenceNav.Retract()
@@ -5,11 +5,24 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
 )
 
 // GetService returns a get resource service.
-func GetService(database db.DB) Get {
-	return &getService{database: database}
+func GetService(database db.DB, options ...GetOption) Get {
+	s := &getService{database: database}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// GetWithFilters returns a GetOption to run the filters on the resource read from the database, before it is returned
+// (i.e. filter.MetaReconcileFilter). An error from the filters fails the request.
+func GetWithFilters(filters ...filter.ByResource) GetOption {
+	return func(s *getService) {
+		s.filters = append(s.filters, filters...)
+	}
 }
 
 type (
@@ -17,6 +30,8 @@ type (
 	Get interface {
 		Do(ctx context.Context, req *GetRequest) (resp *GetResponse, err error)
 	}
+	// Option to customize the get resource service
+	GetOption func(s *getService)
 	// Get resource request
 	GetRequest struct {
 		ResourceID string           // id of the resource to get
@@ -30,6 +45,7 @@ type (
 
 type getService struct {
 	database db.DB
+	filters  []filter.ByResource
 }
 
 func (s *getService) Do(ctx context.Context, req *GetRequest) (resp *GetResponse, err error) {
@@ -38,6 +54,12 @@ func (s *getService) Do(ctx context.Context, req *GetRequest) (resp *GetResponse
 		return
 	}
 
+	for _, f := range s.filters {
+		if err = f.Filter(ctx, resource); err != nil {
+			return
+		}
+	}
+
 	resp = &GetResponse{Resource: resource}
 	return
 }
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,6 +53,29 @@ func (s *GetServiceTestSuite) TestDo() {
 				assert.Equal(t, "foobar", resp.Resource.Navigator().Dot("id").Current().Raw())
 			},
 		},
+		{
+			name: "get imported resource with absent meta",
+			setup: func(t *testing.T) Get {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"id":       "foobar",
+					"userName": "foobar",
+				}))
+				require.Nil(t, err)
+				return GetService(database, GetWithFilters(filter.MetaReconcileFilter(nil)))
+			},
+			getRequest: func() *GetRequest {
+				return &GetRequest{
+					ResourceID: "foobar",
+				}
+			},
+			expect: func(t *testing.T, resp *GetResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, "User", resp.Resource.Navigator().Dot("meta").Dot("resourceType").Current().Raw())
+				assert.Equal(t, "/Users/foobar", resp.Resource.MetaLocationOrEmpty())
+			},
+		},
 		{
 			name: "get non-existing",
 			setup: func(t *testing.T) Get {
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/satori/go.uuid"
+)
+
+// IdGenerator generates the server assigned id for new resources of the resource type.
+type IdGenerator interface {
+	// Generate returns a new id for a resource of the resource type, or an error if no id can be generated.
+	Generate(resourceType *spec.ResourceType) (string, error)
+}
+
+// IdGeneratorFunc is an adapter to allow the use of ordinary functions as IdGenerator.
+type IdGeneratorFunc func(resourceType *spec.ResourceType) (string, error)
+
+func (f IdGeneratorFunc) Generate(resourceType *spec.ResourceType) (string, error) {
+	return f(resourceType)
+}
+
+// UUIDGenerator returns an IdGenerator that generates version 4 UUIDs for resources of all resource types.
+func UUIDGenerator() IdGenerator {
+	return uuidGenerator{}
+}
+
+type uuidGenerator struct{}
+
+func (g uuidGenerator) Generate(_ *spec.ResourceType) (string, error) {
+	return uuid.NewV4().String(), nil
+}
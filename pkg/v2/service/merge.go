@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	scimjson "github.com/imulab/go-scim/pkg/v2/json"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"io"
+	"io/ioutil"
+)
+
+// MergeService returns a service to merge a partial representation into an existing resource. Unlike replace, merge
+// only touches the attributes present in the partial resource:
+//   - attributes absent from the partial resource are preserved;
+//   - attributes explicitly assigned null (or [] for multiValued attributes) are cleared;
+//   - singular complex attributes are merged sub attribute by sub attribute;
+//   - other attributes, including multiValued ones, are replaced as a whole.
+//
+// Absent and null attributes are told apart by the Dirty state of the unassigned properties, hence the partial resource
+// is expected to be de-serialized from JSON, or otherwise constructed from a new resource.
+//
+// These are the semantics of JSON Merge Patch (RFC 7386), hence the service can also take a merge patch document as
+// the PayloadSource of the request, which is de-serialized into the partial resource of the type of the resource to
+// merge into. The document must be a JSON object whose fields are attributes of the resource type.
+//
+// ReadOnly attributes and "schemas" of the partial resource are ignored, since they are managed by the server. Merge
+// is effectively a patch: preFilters and postFilters run in the same way as PatchService, hence the same filters (i.e.
+// validation against immutable attributes) shall be supplied to enforce the SCIM rules.
+func MergeService(
+	config *spec.ServiceProviderConfig,
+	database db.DB,
+	preFilters []filter.ByResource,
+	postFilters []filter.ByResource,
+) Merge {
+	return &mergeService{
+		patch: &patchService{
+			preFilters:  preFilters,
+			postFilters: postFilters,
+			database:    database,
+			config:      config,
+		},
+	}
+}
+
+type (
+	// Merge resource service
+	Merge interface {
+		Do(ctx context.Context, req *MergeRequest) (resp *MergeResponse, err error)
+	}
+	// Merge resource request
+	MergeRequest struct {
+		ResourceID    string                             // id of the resource to merge into
+		Partial       *prop.Resource                     // partial resource to merge
+		PayloadSource io.Reader                          // merge patch document to de-serialize the partial resource from, when Partial is nil
+		MatchCriteria func(resource *prop.Resource) bool // extra criteria to meet for the resource to be merged into
+		Delta         bool                               // true to render only the changed attributes in response
+	}
+	// Merge resource response
+	MergeResponse struct {
+		Merged   bool               // true if the resource was changed; false if the resource was not changed but there was no error
+		Ref      *prop.Resource     // reference resource (the before state)
+		Resource *prop.Resource     // merged resource (the after state)
+		Options  []scimjson.Options // serialization options for the merged resource; restricts to changes when delta was requested
+	}
+)
+
+type mergeService struct {
+	patch *patchService
+}
+
+func (s *mergeService) Do(ctx context.Context, req *MergeRequest) (resp *MergeResponse, err error) {
+	if req == nil || (req.Partial == nil && req.PayloadSource == nil) {
+		err = fmt.Errorf("%w: no partial resource for merge service", spec.ErrInternal)
+		return
+	}
+
+	var document []byte
+	if req.Partial == nil {
+		if document, err = ioutil.ReadAll(req.PayloadSource); err != nil {
+			err = fmt.Errorf("%w: failed to read request body", spec.ErrInternal)
+			return
+		}
+	}
+
+	patchResp, err := s.patch.patch(ctx, req.ResourceID, req.MatchCriteria, req.Delta, func(resource *prop.Resource) error {
+		partial := req.Partial
+		if partial == nil {
+			var err error
+			if partial, err = s.parseDocument(document, resource.ResourceType()); err != nil {
+				return err
+			}
+		}
+		if resource.ResourceType().ID() != partial.ResourceType().ID() {
+			return fmt.Errorf("%w: cannot merge '%s' into '%s'", spec.ErrInvalidValue,
+				partial.ResourceType().Name(), resource.ResourceType().Name())
+		}
+		return s.merge(resource.Navigator(), partial.RootProperty())
+	})
+	if err != nil {
+		return
+	}
+
+	resp = &MergeResponse{
+		Merged:   patchResp.Patched,
+		Ref:      patchResp.Ref,
+		Resource: patchResp.Resource,
+		Options:  patchResp.Options,
+	}
+	return
+}
+
+// parseDocument de-serializes the merge patch document into a partial resource of the resource type. Unlike RFC 7386,
+// which replaces the whole target with a document that is not a JSON object, such documents are rejected.
+func (s *mergeService) parseDocument(document []byte, resourceType *spec.ResourceType) (*prop.Resource, error) {
+	if trimmed := bytes.TrimSpace(document); len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil, fmt.Errorf("%w: merge patch document must be a JSON object", spec.ErrInvalidSyntax)
+	}
+
+	partial := prop.NewResource(resourceType)
+	if err := scimjson.Deserialize(document, partial); err != nil {
+		return nil, err
+	}
+	return partial, nil
+}
+
+// merge merges the children of the partial property into the property currently focused by the navigator.
+func (s *mergeService) merge(nav prop.Navigator, partial prop.Property) error {
+	return partial.ForEachChild(func(_ int, child prop.Property) error {
+		attr := child.Attribute()
+		if child.IsUnassigned() && !child.Dirty() {
+			return nil // absent from the partial resource
+		}
+		if attr.Mutability() == spec.MutabilityReadOnly || attr.ID() == "schemas" {
+			return nil
+		}
+
+		if nav.Dot(attr.Name()); nav.HasError() {
+			return nav.Error()
+		}
+		defer nav.Retract()
+
+		switch {
+		case child.IsUnassigned():
+			nav.Delete()
+		case !attr.MultiValued() && attr.Type() == spec.TypeComplex:
+			return s.merge(nav, child)
+		default:
+			nav.Replace(child.Raw())
+		}
+		return nav.Error()
+	})
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	scimjson "github.com/imulab/go-scim/pkg/v2/json"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMergeService(t *testing.T) {
+	s := new(MergeServiceTestSuite)
+	suite.Run(t, s)
+}
+
+type MergeServiceTestSuite struct {
+	suite.Suite
+	resourceType *spec.ResourceType
+	config       *spec.ServiceProviderConfig
+}
+
+func (s *MergeServiceTestSuite) TestDo() {
+	tests := []struct {
+		name       string
+		getRequest func(t *testing.T) *MergeRequest
+		expect     func(t *testing.T, resp *MergeResponse, err error)
+	}{
+		{
+			name: "present attributes overwrite and absent attributes are preserved",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					Partial: s.partialOf(t, `
+{
+	"displayName": "Foo Bar",
+	"name": {
+		"givenName": "Foo"
+	},
+	"emails": [
+		{
+			"value": "foo@baz.com",
+			"type": "work"
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Merged)
+				assert.NotEqual(t, resp.Ref.MetaVersionOrEmpty(), resp.Resource.MetaVersionOrEmpty())
+				assert.Equal(t, "Foo Bar", resp.Resource.Navigator().Dot("displayName").Current().Raw())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("name").Dot("givenName").Current().Raw())
+				assert.Equal(t, "Bar", resp.Resource.Navigator().Dot("name").Dot("familyName").Current().Raw())
+				assert.Equal(t, "foo", resp.Resource.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, "foo", resp.Resource.Navigator().Dot("nickName").Current().Raw())
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"value": "foo@baz.com", "type": "work"},
+				}, scrubbed(resp.Resource.Navigator().Dot("emails").Current().Raw()))
+				assert.Equal(t, 1, resp.Resource.Navigator().Dot("phoneNumbers").Current().CountChildren())
+			},
+		},
+		{
+			name: "null attributes are cleared",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					Partial: s.partialOf(t, `
+{
+	"nickName": null,
+	"name": null,
+	"phoneNumbers": []
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Merged)
+				assert.True(t, resp.Resource.Navigator().Dot("nickName").Current().IsUnassigned())
+				assert.True(t, resp.Resource.Navigator().Dot("name").Current().IsUnassigned())
+				assert.True(t, resp.Resource.Navigator().Dot("phoneNumbers").Current().IsUnassigned())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("displayName").Current().Raw())
+			},
+		},
+		{
+			name: "readOnly attributes in partial are ignored",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					Partial: s.partialOf(t, `
+{
+	"id": "bar",
+	"groups": [
+		{
+			"value": "g1"
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.False(t, resp.Merged)
+				assert.Equal(t, "foo", resp.Ref.IdOrEmpty())
+			},
+		},
+		{
+			name: "partial resulting in no change",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					Partial:    s.partialOf(t, `{"userName": "foo", "name": {"familyName": "Bar"}}`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.False(t, resp.Merged)
+			},
+		},
+		{
+			name: "merge with delta response",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					Delta:      true,
+					Partial:    s.partialOf(t, `{"nickName": null, "timezone": "Asia/Tokyo"}`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Merged)
+
+				raw, err := scimjson.Serialize(resp.Resource, resp.Options...)
+				assert.Nil(t, err)
+
+				rendered := map[string]interface{}{}
+				assert.Nil(t, json.Unmarshal(raw, &rendered))
+				assert.Equal(t, "Asia/Tokyo", rendered["timezone"])
+				assert.Contains(t, rendered, "nickName")
+				assert.Nil(t, rendered["nickName"])
+				assert.NotContains(t, rendered, "userName")
+				assert.NotContains(t, rendered, "emails")
+			},
+		},
+		{
+			name: "merge patch document",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "foo",
+					PayloadSource: strings.NewReader(`
+{
+	"nickName": null,
+	"name": {
+		"givenName": "Foo",
+		"familyName": null
+	},
+	"emails": [
+		{
+			"value": "foo@baz.com"
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Merged)
+				assert.True(t, resp.Resource.Navigator().Dot("nickName").Current().IsUnassigned())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("name").Dot("givenName").Current().Raw())
+				assert.True(t, resp.Resource.Navigator().Dot("name").Dot("familyName").Current().IsUnassigned())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("displayName").Current().Raw())
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"value": "foo@baz.com"},
+				}, scrubbed(resp.Resource.Navigator().Dot("emails").Current().Raw()))
+			},
+		},
+		{
+			name: "merge patch document clearing a required attribute",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID:    "foo",
+					PayloadSource: strings.NewReader(`{"userName": null}`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidValue, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "merge patch document that is not an object",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID:    "foo",
+					PayloadSource: strings.NewReader(`["nickName"]`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidSyntax, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "merge patch document with unknown attribute",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID:    "foo",
+					PayloadSource: strings.NewReader(`{"foo": "bar"}`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrInvalidPath, errors.Unwrap(err))
+			},
+		},
+		{
+			name: "merge into non-existing resource",
+			getRequest: func(t *testing.T) *MergeRequest {
+				return &MergeRequest{
+					ResourceID: "bar",
+					Partial:    s.partialOf(t, `{"nickName": "bar"}`),
+				}
+			},
+			expect: func(t *testing.T, resp *MergeResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrNotFound, errors.Unwrap(err))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			database := db.Memory()
+			require.Nil(t, database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+				"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+				"id":          "foo",
+				"userName":    "foo",
+				"displayName": "Foo",
+				"nickName":    "foo",
+				"timezone":    "Asia/Shanghai",
+				"name": map[string]interface{}{
+					"givenName":  "F",
+					"familyName": "Bar",
+				},
+				"emails": []interface{}{
+					map[string]interface{}{
+						"value": "foo@bar.com",
+						"type":  "home",
+					},
+				},
+				"phoneNumbers": []interface{}{
+					map[string]interface{}{
+						"value": "123-45678",
+					},
+				},
+			})))
+			service := MergeService(s.config, database, nil, []filter.ByResource{
+				filter.ByPropertyToByResource(
+					filter.ReadOnlyFilter(),
+					filter.BCryptFilter(),
+				),
+				filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+				filter.MetaFilter(),
+			})
+			resp, err := service.Do(context.TODO(), test.getRequest(t))
+			test.expect(t, resp, err)
+		})
+	}
+}
+
+// scrubbed removes nil values from the maps of the raw value of a multiValued complex property.
+func scrubbed(raw interface{}) interface{} {
+	elements := make([]interface{}, 0)
+	for _, elem := range raw.([]interface{}) {
+		m := map[string]interface{}{}
+		for k, v := range elem.(map[string]interface{}) {
+			if v != nil {
+				m[k] = v
+			}
+		}
+		elements = append(elements, m)
+	}
+	return elements
+}
+
+func (s *MergeServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.Nil(t, r.Navigator().Replace(data).Error())
+	return r
+}
+
+func (s *MergeServiceTestSuite) partialOf(t *testing.T, raw string) *prop.Resource {
+	r := prop.NewResource(s.resourceType)
+	require.Nil(t, scimjson.Deserialize([]byte(raw), r))
+	return r
+}
+
+func (s *MergeServiceTestSuite) SetupSuite() {
+	for _, each := range []struct {
+		filepath  string
+		structure interface{}
+		post      func(parsed interface{})
+	}{
+		{
+			filepath:  "../../../public/schemas/core_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/schemas/user_schema.json",
+			structure: new(spec.Schema),
+			post: func(parsed interface{}) {
+				spec.Schemas().Register(parsed.(*spec.Schema))
+			},
+		},
+		{
+			filepath:  "../../../public/resource_types/user_resource_type.json",
+			structure: new(spec.ResourceType),
+			post: func(parsed interface{}) {
+				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
+			},
+		},
+	} {
+		f, err := os.Open(each.filepath)
+		require.Nil(s.T(), err)
+
+		raw, err := ioutil.ReadAll(f)
+		require.Nil(s.T(), err)
+
+		err = json.Unmarshal(raw, each.structure)
+		require.Nil(s.T(), err)
+
+		if each.post != nil {
+			each.post(each.structure)
+		}
+	}
+
+	s.config = new(spec.ServiceProviderConfig)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "patch": {
+    "supported": true
+  }
+}
+`), s.config))
+}
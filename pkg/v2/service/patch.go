@@ -54,12 +54,14 @@ type (
 		ResourceID    string                             // id of the resource to patch
 		MatchCriteria func(resource *prop.Resource) bool // extra criteria to meet for the resource to be patched
 		PayloadSource io.Reader                          // source to read the patch payload from
+		Delta         bool                               // true to render only the changed attributes in response
 	}
 	// Patch resource response
 	PatchResponse struct {
-		Patched  bool           // true if the resource was patched; false if the resource was not patched but there was no error
-		Ref      *prop.Resource // reference resource (the before state)
-		Resource *prop.Resource // patched resource (the after state)
+		Patched  bool               // true if the resource was patched; false if the resource was not patched but there was no error
+		Ref      *prop.Resource     // reference resource (the before state)
+		Resource *prop.Resource     // patched resource (the after state)
+		Options  []scimjson.Options // serialization options for the patched resource; restricts to changes when delta was requested
 	}
 )
 
@@ -83,13 +85,47 @@ func (s *patchService) Do(ctx context.Context, req *PatchRequest) (resp *PatchRe
 		return
 	}
 
-	resource, err := s.database.Get(ctx, req.ResourceID, nil)
+	return s.patch(ctx, req.ResourceID, req.MatchCriteria, req.Delta, func(resource *prop.Resource) error {
+		for _, patchOp := range patch.Operations {
+			switch strings.ToLower(patchOp.Op) {
+			case "add":
+				if valueToAdd, err := patchOp.ParseValue(resource); err != nil {
+					return err
+				} else if err := crud.Add(resource, patchOp.Path, valueToAdd); err != nil {
+					return err
+				}
+			case "replace":
+				if valueToReplace, err := patchOp.ParseValue(resource); err != nil {
+					return err
+				} else if err := crud.Replace(resource, patchOp.Path, valueToReplace); err != nil {
+					return err
+				}
+			case "remove":
+				if err := crud.Delete(resource, patchOp.Path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// patch fetches the resource, runs the filters around the modification, and saves the modified resource back to
+// database if it has changed. It is shared by the patch and merge services.
+func (s *patchService) patch(
+	ctx context.Context,
+	resourceID string,
+	matchCriteria func(resource *prop.Resource) bool,
+	delta bool,
+	modify func(resource *prop.Resource) error,
+) (resp *PatchResponse, err error) {
+	resource, err := s.database.Get(ctx, resourceID, nil)
 	if err != nil {
 		return
 	}
 
-	if s.config.ETag.Supported && req.MatchCriteria != nil {
-		if !req.MatchCriteria(resource) {
+	if s.config.ETag.Supported && matchCriteria != nil {
+		if !matchCriteria(resource) {
 			err = fmt.Errorf("%w: resource does not meet pre condition", spec.ErrConflict)
 			return
 		}
@@ -106,25 +142,8 @@ func (s *patchService) Do(ctx context.Context, req *PatchRequest) (resp *PatchRe
 		}
 	}
 
-	for _, patchOp := range patch.Operations {
-		switch strings.ToLower(patchOp.Op) {
-		case "add":
-			if valueToAdd, err := patchOp.ParseValue(resource); err != nil {
-				return nil, err
-			} else if err := crud.Add(resource, patchOp.Path, valueToAdd); err != nil {
-				return nil, err
-			}
-		case "replace":
-			if valueToReplace, err := patchOp.ParseValue(resource); err != nil {
-				return nil, err
-			} else if err := crud.Replace(resource, patchOp.Path, valueToReplace); err != nil {
-				return nil, err
-			}
-		case "remove":
-			if err := crud.Delete(resource, patchOp.Path); err != nil {
-				return nil, err
-			}
-		}
+	if err = modify(resource); err != nil {
+		return
 	}
 
 	for _, f := range s.postFilters {
@@ -153,6 +172,10 @@ func (s *patchService) Do(ctx context.Context, req *PatchRequest) (resp *PatchRe
 		Patched:  true,
 		Resource: resource,
 		Ref:      ref,
+		Options:  []scimjson.Options{},
+	}
+	if delta {
+		resp.Options = append(resp.Options, scimjson.Delta(ref))
 	}
 	return
 }
@@ -222,7 +245,7 @@ func (o *PatchOperation) ParseValue(resource *prop.Resource) (interface{}, error
 			if err != nil {
 				return nil, err
 			}
-			if head.IsPath() && head.Token() == resource.ResourceType().ID() {
+			if head.IsPath() && strings.EqualFold(head.Token(), resource.ResourceType().Schema().ID()) {
 				head = head.Next()
 			}
 		}
@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
+	scimjson "github.com/imulab/go-scim/pkg/v2/json"
 	"github.com/imulab/go-scim/pkg/v2/prop"
 	"github.com/imulab/go-scim/pkg/v2/service/filter"
 	"github.com/imulab/go-scim/pkg/v2/spec"
@@ -96,6 +98,189 @@ func (s *PatchServiceTestSuite) TestDo() {
 				assert.Equal(t, "work", resp.Resource.Navigator().Dot("emails").At(0).Dot("type").Current().Raw())
 			},
 		},
+		{
+			name: "patch add appends elements in order",
+			setup: func(t *testing.T) Patch {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"id":       "foo",
+					"userName": "foo",
+					"emails": []interface{}{
+						map[string]interface{}{
+							"value": "foo@bar.com",
+						},
+					},
+					"roles": []interface{}{
+						map[string]interface{}{"value": "b"},
+						map[string]interface{}{"value": "a"},
+					},
+				}))
+				require.Nil(t, err)
+				return PatchService(s.config, database, nil, []filter.ByResource{
+					filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+					filter.MetaFilter(),
+				})
+			},
+			getRequest: func() *PatchRequest {
+				return &PatchRequest{
+					ResourceID: "foo",
+					PayloadSource: strings.NewReader(`
+{
+	"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+	"Operations": [
+		{
+			"op": "add",
+			"path": "roles",
+			"value": [{"value": "d"}, {"value": "a"}, {"value": "c"}]
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *PatchResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Patched)
+				for i, value := range []string{"b", "a", "d", "c"} {
+					assert.Equal(t, value, resp.Resource.Navigator().Dot("roles").At(i).Dot("value").Current().Raw())
+				}
+				assert.Equal(t, 4, resp.Resource.Navigator().Dot("roles").Current().CountChildren())
+			},
+		},
+		{
+			name: "patch with delta response",
+			setup: func(t *testing.T) Patch {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"schemas":     []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"id":          "foo",
+					"userName":    "foo",
+					"displayName": "Foo",
+					"timezone":    "Asia/Shanghai",
+					"emails": []interface{}{
+						map[string]interface{}{
+							"value": "foo@bar.com",
+							"type":  "home",
+						},
+					},
+				}))
+				require.Nil(t, err)
+				return PatchService(s.config, database, nil, []filter.ByResource{
+					filter.ByPropertyToByResource(
+						filter.ReadOnlyFilter(),
+						filter.BCryptFilter(),
+					),
+					filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+					filter.MetaFilter(),
+				})
+			},
+			getRequest: func() *PatchRequest {
+				return &PatchRequest{
+					ResourceID: "foo",
+					Delta:      true,
+					PayloadSource: strings.NewReader(`
+{
+	"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+	"Operations": [
+		{
+			"op": "replace",
+			"path": "emails[value eq \"foo@bar.com\"].type",
+			"value": "work"
+		},
+		{
+			"op": "add",
+			"path": "password",
+			"value": "s3cret"
+		},
+		{
+			"op": "remove",
+			"path": "timezone"
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *PatchResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Patched)
+				assert.Len(t, resp.Options, 1)
+
+				raw, err := scimjson.Serialize(resp.Resource, resp.Options...)
+				assert.Nil(t, err)
+
+				rendered := map[string]interface{}{}
+				assert.Nil(t, json.Unmarshal(raw, &rendered))
+				assert.Equal(t, "foo", rendered["id"])
+				assert.NotNil(t, rendered["schemas"])
+				assert.NotNil(t, rendered["meta"])
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"value": "foo@bar.com", "type": "work"},
+				}, rendered["emails"])
+				assert.Contains(t, rendered, "timezone")
+				assert.Nil(t, rendered["timezone"])
+				assert.NotContains(t, rendered, "password")
+				assert.NotContains(t, rendered, "userName")
+				assert.NotContains(t, rendered, "displayName")
+			},
+		},
+		{
+			name: "patch with mixed case paths",
+			setup: func(t *testing.T) Patch {
+				database := db.Memory()
+				err := database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+					"schemas":  []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User"},
+					"id":       "foo",
+					"userName": "foo",
+					"emails": []interface{}{
+						map[string]interface{}{
+							"value": "foo@bar.com",
+							"type":  "home",
+						},
+					},
+				}))
+				require.Nil(t, err)
+				return PatchService(s.config, database, nil, []filter.ByResource{
+					filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+					filter.MetaFilter(),
+				})
+			},
+			getRequest: func() *PatchRequest {
+				return &PatchRequest{
+					ResourceID: "foo",
+					PayloadSource: strings.NewReader(`
+{
+	"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+	"Operations": [
+		{
+			"op": "replace",
+			"path": "USERNAME",
+			"value": "foobar"
+		},
+		{
+			"op": "replace",
+			"path": "Emails[VALUE eq \"foo@bar.com\"].Type",
+			"value": "work"
+		},
+		{
+			"op": "add",
+			"path": "URN:IETF:PARAMS:SCIM:SCHEMAS:CORE:2.0:User:NAME.FamilyName",
+			"value": "Bar"
+		}
+	]
+}
+`),
+				}
+			},
+			expect: func(t *testing.T, resp *PatchResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Patched)
+				assert.Equal(t, "foobar", resp.Resource.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, "work", resp.Resource.Navigator().Dot("emails").At(0).Dot("type").Current().Raw())
+				assert.Equal(t, "Bar", resp.Resource.Navigator().Dot("name").Dot("familyName").Current().Raw())
+			},
+		},
 		{
 			name: "patch to not make a difference",
 			setup: func(t *testing.T) Patch {
@@ -155,6 +340,101 @@ func (s *PatchServiceTestSuite) TestDo() {
 	}
 }
 
+func (s *PatchServiceTestSuite) TestDoEmptyName() {
+	tests := []struct {
+		name     string
+		payloads []string
+	}{
+		{
+			name: "remove each sub attribute in one request",
+			payloads: []string{`
+{
+	"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+	"Operations": [
+		{"op": "remove", "path": "name.givenName"},
+		{"op": "remove", "path": "name.familyName"},
+		{"op": "remove", "path": "name.formatted"}
+	]
+}
+`},
+		},
+		{
+			name: "remove each sub attribute in consecutive requests",
+			payloads: []string{
+				`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "remove", "path": "name.formatted"}]}`,
+				`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "remove", "path": "name.familyName"}]}`,
+				`{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "remove", "path": "name.givenName"}]}`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			r := prop.NewResource(s.resourceType)
+			require.Nil(t, scimjson.Deserialize([]byte(`
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "foo",
+  "name": {
+    "formatted": "David Q",
+    "givenName": "David",
+    "familyName": "Q"
+  },
+  "emails": [
+    {
+      "value": "foo@bar.com"
+    }
+  ]
+}
+`), r))
+			database := db.Memory()
+			require.Nil(t, database.Insert(context.TODO(), r))
+
+			service := PatchService(s.config, database, nil, []filter.ByResource{
+				filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+				filter.MetaFilter(),
+			})
+			var resp *PatchResponse
+			for _, payload := range test.payloads {
+				var err error
+				resp, err = service.Do(context.TODO(), &PatchRequest{
+					ResourceID:    "foo",
+					PayloadSource: strings.NewReader(payload),
+				})
+				require.Nil(t, err)
+				require.True(t, resp.Patched)
+			}
+			assert.True(t, resp.Resource.Navigator().Dot("name").Current().IsUnassigned())
+
+			for _, each := range []struct {
+				options []scimjson.Options
+				expect  func(t *testing.T, rendered map[string]interface{})
+			}{
+				{
+					expect: func(t *testing.T, rendered map[string]interface{}) {
+						assert.NotContains(t, rendered, "name")
+					},
+				},
+				{
+					options: []scimjson.Options{scimjson.PreserveEmpty()},
+					expect: func(t *testing.T, rendered map[string]interface{}) {
+						assert.Equal(t, map[string]interface{}{}, rendered["name"])
+						assert.NotContains(t, rendered, "addresses")
+					},
+				},
+			} {
+				raw, err := scimjson.Serialize(resp.Resource, each.options...)
+				require.Nil(t, err)
+				rendered := map[string]interface{}{}
+				require.Nil(t, json.Unmarshal(raw, &rendered))
+				assert.Equal(t, "foo", rendered["userName"])
+				each.expect(t, rendered)
+			}
+		})
+	}
+}
+
 func (s *PatchServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
 	r := prop.NewResource(s.resourceType)
 	require.Nil(t, r.Navigator().Replace(data).Error())
@@ -186,6 +466,7 @@ func (s *PatchServiceTestSuite) SetupSuite() {
 			structure: new(spec.ResourceType),
 			post: func(parsed interface{}) {
 				s.resourceType = parsed.(*spec.ResourceType)
+				crud.Register(s.resourceType)
 			},
 		},
 	} {
@@ -7,16 +7,56 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/crud/expr"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/json"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 )
 
 // QueryService returns a query resource service. This service is only capable of performing querying on a single type
 // of resource. This does not handle root query.
-func QueryService(config *spec.ServiceProviderConfig, database db.DB) Query {
-	return &queryService{
+//
+// The filter is compiled once, and validated against the resource type set by QueryWithResourceType, if any. When the
+// database implements db.CompiledQuery, the compiled filter is passed down to it, instead of being compiled again.
+//
+// The maximum page size is the filter.maxResults from config, if positive; count larger than it is capped to it, and
+// it is also applied when neither client nor QueryWithDefaultPageSize specifies count.
+//
+// The TotalResults of the response is the number of all resources matching the filter, counted by db.DB.Count
+// independently of the pagination, while Resources only holds the requested page.
+func QueryService(config *spec.ServiceProviderConfig, database db.DB, options ...QueryOption) Query {
+	s := &queryService{
 		database: database,
 		config:   config,
 	}
+	for _, opt := range options {
+		opt(s)
+	}
+	return s
+}
+
+// QueryWithDefaultPageSize returns a QueryOption to apply the defaultPageSize when client does not specify count. It
+// has no effect if defaultPageSize is not positive.
+func QueryWithDefaultPageSize(defaultPageSize int) QueryOption {
+	return func(s *queryService) {
+		s.defaultPageSize = defaultPageSize
+	}
+}
+
+// QueryWithResourceType returns a QueryOption to validate the attributes referenced by the filter against the resource
+// type (see expr.CompileFilterFor), so that a filter referencing an undefined attribute is rejected with
+// spec.ErrInvalidFilter, instead of yielding no results. Without this option, only the syntax of the filter is checked.
+func QueryWithResourceType(resourceType *spec.ResourceType) QueryOption {
+	return func(s *queryService) {
+		s.resourceType = resourceType
+	}
+}
+
+// QueryWithFilters returns a QueryOption to run the filters on each resource read from the database, before they are
+// returned (i.e. filter.MetaReconcileFilter). An error from the filters fails the request.
+func QueryWithFilters(filters ...filter.ByResource) QueryOption {
+	return func(s *queryService) {
+		s.filters = append(s.filters, filters...)
+	}
 }
 
 type (
@@ -24,6 +64,8 @@ type (
 	Query interface {
 		Do(ctx context.Context, req *QueryRequest) (resp *QueryResponse, err error)
 	}
+	// Option to customize the query resource service
+	QueryOption func(s *queryService)
 	// Query resource request
 	QueryRequest struct {
 		Filter     string
@@ -33,17 +75,20 @@ type (
 	}
 	// Query resource response
 	QueryResponse struct {
-		TotalResults int
-		StartIndex   int
-		ItemsPerPage int
+		TotalResults int // number of all resources matching the filter, regardless of pagination
+		StartIndex   int // 1-based index of the first resource on the page
+		ItemsPerPage int // number of resources on the page
 		Resources    []json.Serializable
 		Projection   *crud.Projection // included so that caller may render properly
 	}
 )
 
 type queryService struct {
-	database db.DB
-	config   *spec.ServiceProviderConfig
+	database        db.DB
+	config          *spec.ServiceProviderConfig
+	defaultPageSize int
+	resourceType    *spec.ResourceType
+	filters         []filter.ByResource
 }
 
 func (s *queryService) Do(ctx context.Context, req *QueryRequest) (resp *QueryResponse, err error) {
@@ -54,34 +99,37 @@ func (s *queryService) Do(ctx context.Context, req *QueryRequest) (resp *QueryRe
 	if err = req.ValidateAndDefault(); err != nil {
 		return
 	}
+	compiled, err := expr.CompileFilterFor(req.Filter, s.resourceType)
+	if err != nil {
+		return
+	}
+
+	pagination := s.pagination(req.Pagination)
 
 	resp = new(QueryResponse)
 	resp.Projection = req.Projection
-
-	if req.Pagination != nil {
-		resp.StartIndex = req.Pagination.StartIndex
+	resp.StartIndex = 1
+	if pagination != nil {
+		resp.StartIndex = pagination.StartIndex
 	}
 
-	if resp.TotalResults, err = s.database.Count(ctx, req.Filter); err != nil {
+	if resp.TotalResults, err = s.count(ctx, req.Filter, compiled); err != nil {
 		return
 	}
-	if req.Pagination != nil && req.Pagination.Count == 0 {
+	if pagination != nil && pagination.Count == 0 {
 		return
 	}
 
-	if s.config.Filter.MaxResults > 0 {
-		if (req.Pagination == nil && resp.TotalResults > s.config.Filter.MaxResults) ||
-			(req.Pagination != nil && req.Pagination.Count > s.config.Filter.MaxResults) {
-			err = spec.ErrTooMany
-			return
-		}
-	}
-
-	resources, err := s.database.Query(ctx, req.Filter, req.Sort, req.Pagination, req.Projection)
+	resources, err := s.query(ctx, req, compiled, pagination)
 	if err != nil {
 		return
 	}
 	for _, r := range resources {
+		for _, f := range s.filters {
+			if err = f.Filter(ctx, r); err != nil {
+				return
+			}
+		}
 		resp.Resources = append(resp.Resources, r)
 	}
 
@@ -89,6 +137,47 @@ func (s *queryService) Do(ctx context.Context, req *QueryRequest) (resp *QueryRe
 	return
 }
 
+// count counts the resources matching the filter, with its compiled form if the database implements db.CompiledQuery.
+func (s *queryService) count(ctx context.Context, filter string, compiled *expr.Expression) (int, error) {
+	if database, ok := s.database.(db.CompiledQuery); ok {
+		return database.CountCompiled(ctx, compiled)
+	}
+	return s.database.Count(ctx, filter)
+}
+
+// query queries the requested page of resources, with the compiled filter if the database implements
+// db.CompiledQuery.
+func (s *queryService) query(ctx context.Context, req *QueryRequest, compiled *expr.Expression, pagination *crud.Pagination) ([]*prop.Resource, error) {
+	if database, ok := s.database.(db.CompiledQuery); ok {
+		return database.QueryCompiled(ctx, compiled, req.Sort, pagination, req.Projection)
+	}
+	return s.database.Query(ctx, req.Filter, req.Sort, pagination, req.Projection)
+}
+
+// pagination returns the effective pagination after applying the default and maximum page size. The returned
+// pagination may be nil when no page size is in effect, or have a CountUnspecified count when client only specifies
+// the startIndex.
+func (s *queryService) pagination(requested *crud.Pagination) *crud.Pagination {
+	var p *crud.Pagination
+	if requested != nil {
+		p = &crud.Pagination{StartIndex: requested.StartIndex, Count: requested.Count}
+	} else {
+		p = &crud.Pagination{StartIndex: 1, Count: crud.CountUnspecified}
+	}
+
+	if p.Count == crud.CountUnspecified && s.defaultPageSize > 0 {
+		p.Count = s.defaultPageSize
+	}
+	if max := s.config.Filter.MaxResults; max > 0 && (p.Count == crud.CountUnspecified || p.Count > max) {
+		p.Count = max
+	}
+
+	if requested == nil && p.Count == crud.CountUnspecified {
+		return nil
+	}
+	return p
+}
+
 func (s *queryService) checkSupport(request *QueryRequest) error {
 	if !s.config.Filter.Supported {
 		if len(request.Filter) > 0 {
@@ -105,13 +194,12 @@ func (s *queryService) checkSupport(request *QueryRequest) error {
 	return nil
 }
 
+// ValidateAndDefault validates the sort and projection of the request, and applies the defaults to the filter,
+// pagination and sort. The filter is not compiled here, but by the query service, which also validates it against the
+// resource type set by QueryWithResourceType.
 func (q *QueryRequest) ValidateAndDefault() error {
 	if len(q.Filter) == 0 {
 		q.Filter = "id pr"
-	} else {
-		if _, err := expr.CompileFilter(q.Filter); err != nil {
-			return err
-		}
 	}
 	if q.Pagination != nil {
 		if q.Pagination.StartIndex <= 0 {
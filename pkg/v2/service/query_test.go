@@ -3,9 +3,12 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/imulab/go-scim/pkg/v2/crud"
 	"github.com/imulab/go-scim/pkg/v2/db"
 	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -90,6 +93,36 @@ func (s *QueryServiceTestSuite) TestDo() {
 				assert.Equal(t, "user003", resp.Resources[0].(*prop.Resource).Navigator().Dot("id").Current().Raw())
 			},
 		},
+		{
+			name: "query reconciles meta of imported resources",
+			setup: func(t *testing.T) Query {
+				database := db.Memory()
+				for _, userData := range []interface{}{
+					map[string]interface{}{"id": "user001", "userName": "user001"},
+					map[string]interface{}{"id": "user002", "userName": "user002", "meta": map[string]interface{}{
+						"resourceType": "Group",
+						"location":     "https://stale.example.com/Users/user002",
+					}},
+				} {
+					require.Nil(t, database.Insert(context.TODO(), s.resourceOf(t, userData)))
+				}
+				return QueryService(s.config, database, QueryWithFilters(filter.MetaReconcileFilter(nil)))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter: "id pr",
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				require.Len(t, resp.Resources, 2)
+				for _, r := range resp.Resources {
+					resource := r.(*prop.Resource)
+					assert.Equal(t, "User", resource.Navigator().Dot("meta").Dot("resourceType").Current().Raw())
+					assert.Equal(t, "/Users/"+resource.IdOrEmpty(), resource.MetaLocationOrEmpty())
+				}
+			},
+		},
 		{
 			name: "sort",
 			setup: func(t *testing.T) Query {
@@ -160,6 +193,175 @@ func (s *QueryServiceTestSuite) TestDo() {
 				}
 			},
 		},
+		{
+			name: "total results counts all matches beyond the page",
+			setup: func(t *testing.T) Query {
+				database := db.Memory()
+				for i := 0; i < 12; i++ {
+					userName := fmt.Sprintf("match%02d", i)
+					if i >= 10 {
+						userName = fmt.Sprintf("other%02d", i)
+					}
+					require.Nil(t, database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+						"id":       fmt.Sprintf("user%02d", i),
+						"userName": userName,
+					})))
+				}
+				return QueryService(s.config, database)
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter:     `userName sw "match"`,
+					Sort:       &crud.Sort{By: "userName", Order: crud.SortAsc},
+					Pagination: &crud.Pagination{StartIndex: 3, Count: 2},
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 10, resp.TotalResults)
+				assert.Equal(t, 3, resp.StartIndex)
+				assert.Equal(t, 2, resp.ItemsPerPage)
+				for i, expected := range []string{"user02", "user03"} {
+					assert.Equal(t, expected, resp.Resources[i].(*prop.Resource).Navigator().Dot("id").Current().Raw())
+				}
+			},
+		},
+		{
+			name: "filter referencing undefined attribute is rejected",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, s.sortedUsers(t), QueryWithResourceType(s.resourceType))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{Filter: `nonExistentAttr eq "x"`}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Equal(t, spec.ErrInvalidFilter, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "nonExistentAttr")
+			},
+		},
+		{
+			name: "filter referencing undefined attribute matches nothing without resource type",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, s.sortedUsers(t))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{Filter: `nonExistentAttr eq "x"`}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 0, resp.TotalResults)
+			},
+		},
+		{
+			name: "database without compiled query is queried with the filter",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, struct{ db.DB }{s.sortedUsers(t)}, QueryWithResourceType(s.resourceType))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{Filter: `id sw "user00"`}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Len(t, resp.Resources, 5)
+			},
+		},
+		{
+			name: "default page size applied when count is unspecified",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, s.sortedUsers(t), QueryWithDefaultPageSize(2))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter: "userName pr",
+					Sort:   &crud.Sort{By: "userName", Order: crud.SortAsc},
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Equal(t, 1, resp.StartIndex)
+				assert.Equal(t, 2, resp.ItemsPerPage)
+				assert.Len(t, resp.Resources, 2)
+			},
+		},
+		{
+			name: "default page size applied when only startIndex is specified",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, s.sortedUsers(t), QueryWithDefaultPageSize(2))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter:     "userName pr",
+					Sort:       &crud.Sort{By: "userName", Order: crud.SortAsc},
+					Pagination: &crud.Pagination{StartIndex: 3, Count: crud.CountUnspecified},
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Equal(t, 3, resp.StartIndex)
+				assert.Equal(t, 2, resp.ItemsPerPage)
+				for i, expected := range []string{"user003", "user004"} {
+					assert.Equal(t, expected, resp.Resources[i].(*prop.Resource).Navigator().Dot("id").Current().Raw())
+				}
+			},
+		},
+		{
+			name: "count capped to max page size",
+			setup: func(t *testing.T) Query {
+				config := *s.config
+				config.Filter.MaxResults = 3
+				return QueryService(&config, s.sortedUsers(t))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter:     "userName pr",
+					Pagination: &crud.Pagination{StartIndex: 1, Count: 10},
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Equal(t, 3, resp.ItemsPerPage)
+				assert.Len(t, resp.Resources, 3)
+			},
+		},
+		{
+			name: "max page size applied when count is unspecified and there is no default",
+			setup: func(t *testing.T) Query {
+				config := *s.config
+				config.Filter.MaxResults = 4
+				return QueryService(&config, s.sortedUsers(t))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{Filter: "userName pr"}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Equal(t, 4, resp.ItemsPerPage)
+			},
+		},
+		{
+			name: "items per page reflects the resources returned on the last page",
+			setup: func(t *testing.T) Query {
+				return QueryService(s.config, s.sortedUsers(t))
+			},
+			getRequest: func() *QueryRequest {
+				return &QueryRequest{
+					Filter:     "userName pr",
+					Pagination: &crud.Pagination{StartIndex: 4, Count: 3},
+				}
+			},
+			expect: func(t *testing.T, resp *QueryResponse, err error) {
+				assert.Nil(t, err)
+				assert.Equal(t, 5, resp.TotalResults)
+				assert.Equal(t, 4, resp.StartIndex)
+				assert.Equal(t, 2, resp.ItemsPerPage)
+				assert.Len(t, resp.Resources, 2)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -171,6 +373,18 @@ func (s *QueryServiceTestSuite) TestDo() {
 	}
 }
 
+// sortedUsers returns a database with five users, whose id and userName are user001 to user005.
+func (s *QueryServiceTestSuite) sortedUsers(t *testing.T) db.DB {
+	database := db.Memory()
+	for _, id := range []string{"user001", "user002", "user003", "user004", "user005"} {
+		require.Nil(t, database.Insert(context.TODO(), s.resourceOf(t, map[string]interface{}{
+			"id":       id,
+			"userName": id,
+		})))
+	}
+	return database
+}
+
 func (s *QueryServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
 	r := prop.NewResource(s.resourceType)
 	require.Nil(t, r.Navigator().Replace(data).Error())
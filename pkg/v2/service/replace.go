@@ -10,9 +10,26 @@ import (
 	"github.com/imulab/go-scim/pkg/v2/spec"
 	"io"
 	"io/ioutil"
+	"strings"
 )
 
 // ReplaceService returns a replace service.
+//
+// By default, replace is a full replacement: attributes absent from the replacement payload are cleared. When the
+// request carries a Scope, which the client has to opt in to explicitly (see handlerutil.ReplaceRequest), the
+// replacement is scoped to the attributes the client was shown (i.e. the attributes parameter of the GET the
+// representation was fetched with): an attribute covered by one of the scoped paths is replaced by that of the payload,
+// or cleared if absent from the payload, while all other attributes of the stored resource are preserved and those
+// present in the payload are ignored. A sub attribute path (i.e. name.givenName) scopes the replacement down to the sub
+// attribute of a singular complex attribute, but scopes a multiValued attribute (i.e. emails.value) as a whole, since
+// its elements cannot be told apart reliably. Paths may be prefixed by the main schema id, and attributes of a schema
+// extension must be prefixed by the extension schema id.
+//
+// The scoped replacement is composed before the filters run, hence the SCIM rules apply in the same way as a full
+// replacement of the composed resource: readOnly attributes are reset or copied from the stored resource, even if they
+// are scoped; immutable attributes that are scoped must retain their stored value, whereas those outside the scope are
+// preserved and never fail the mutability check. Likewise, writeOnly attributes (i.e. password), which are never shown
+// to the client, are preserved unless scoped.
 func ReplaceService(
 	config *spec.ServiceProviderConfig,
 	resourceType *spec.ResourceType,
@@ -37,6 +54,7 @@ type (
 		ResourceID    string                             // id of the resource to be replaced
 		PayloadSource io.Reader                          // source to read replacement payload from
 		MatchCriteria func(resource *prop.Resource) bool // extra criteria to meet in order to be replaced
+		Scope         []string                           // attributes the client was shown; if not empty, only these attributes are replaced
 	}
 	// Replace resource response
 	ReplaceResponse struct {
@@ -71,6 +89,12 @@ func (s *replaceService) Do(ctx context.Context, req *ReplaceRequest) (resp *Rep
 		return
 	}
 
+	if len(req.Scope) > 0 {
+		if replacement, err = s.scoped(ref, replacement, req.Scope); err != nil {
+			return
+		}
+	}
+
 	for _, f := range s.filters {
 		if err = f.FilterRef(ctx, replacement, ref); err != nil {
 			return
@@ -118,3 +142,59 @@ func (s *replaceService) parseResource(req *ReplaceRequest) (*prop.Resource, err
 
 	return resource, nil
 }
+
+// scoped returns a copy of the reference resource, whose attributes covered by the scope are replaced by those of the
+// replacement resource.
+func (s *replaceService) scoped(ref *prop.Resource, replacement *prop.Resource, scope []string) (*prop.Resource, error) {
+	paths := make([]string, 0, len(scope))
+	for _, path := range scope {
+		if path = strings.TrimSpace(path); len(path) > 0 {
+			paths = append(paths, strings.TrimPrefix(strings.ToLower(path), strings.ToLower(s.resourceType.Schema().ID()+":")))
+		}
+	}
+
+	resource := ref.Clone()
+	if err := replaceScoped(resource.Navigator(), replacement.RootProperty(), paths); err != nil {
+		return nil, err
+	}
+	return resource, nil
+}
+
+// replaceScoped replaces the children of the navigator's current property with the corresponding children of the
+// source property, when they are covered by the paths. Singular complex children containing some of the paths are
+// replaced recursively.
+func replaceScoped(nav prop.Navigator, source prop.Property, paths []string) error {
+	return source.ForEachChild(func(_ int, child prop.Property) error {
+		var (
+			attr = child.Attribute()
+			path = strings.ToLower(attr.Path())
+		)
+
+		var scoped, recurse bool
+		for _, each := range paths {
+			if spec.PathCovers(each, path) {
+				scoped = true
+				break
+			}
+			if spec.PathCovers(path, each) && attr.Type() == spec.TypeComplex {
+				recurse = true
+			}
+		}
+		if !scoped && !recurse {
+			return nil
+		}
+
+		if nav.Dot(attr.Name()).HasError() {
+			return nav.Error()
+		}
+		defer nav.Retract()
+
+		if scoped || attr.MultiValued() {
+			if child.IsUnassigned() {
+				return nav.Delete().Error()
+			}
+			return nav.Replace(child.Raw()).Error()
+		}
+		return replaceScoped(nav, child, paths)
+	})
+}
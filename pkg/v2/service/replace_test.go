@@ -24,7 +24,8 @@ func TestReplaceService(t *testing.T) {
 
 type ReplaceServiceTestSuite struct {
 	suite.Suite
-	resourceType *spec.ResourceType
+	resourceType       *spec.ResourceType
+	scopedResourceType *spec.ResourceType
 }
 
 func (s *ReplaceServiceTestSuite) TestDo() {
@@ -183,6 +184,209 @@ func (s *ReplaceServiceTestSuite) TestDo() {
 	}
 }
 
+func (s *ReplaceServiceTestSuite) TestDoScoped() {
+	const stored = `
+{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:test:Scoped"
+  ],
+  "id": "foo",
+  "userName": "foo",
+  "displayName": "Foo",
+  "name": {
+    "givenName": "David",
+    "familyName": "Q"
+  },
+  "emails": [
+    {
+      "value": "foo@bar.com"
+    }
+  ],
+  "groups": [
+    {
+      "value": "g1"
+    }
+  ],
+  "urn:test:Scoped": {
+    "employeeId": "E1"
+  }
+}
+`
+	tests := []struct {
+		name    string
+		scope   []string
+		payload string
+		expect  func(t *testing.T, resp *ReplaceResponse, err error)
+	}{
+		{
+			name:  "unscoped attributes are preserved",
+			scope: []string{"userName", "emails"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "bar",
+  "emails": [
+    {
+      "value": "bar@bar.com"
+    }
+  ]
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				nav := resp.Resource.Navigator()
+				assert.Equal(t, "bar", nav.Dot("userName").Current().Raw())
+				assert.Equal(t, "bar@bar.com", nav.Retract().Dot("emails").At(0).Dot("value").Current().Raw())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("displayName").Current().Raw())
+				assert.Equal(t, "Q", resp.Resource.Navigator().Dot("name").Dot("familyName").Current().Raw())
+				assert.Equal(t, "E1", resp.Resource.Navigator().Dot("urn:test:Scoped").Dot("employeeId").Current().Raw())
+				assert.Equal(t, []interface{}{"urn:ietf:params:scim:schemas:core:2.0:User", "urn:test:Scoped"},
+					resp.Resource.Navigator().Dot("schemas").Current().Raw())
+			},
+		},
+		{
+			name:  "attributes outside of scope in payload are ignored",
+			scope: []string{"urn:ietf:params:scim:schemas:core:2.0:User:userName"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "bar",
+  "displayName": "Bar"
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				assert.Equal(t, "bar", resp.Resource.Navigator().Dot("userName").Current().Raw())
+				assert.Equal(t, "Foo", resp.Resource.Navigator().Dot("displayName").Current().Raw())
+			},
+		},
+		{
+			name:  "scoped attributes absent from payload are cleared",
+			scope: []string{"userName", "displayName"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "foo"
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				assert.True(t, resp.Resource.Navigator().Dot("displayName").Current().IsUnassigned())
+				assert.Equal(t, "David", resp.Resource.Navigator().Dot("name").Dot("givenName").Current().Raw())
+			},
+		},
+		{
+			name:  "sub attribute scope preserves siblings",
+			scope: []string{"userName", "name.givenName"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "foo",
+  "name": {
+    "givenName": "Jon"
+  }
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				assert.Equal(t, "Jon", resp.Resource.Navigator().Dot("name").Dot("givenName").Current().Raw())
+				assert.Equal(t, "Q", resp.Resource.Navigator().Dot("name").Dot("familyName").Current().Raw())
+			},
+		},
+		{
+			name:  "scoped readOnly attribute is copied from stored resource",
+			scope: []string{"userName", "groups"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "bar",
+  "groups": [
+    {
+      "value": "g2"
+    }
+  ]
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				assert.Equal(t, "g1", resp.Resource.Navigator().Dot("groups").At(0).Dot("value").Current().Raw())
+			},
+		},
+		{
+			name:  "unscoped immutable attribute is preserved",
+			scope: []string{"userName"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "id": "foo",
+  "userName": "bar"
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.Nil(t, err)
+				assert.True(t, resp.Replaced)
+				assert.Equal(t, "E1", resp.Resource.Navigator().Dot("urn:test:Scoped").Dot("employeeId").Current().Raw())
+			},
+		},
+		{
+			name:  "scoped immutable attribute cannot be changed",
+			scope: []string{"userName", "urn:test:Scoped:employeeId"},
+			payload: `
+{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:test:Scoped"],
+  "id": "foo",
+  "userName": "foo",
+  "urn:test:Scoped": {
+    "employeeId": "E2"
+  }
+}
+`,
+			expect: func(t *testing.T, resp *ReplaceResponse, err error) {
+				assert.NotNil(t, err)
+				assert.Equal(t, spec.ErrMutability, errors.Unwrap(err))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			var data map[string]interface{}
+			require.Nil(t, json.Unmarshal([]byte(stored), &data))
+			ref := prop.NewResource(s.scopedResourceType)
+			require.Nil(t, ref.Navigator().Replace(data).Error())
+
+			database := db.Memory()
+			require.Nil(t, database.Insert(context.TODO(), ref))
+
+			service := ReplaceService(&spec.ServiceProviderConfig{}, s.scopedResourceType, database, []filter.ByResource{
+				filter.ByPropertyToByResource(
+					filter.ReadOnlyFilter(),
+					filter.BCryptFilter(),
+				),
+				filter.ByPropertyToByResource(filter.ValidationFilter(database)),
+				filter.MetaFilter(),
+			})
+			resp, err := service.Do(context.TODO(), &ReplaceRequest{
+				ResourceID:    "foo",
+				PayloadSource: strings.NewReader(test.payload),
+				Scope:         test.scope,
+			})
+			test.expect(t, resp, err)
+		})
+	}
+}
+
 func (s *ReplaceServiceTestSuite) resourceOf(t *testing.T, data interface{}) *prop.Resource {
 	r := prop.NewResource(s.resourceType)
 	require.Nil(t, r.Navigator().Replace(data).Error())
@@ -230,4 +434,39 @@ func (s *ReplaceServiceTestSuite) SetupSuite() {
 			each.post(each.structure)
 		}
 	}
+
+	extension := new(spec.Schema)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "urn:test:Scoped",
+  "name": "Scoped",
+  "attributes": [
+    {
+      "id": "urn:test:Scoped:employeeId",
+      "name": "employeeId",
+      "type": "string",
+      "mutability": "immutable",
+      "_index": 0,
+      "_path": "urn:test:Scoped:employeeId"
+    }
+  ]
+}
+`), extension))
+	spec.Schemas().Register(extension)
+
+	s.scopedResourceType = new(spec.ResourceType)
+	require.Nil(s.T(), json.Unmarshal([]byte(`
+{
+  "id": "User",
+  "name": "User",
+  "endpoint": "/Users",
+  "schema": "urn:ietf:params:scim:schemas:core:2.0:User",
+  "schemaExtensions": [
+    {
+      "schema": "urn:test:Scoped",
+      "required": false
+    }
+  ]
+}
+`), s.scopedResourceType))
 }
@@ -226,6 +226,16 @@ func (attr *Attribute) GoesBy(name string) bool {
 	}
 }
 
+// PathCovers returns true if the lower cased attribute path is the same as, or the path of an ancestor of, the other
+// lower cased attribute path. Attribute names are delimited by a period, and the path of an attribute of a schema
+// extension is delimited from the extension's schema URN by a colon.
+func PathCovers(path string, other string) bool {
+	if !strings.HasPrefix(other, path) {
+		return false
+	}
+	return len(other) == len(path) || other[len(path)] == '.' || other[len(path)] == ':'
+}
+
 // DFS perform a depth-first-traversal on the given attribute and invokes callback
 func (attr *Attribute) DFS(callback func(attr *Attribute)) {
 	callback(attr)
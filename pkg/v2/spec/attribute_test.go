@@ -186,6 +186,27 @@ func (s *AttributeTestSuite) TestGoesBy() {
 	}
 }
 
+func (s *AttributeTestSuite) TestPathCovers() {
+	tests := []struct {
+		name   string
+		path   string
+		other  string
+		expect bool
+	}{
+		{name: "same path", path: "name.givenname", other: "name.givenname", expect: true},
+		{name: "parent path", path: "name", other: "name.givenname", expect: true},
+		{name: "extension schema", path: "urn:test:user", other: "urn:test:user:department", expect: true},
+		{name: "sub path", path: "name.givenname", other: "name", expect: false},
+		{name: "common prefix", path: "name", other: "nameprefix", expect: false},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expect, PathCovers(test.path, test.other))
+		})
+	}
+}
+
 func (s *AttributeTestSuite) TestDeriveElementAttribute() {
 	raw := `
 {
@@ -0,0 +1,55 @@
+package spec
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	deprecationReg          *deprecationRegistry
+	deprecationRegistryOnce sync.Once
+)
+
+type deprecationRegistry struct {
+	sync.RWMutex
+	db map[string]struct{}
+}
+
+// Register marks the attribute identified by the attribute id (i.e. urn:ietf:params:scim:schemas:core:2.0:User:nickName)
+// as deprecated. Attribute ids are matched case insensitively. Deprecated attributes are still serialized and validated
+// normally; the mark only serves to inform clients that the attribute is subject to removal.
+func (r *deprecationRegistry) Register(attributeId string) {
+	r.Lock()
+	defer r.Unlock()
+	r.db[strings.ToLower(attributeId)] = struct{}{}
+}
+
+// Unregister removes the deprecation mark of the attribute identified by the attribute id, if any.
+func (r *deprecationRegistry) Unregister(attributeId string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.db, strings.ToLower(attributeId))
+}
+
+// IsDeprecated returns true if the attribute identified by the attribute id was marked as deprecated.
+func (r *deprecationRegistry) IsDeprecated(attributeId string) bool {
+	r.RLock()
+	defer r.RUnlock()
+	_, ok := r.db[strings.ToLower(attributeId)]
+	return ok
+}
+
+// Deprecations return the deprecation registry, which marks attributes as deprecated. Marks are kept separately from
+// the schemas, so that attributes of standard schemas can be deprecated without modifying the schema definitions.
+func Deprecations() *deprecationRegistry {
+	deprecationRegistryOnce.Do(func() {
+		deprecationReg = &deprecationRegistry{db: map[string]struct{}{}}
+	})
+	return deprecationReg
+}
+
+// Deprecated returns true if this attribute was marked as deprecated in the Deprecations registry. Element attributes
+// derived from a deprecated multiValued attribute are not marked themselves.
+func (attr *Attribute) Deprecated() bool {
+	return Deprecations().IsDeprecated(attr.id)
+}
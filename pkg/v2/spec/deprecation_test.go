@@ -0,0 +1,23 @@
+package spec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeprecations(t *testing.T) {
+	attr := &Attribute{id: "urn:ietf:params:scim:schemas:core:2.0:User:nickName", name: "nickName", typ: TypeString}
+	elem := (&Attribute{id: "urn:ietf:params:scim:schemas:core:2.0:User:emails", name: "emails", typ: TypeString, multiValued: true}).DeriveElementAttribute()
+
+	assert.False(t, attr.Deprecated())
+
+	Deprecations().Register("urn:ietf:params:scim:schemas:core:2.0:User:NICKNAME")
+	Deprecations().Register("urn:ietf:params:scim:schemas:core:2.0:User:emails")
+	defer Deprecations().Unregister("urn:ietf:params:scim:schemas:core:2.0:User:emails")
+	assert.True(t, attr.Deprecated())
+	assert.True(t, Deprecations().IsDeprecated("urn:ietf:params:scim:schemas:core:2.0:User:emails"))
+	assert.False(t, elem.Deprecated())
+
+	Deprecations().Unregister(attr.ID())
+	assert.False(t, attr.Deprecated())
+}
@@ -1,38 +1,72 @@
 package spec
 
+import "fmt"
+
+// ScimType is the detail error keyword of a SCIM error, as enumerated in RFC7644 Section 3.12.
+type ScimType string
+
+// SCIM detail error keywords defined in RFC7644 Section 3.12
+const (
+	ScimTypeInvalidFilter ScimType = "invalidFilter"
+	ScimTypeTooMany       ScimType = "tooMany"
+	ScimTypeUniqueness    ScimType = "uniqueness"
+	ScimTypeMutability    ScimType = "mutability"
+	ScimTypeInvalidSyntax ScimType = "invalidSyntax"
+	ScimTypeInvalidPath   ScimType = "invalidPath"
+	ScimTypeNoTarget      ScimType = "noTarget"
+	ScimTypeInvalidValue  ScimType = "invalidValue"
+	ScimTypeInvalidVers   ScimType = "invalidVers"
+	ScimTypeSensitive     ScimType = "sensitive"
+)
+
+// Valid returns true if the ScimType is one of the detail error keywords enumerated in RFC7644 Section 3.12.
+func (t ScimType) Valid() bool {
+	switch t {
+	case ScimTypeInvalidFilter, ScimTypeTooMany, ScimTypeUniqueness, ScimTypeMutability, ScimTypeInvalidSyntax,
+		ScimTypeInvalidPath, ScimTypeNoTarget, ScimTypeInvalidValue, ScimTypeInvalidVers, ScimTypeSensitive:
+		return true
+	default:
+		return false
+	}
+}
+
 // Error prototypes
 var (
 	// The specified filter syntax was invalid, or the specified attribute and filter comparison combination is not supported.
-	ErrInvalidFilter = &Error{Status: 400, Type: "invalidFilter"}
+	ErrInvalidFilter = &Error{Status: 400, Type: string(ScimTypeInvalidFilter)}
 
 	// The specified filter yields many more results than the server is willing to calculate or process.
-	ErrTooMany = &Error{Status: 400, Type: "tooMany"}
+	ErrTooMany = &Error{Status: 400, Type: string(ScimTypeTooMany)}
 
-	// One or more of the attribute values are already in use or are reserved.
-	ErrUniqueness = &Error{Status: 400, Type: "uniqueness"}
+	// One or more of the attribute values are already in use or are reserved. As defined in RFC7644 Section 3.12, it
+	// is responded with 409 (Conflict).
+	ErrUniqueness = &Error{Status: 409, Type: string(ScimTypeUniqueness)}
 
 	// The attempted modification is not compatible with the target attribute's mutability or current state (e.g.,
 	// modification of an "immutable" attribute with an existing value).
-	ErrMutability = &Error{Status: 400, Type: "mutability"}
+	ErrMutability = &Error{Status: 400, Type: string(ScimTypeMutability)}
 
 	// The request body message structure was invalid or did not conform to the request schema.
-	ErrInvalidSyntax = &Error{Status: 400, Type: "invalidSyntax"}
+	ErrInvalidSyntax = &Error{Status: 400, Type: string(ScimTypeInvalidSyntax)}
 
 	// The "path" attribute was invalid or malformed.
-	ErrInvalidPath = &Error{Status: 400, Type: "invalidPath"}
+	ErrInvalidPath = &Error{Status: 400, Type: string(ScimTypeInvalidPath)}
 
 	// The specified "path" did not yield an attribute or attribute value that could be operated on. This occurs when
 	// the specified "path" value contains a filter that yields no match.
-	ErrNoTarget = &Error{Status: 400, Type: "noTarget"}
+	ErrNoTarget = &Error{Status: 400, Type: string(ScimTypeNoTarget)}
 
 	// A required value was missing, or the value specified was not compatible with the operation or attribute type.
-	ErrInvalidValue = &Error{Status: 400, Type: "invalidValue"}
+	ErrInvalidValue = &Error{Status: 400, Type: string(ScimTypeInvalidValue)}
+
+	// The specified SCIM protocol version is not supported.
+	ErrInvalidVers = &Error{Status: 400, Type: string(ScimTypeInvalidVers)}
 
 	// The resource was not found from persistence store.
 	ErrNotFound = &Error{Status: 404, Type: "notFound"}
 
 	// The specified request cannot be completed, due to the passing of sensitive information in a request URI.
-	ErrSensitive = &Error{Status: 400, Type: "sensitive"}
+	ErrSensitive = &Error{Status: 400, Type: string(ScimTypeSensitive)}
 
 	// The resource is in conflict with some pre conditions.
 	ErrConflict = &Error{Status: 412, Type: "conflict"}
@@ -44,14 +78,108 @@ var (
 // A SCIM error message.
 // The structure is left completely open for convenience, but it is not recommended to create Error directly.
 // To create an error, use the error prototypes (i.e. ErrInvalidFilter). If needed, wrap the error prototype
-// by fmt.Errorf("additional detail: %w", err).
+// by fmt.Errorf("additional detail: %w", err), or use the constructors (i.e. NewInvalidValue) which carry the detail
+// in the error itself. Non-standard errors can be defined by creating new prototypes with the desired status and type.
+//
+// Extras carries structured information (i.e. a request id, or a documentation URL) to be rendered together with the
+// error. Use WithExtras to obtain a copy of the prototype carrying the extras, as prototypes are shared.
 type Error struct {
 	Status int
 	Type   string
+	Detail string
+	Extras map[string]interface{}
 }
 
 func (s Error) Error() string {
-	return s.Type
+	if len(s.Detail) == 0 {
+		return s.Type
+	}
+	return s.Type + ": " + s.Detail
+}
+
+// NewError returns an error of the status and SCIM detail error keyword, carrying the detail. An error wrapping
+// ErrInternal is returned instead if scimType is not one of the keywords enumerated in RFC7644 Section 3.12. The
+// returned error matches the error prototype of the same status and type in errors.Is. Prefer the constructors of
+// specific types (i.e. NewInvalidValue), which also set the status defined for the type.
+func NewError(status int, scimType ScimType, detail string) (*Error, error) {
+	if !scimType.Valid() {
+		return nil, fmt.Errorf("%w: invalid scimType '%s'", ErrInternal, scimType)
+	}
+	return newError(status, scimType, detail), nil
+}
+
+// newError returns an error of the status and the enumerated SCIM detail error keyword, carrying the detail.
+func newError(status int, scimType ScimType, detail string) *Error {
+	return &Error{Status: status, Type: string(scimType), Detail: detail}
+}
+
+// NewInvalidFilter returns an invalidFilter error carrying the detail. It matches ErrInvalidFilter in errors.Is.
+func NewInvalidFilter(detail string) *Error {
+	return newError(ErrInvalidFilter.Status, ScimTypeInvalidFilter, detail)
+}
+
+// NewTooMany returns a tooMany error carrying the detail. It matches ErrTooMany in errors.Is.
+func NewTooMany(detail string) *Error {
+	return newError(ErrTooMany.Status, ScimTypeTooMany, detail)
+}
+
+// NewUniqueness returns a uniqueness error carrying the detail. It matches ErrUniqueness in errors.Is.
+func NewUniqueness(detail string) *Error {
+	return newError(ErrUniqueness.Status, ScimTypeUniqueness, detail)
+}
+
+// NewMutability returns a mutability error carrying the detail. It matches ErrMutability in errors.Is.
+func NewMutability(detail string) *Error {
+	return newError(ErrMutability.Status, ScimTypeMutability, detail)
+}
+
+// NewInvalidSyntax returns an invalidSyntax error carrying the detail. It matches ErrInvalidSyntax in errors.Is.
+func NewInvalidSyntax(detail string) *Error {
+	return newError(ErrInvalidSyntax.Status, ScimTypeInvalidSyntax, detail)
+}
+
+// NewInvalidPath returns an invalidPath error carrying the detail. It matches ErrInvalidPath in errors.Is.
+func NewInvalidPath(detail string) *Error {
+	return newError(ErrInvalidPath.Status, ScimTypeInvalidPath, detail)
+}
+
+// NewNoTarget returns a noTarget error carrying the detail. It matches ErrNoTarget in errors.Is.
+func NewNoTarget(detail string) *Error {
+	return newError(ErrNoTarget.Status, ScimTypeNoTarget, detail)
+}
+
+// NewInvalidValue returns an invalidValue error carrying the detail. It matches ErrInvalidValue in errors.Is.
+func NewInvalidValue(detail string) *Error {
+	return newError(ErrInvalidValue.Status, ScimTypeInvalidValue, detail)
+}
+
+// NewInvalidVers returns an invalidVers error carrying the detail. It matches ErrInvalidVers in errors.Is.
+func NewInvalidVers(detail string) *Error {
+	return newError(ErrInvalidVers.Status, ScimTypeInvalidVers, detail)
+}
+
+// NewSensitive returns a sensitive error carrying the detail. It matches ErrSensitive in errors.Is.
+func NewSensitive(detail string) *Error {
+	return newError(ErrSensitive.Status, ScimTypeSensitive, detail)
+}
+
+// WithExtras returns a copy of the error that carries the extras in addition to the extras already present. The copy
+// still matches the error in errors.Is.
+func (s *Error) WithExtras(extras map[string]interface{}) *Error {
+	merged := make(map[string]interface{}, len(s.Extras)+len(extras))
+	for k, v := range s.Extras {
+		merged[k] = v
+	}
+	for k, v := range extras {
+		merged[k] = v
+	}
+	return &Error{Status: s.Status, Type: s.Type, Detail: s.Detail, Extras: merged}
+}
+
+// Is returns true if target is a *Error of the same status and type, regardless of the detail and the extras.
+func (s *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other != nil && s.Status == other.Status && s.Type == other.Type
 }
 
 var (
@@ -0,0 +1,56 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       *Error
+		prototype *Error
+	}{
+		{name: "invalidFilter", err: NewInvalidFilter("detail"), prototype: ErrInvalidFilter},
+		{name: "tooMany", err: NewTooMany("detail"), prototype: ErrTooMany},
+		{name: "uniqueness", err: NewUniqueness("detail"), prototype: ErrUniqueness},
+		{name: "mutability", err: NewMutability("detail"), prototype: ErrMutability},
+		{name: "invalidSyntax", err: NewInvalidSyntax("detail"), prototype: ErrInvalidSyntax},
+		{name: "invalidPath", err: NewInvalidPath("detail"), prototype: ErrInvalidPath},
+		{name: "noTarget", err: NewNoTarget("detail"), prototype: ErrNoTarget},
+		{name: "invalidValue", err: NewInvalidValue("detail"), prototype: ErrInvalidValue},
+		{name: "invalidVers", err: NewInvalidVers("detail"), prototype: ErrInvalidVers},
+		{name: "sensitive", err: NewSensitive("detail"), prototype: ErrSensitive},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.True(t, ScimType(test.prototype.Type).Valid())
+			assert.Equal(t, test.name, test.err.Type)
+			assert.Equal(t, test.prototype.Status, test.err.Status)
+			assert.Equal(t, test.name+": detail", test.err.Error())
+			assert.True(t, errors.Is(test.err, test.prototype))
+			assert.True(t, errors.Is(fmt.Errorf("wrapped: %w", test.err), test.prototype))
+		})
+	}
+}
+
+func TestNewErrorInvalidScimType(t *testing.T) {
+	assert.False(t, ScimType("invalidValues").Valid())
+	_, err := NewError(400, "invalidValues", "detail")
+	assert.True(t, errors.Is(err, ErrInternal))
+
+	scimErr, err := NewError(400, ScimTypeInvalidValue, "detail")
+	assert.Nil(t, err)
+	assert.True(t, errors.Is(scimErr, ErrInvalidValue))
+}
+
+func TestErrorWithExtras(t *testing.T) {
+	err := NewUniqueness("value of 'userName' is not unique").WithExtras(map[string]interface{}{"attribute": "userName"})
+	assert.Equal(t, "uniqueness: value of 'userName' is not unique", err.Error())
+	assert.Equal(t, map[string]interface{}{"attribute": "userName"}, err.Extras)
+	assert.True(t, errors.Is(err, ErrUniqueness))
+	assert.Empty(t, ErrUniqueness.Extras)
+}
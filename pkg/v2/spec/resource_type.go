@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"github.com/imulab/go-scim/pkg/v2/annotation"
 	"github.com/imulab/go-scim/pkg/v2/spec/internal"
+	"strings"
 )
 
 // Resource type models the SCIM resource type. It is a collection of one main schema and zero or more schema extensions
@@ -85,6 +86,53 @@ func (t *ResourceType) CountExtensions() int {
 	return len(t.extensions)
 }
 
+// AttributeByPath returns the attribute addressed by the path among the core schema, main schema and the schema
+// extensions of the resource type, or false if no such attribute was found. Paths to the main schema and core schema
+// attributes may be prefixed by the main schema id (i.e. urn:ietf:params:scim:schemas:core:2.0:User:userName), while
+// paths to extension attributes must be prefixed by the extension schema id (i.e.
+// urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value). The extension schema id itself addresses
+// the extension container attribute, as seen in SuperAttribute. Like Schema.AttributeByPath, attribute names are case
+// insensitive.
+//
+// An unprefixed path which addresses no attribute of the main schema or core schema may address an extension attribute
+// by the short form (i.e. department for urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department), as
+// long as exactly one schema extension defines it. A short form defined by more than one schema extension is ambiguous
+// and not resolved.
+func (t *ResourceType) AttributeByPath(path string) (*Attribute, bool) {
+	for _, ext := range t.extensions {
+		if strings.EqualFold(path, ext.id) {
+			return t.SuperAttribute(false).SubAttributeForName(ext.id), true
+		}
+		if trimmed := trimSchemaId(path, ext.id); trimmed != path {
+			return ext.AttributeByPath(trimmed)
+		}
+	}
+
+	trimmed := trimSchemaId(path, t.schema.id)
+	if attr, ok := attributeByPath(t.schema.attributes, trimmed); ok {
+		return attr, true
+	}
+	if core, ok := Schemas().Get(CoreSchemaId); ok {
+		if attr, ok := attributeByPath(core.attributes, trimmed); ok {
+			return attr, true
+		}
+	}
+	if trimmed != path {
+		return nil, false
+	}
+
+	var found *Attribute
+	for _, ext := range t.extensions {
+		if attr, ok := attributeByPath(ext.attributes, path); ok {
+			if found != nil {
+				return nil, false
+			}
+			found = attr
+		}
+	}
+	return found, found != nil
+}
+
 // ResourceTypeName returns the resource type of the ResourceType resource. This value is formally defined and hence fixed.
 func (t *ResourceType) ResourceTypeName() string {
 	return "ResourceType"
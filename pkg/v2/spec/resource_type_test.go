@@ -59,3 +59,127 @@ func (s *ResourceTypeTestSuite) TestUnmarshal() {
 	assert.NotNil(s.T(), rt.Schema())
 	assert.Len(s.T(), rt.extensions, 1)
 }
+
+func (s *ResourceTypeTestSuite) TestAttributeByPath() {
+	const extId = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+	Schemas().Register(&Schema{
+		id: CoreSchemaId,
+		attributes: []*Attribute{
+			{id: "id", name: "id", path: "id", typ: TypeString},
+			{
+				id:   "meta",
+				name: "meta",
+				path: "meta",
+				typ:  TypeComplex,
+				subAttributes: []*Attribute{
+					{id: "meta.version", name: "version", path: "meta.version", typ: TypeString},
+				},
+			},
+		},
+	})
+	rt := &ResourceType{
+		id:     "User",
+		schema: testAttributeByPathSchema(),
+		extensions: []*Schema{
+			{
+				id: extId,
+				attributes: []*Attribute{
+					{
+						id:   extId + ":manager",
+						name: "manager",
+						path: "manager",
+						typ:  TypeComplex,
+						subAttributes: []*Attribute{
+							{id: extId + ":manager.value", name: "value", path: "manager.value", typ: TypeString},
+						},
+					},
+					{id: extId + ":costCenter", name: "costCenter", path: "costCenter", typ: TypeString},
+				},
+			},
+			{
+				id: "urn:test:other",
+				attributes: []*Attribute{
+					{id: "urn:test:other:costCenter", name: "costCenter", path: "costCenter", typ: TypeString},
+				},
+			},
+		},
+		required: map[string]bool{extId: false, "urn:test:other": false},
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		expect string
+	}{
+		{
+			name:   "main schema attribute",
+			path:   "emails.type",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:emails.type",
+		},
+		{
+			name:   "main schema attribute prefixed with schema id",
+			path:   "urn:ietf:params:scim:schemas:core:2.0:User:userName",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:userName",
+		},
+		{
+			name:   "core attribute",
+			path:   "meta.version",
+			expect: "meta.version",
+		},
+		{
+			name:   "core attribute prefixed with main schema id",
+			path:   "urn:ietf:params:scim:schemas:core:2.0:User:id",
+			expect: "id",
+		},
+		{
+			name:   "extension attribute",
+			path:   "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:manager.value",
+			expect: extId + ":manager.value",
+		},
+		{
+			name:   "extension attribute with case insensitive prefix",
+			path:   "URN:IETF:PARAMS:SCIM:SCHEMAS:EXTENSION:ENTERPRISE:2.0:USER:Manager",
+			expect: extId + ":manager",
+		},
+		{
+			name:   "extension container",
+			path:   extId,
+			expect: extId,
+		},
+		{
+			name:   "extension attribute in short form",
+			path:   "manager.value",
+			expect: extId + ":manager.value",
+		},
+		{
+			name: "extension attribute in short form prefixed with main schema id",
+			path: "urn:ietf:params:scim:schemas:core:2.0:User:manager",
+		},
+		{
+			name: "extension attribute in short form defined by more than one extension",
+			path: "costCenter",
+		},
+		{
+			name: "unknown extension attribute",
+			path: extId + ":employeeNumber",
+		},
+		{
+			name: "unknown attribute",
+			path: "meta.foo",
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			attr, ok := rt.AttributeByPath(test.path)
+			if len(test.expect) == 0 {
+				assert.False(t, ok)
+				assert.Nil(t, attr)
+			} else {
+				assert.True(t, ok)
+				assert.Equal(t, test.expect, attr.ID())
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package spec
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -50,6 +52,14 @@ func (s *Schema) ForEachAttribute(callback func(attr *Attribute) error) error {
 	return nil
 }
 
+// AttributeByPath returns the attribute addressed by the path, or false if no such attribute was found. The path is
+// a dot delimited sequence of attribute names (i.e. emails.type), optionally prefixed by the schema id and a colon
+// (i.e. urn:ietf:params:scim:schemas:core:2.0:User:emails.type). The attribute names are case insensitive, and
+// sub attributes with special names like "$ref" can be addressed as is.
+func (s *Schema) AttributeByPath(path string) (*Attribute, bool) {
+	return attributeByPath(s.attributes, trimSchemaId(path, s.id))
+}
+
 // ResourceTypeName returns the resource type of the Schema resource. This value is formally defined and hence fixed.
 func (s *Schema) ResourceTypeName() string {
 	return "Schema"
@@ -80,9 +90,77 @@ func (s *Schema) UnmarshalJSON(raw []byte) error {
 	s.name = adapter.Name
 	s.description = adapter.Description
 	s.attributes = adapter.Attributes
+	return s.validate()
+}
+
+// validate checks the attributes of the schema for misconfigurations that would otherwise only surface when requests
+// are processed: canonicalValues declared on attributes that are not of string or reference type, duplicate
+// canonicalValues (compared case insensitively unless the attribute is caseExact), multiValued complex attributes
+// without any sub attributes, and circular complex attribute definitions, in which a sub attribute refers back to one of
+// its ancestors, either as the same instance or by the same id.
+func (s *Schema) validate() error {
+	for _, attr := range s.attributes {
+		if err := s.validateAttribute(attr, nil); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// validateAttribute validates the attribute, and recursively its sub attributes. The ancestors are the chain of complex
+// attributes from the top level down to the parent of the attribute.
+func (s *Schema) validateAttribute(attr *Attribute, ancestors []*Attribute) error {
+	name := attributeName(attr)
+
+	for _, ancestor := range ancestors {
+		if ancestor == attr || (len(attr.id) > 0 && ancestor.id == attr.id) {
+			names := make([]string, 0, len(ancestors)+1)
+			for _, each := range ancestors {
+				names = append(names, attributeName(each))
+			}
+			names = append(names, name)
+			return fmt.Errorf("%w: attribute chain '%s' of schema '%s' is circular", ErrInvalidValue, strings.Join(names, " -> "), s.id)
+		}
+	}
+
+	if len(attr.canonicalValues) > 0 {
+		if attr.typ != TypeString && attr.typ != TypeReference {
+			return fmt.Errorf("%w: attribute '%s' of schema '%s' declares canonicalValues on %s type", ErrInvalidValue, name, s.id, attr.typ.String())
+		}
+		seen := map[string]struct{}{}
+		for _, value := range attr.canonicalValues {
+			key := value
+			if !attr.caseExact {
+				key = strings.ToLower(value)
+			}
+			if _, ok := seen[key]; ok {
+				return fmt.Errorf("%w: attribute '%s' of schema '%s' declares duplicate canonical value '%s'", ErrInvalidValue, name, s.id, value)
+			}
+			seen[key] = struct{}{}
+		}
+	}
+
+	if attr.multiValued && attr.typ == TypeComplex && len(attr.subAttributes) == 0 {
+		return fmt.Errorf("%w: multiValued complex attribute '%s' of schema '%s' has no sub attributes", ErrInvalidValue, name, s.id)
+	}
+
+	chain := append(append([]*Attribute{}, ancestors...), attr)
+	for _, subAttr := range attr.subAttributes {
+		if err := s.validateAttribute(subAttr, chain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attributeName returns the path of the attribute for error messages, or its name if the path is not available.
+func attributeName(attr *Attribute) string {
+	if len(attr.path) > 0 {
+		return attr.path
+	}
+	return attr.name
+}
+
 type schemaJsonAdapter struct {
 	ID          string       `json:"id"`
 	Name        string       `json:"name"`
@@ -136,3 +214,37 @@ func Schemas() *schemaRegistry {
 	})
 	return schemaReg
 }
+
+// trimSchemaId removes the schema id and the following colon from the head of the path, if present.
+func trimSchemaId(path string, schemaId string) string {
+	if len(schemaId) > 0 && len(path) > len(schemaId) && path[len(schemaId)] == ':' && strings.EqualFold(path[:len(schemaId)], schemaId) {
+		return path[len(schemaId)+1:]
+	}
+	return path
+}
+
+// attributeByPath walks the dot delimited path among the attributes and their sub attributes.
+func attributeByPath(attributes []*Attribute, path string) (*Attribute, bool) {
+	if len(path) == 0 {
+		return nil, false
+	}
+
+	var (
+		names  = strings.Split(path, ".")
+		cursor *Attribute
+	)
+	for _, attr := range attributes {
+		if attr.GoesBy(names[0]) {
+			cursor = attr
+			break
+		}
+	}
+	for _, name := range names[1:] {
+		if cursor == nil {
+			break
+		}
+		cursor = cursor.SubAttributeForName(name)
+	}
+
+	return cursor, cursor != nil
+}
@@ -2,6 +2,7 @@ package spec
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"testing"
@@ -80,3 +81,302 @@ func (s *SchemaTestSuite) TestUnmarshal() {
 	assert.Equal(s.T(), "User", schema.Name())
 	assert.Len(s.T(), schema.attributes, 1)
 }
+
+func (s *SchemaTestSuite) TestUnmarshalValidation() {
+	tests := []struct {
+		name       string
+		attributes string
+		expectErr  error
+		expectMsg  string
+	}{
+		{
+			name: "canonical values on string attribute",
+			attributes: `
+[
+  {
+    "name": "type",
+    "type": "string",
+    "canonicalValues": ["work", "home"]
+  }
+]
+`,
+		},
+		{
+			name: "canonical values on non-string attribute",
+			attributes: `
+[
+  {
+    "name": "level",
+    "type": "integer",
+    "canonicalValues": ["1", "2"]
+  }
+]
+`,
+			expectErr: ErrInvalidValue,
+		},
+		{
+			name: "duplicate canonical values",
+			attributes: `
+[
+  {
+    "name": "type",
+    "type": "string",
+    "canonicalValues": ["work", "Work"]
+  }
+]
+`,
+			expectErr: ErrInvalidValue,
+		},
+		{
+			name: "case exact canonical values differing in case",
+			attributes: `
+[
+  {
+    "name": "type",
+    "type": "string",
+    "caseExact": true,
+    "canonicalValues": ["work", "Work"]
+  }
+]
+`,
+		},
+		{
+			name: "invalid canonical values on sub attribute",
+			attributes: `
+[
+  {
+    "name": "emails",
+    "type": "complex",
+    "multiValued": true,
+    "subAttributes": [
+      {
+        "name": "primary",
+        "type": "boolean",
+        "_path": "emails.primary",
+        "canonicalValues": ["true"]
+      }
+    ]
+  }
+]
+`,
+			expectErr: ErrInvalidValue,
+		},
+		{
+			name: "multiValued complex attribute without sub attributes",
+			attributes: `
+[
+  {
+    "name": "emails",
+    "type": "complex",
+    "multiValued": true
+  }
+]
+`,
+			expectErr: ErrInvalidValue,
+		},
+		{
+			name: "complex attribute referring back to its ancestor",
+			attributes: `
+[
+  {
+    "id": "urn:test:Validation:manager",
+    "name": "manager",
+    "type": "complex",
+    "_path": "manager",
+    "subAttributes": [
+      {
+        "id": "urn:test:Validation:manager.reports",
+        "name": "reports",
+        "type": "complex",
+        "multiValued": true,
+        "_path": "manager.reports",
+        "subAttributes": [
+          {
+            "id": "urn:test:Validation:manager",
+            "name": "manager",
+            "type": "complex",
+            "_path": "manager.reports.manager",
+            "subAttributes": [
+              {
+                "id": "urn:test:Validation:manager.value",
+                "name": "value",
+                "type": "string",
+                "_path": "manager.reports.manager.value"
+              }
+            ]
+          }
+        ]
+      }
+    ]
+  }
+]
+`,
+			expectErr: ErrInvalidValue,
+			expectMsg: "manager -> manager.reports -> manager.reports.manager",
+		},
+		{
+			name: "sibling complex attributes with same sub attribute names",
+			attributes: `
+[
+  {
+    "id": "urn:test:Validation:manager",
+    "name": "manager",
+    "type": "complex",
+    "_path": "manager",
+    "subAttributes": [
+      {
+        "id": "urn:test:Validation:manager.value",
+        "name": "value",
+        "type": "string",
+        "_path": "manager.value"
+      }
+    ]
+  },
+  {
+    "id": "urn:test:Validation:assistant",
+    "name": "assistant",
+    "type": "complex",
+    "_path": "assistant",
+    "subAttributes": [
+      {
+        "id": "urn:test:Validation:assistant.value",
+        "name": "value",
+        "type": "string",
+        "_path": "assistant.value"
+      }
+    ]
+  }
+]
+`,
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			raw := `{"id": "urn:test:Validation", "name": "Validation", "attributes": ` + test.attributes + `}`
+			err := json.Unmarshal([]byte(raw), new(Schema))
+			if test.expectErr == nil {
+				assert.Nil(t, err)
+			} else {
+				assert.Equal(t, test.expectErr, errors.Unwrap(err))
+				assert.Contains(t, err.Error(), "urn:test:Validation")
+				assert.Contains(t, err.Error(), test.expectMsg)
+			}
+		})
+	}
+}
+
+func (s *SchemaTestSuite) TestValidateCircularInstance() {
+	manager := &Attribute{id: "manager", name: "manager", typ: TypeComplex, path: "manager"}
+	reports := &Attribute{id: "manager.reports", name: "reports", typ: TypeComplex, multiValued: true, path: "manager.reports", subAttributes: []*Attribute{manager}}
+	manager.subAttributes = []*Attribute{reports}
+
+	err := (&Schema{id: "urn:test:Validation", attributes: []*Attribute{manager}}).validate()
+	assert.Equal(s.T(), ErrInvalidValue, errors.Unwrap(err))
+	assert.Contains(s.T(), err.Error(), "'manager -> manager.reports -> manager'")
+}
+
+func (s *SchemaTestSuite) TestAttributeByPath() {
+	schema := testAttributeByPathSchema()
+
+	tests := []struct {
+		name   string
+		path   string
+		expect string
+	}{
+		{
+			name:   "top level attribute",
+			path:   "userName",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:userName",
+		},
+		{
+			name:   "case insensitive",
+			path:   "USERNAME",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:userName",
+		},
+		{
+			name:   "nested attribute",
+			path:   "emails.type",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:emails.type",
+		},
+		{
+			name:   "reference attribute",
+			path:   "groups.$ref",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:groups.$ref",
+		},
+		{
+			name:   "prefixed with schema id",
+			path:   "urn:ietf:params:scim:schemas:core:2.0:User:emails.Value",
+			expect: "urn:ietf:params:scim:schemas:core:2.0:User:emails.value",
+		},
+		{
+			name: "unknown attribute",
+			path: "nickName",
+		},
+		{
+			name: "unknown sub attribute",
+			path: "emails.display",
+		},
+		{
+			name: "sub attribute of simple attribute",
+			path: "userName.value",
+		},
+		{
+			name: "empty step",
+			path: "emails..value",
+		},
+		{
+			name: "schema id only",
+			path: "urn:ietf:params:scim:schemas:core:2.0:User",
+		},
+		{
+			name: "empty path",
+			path: "",
+		},
+	}
+
+	for _, test := range tests {
+		s.T().Run(test.name, func(t *testing.T) {
+			attr, ok := schema.AttributeByPath(test.path)
+			if len(test.expect) == 0 {
+				assert.False(t, ok)
+				assert.Nil(t, attr)
+			} else {
+				assert.True(t, ok)
+				assert.Equal(t, test.expect, attr.ID())
+			}
+		})
+	}
+}
+
+func testAttributeByPathSchema() *Schema {
+	const id = "urn:ietf:params:scim:schemas:core:2.0:User"
+	return &Schema{
+		id: id,
+		attributes: []*Attribute{
+			{id: id + ":userName", name: "userName", path: "userName", typ: TypeString},
+			{
+				id:          id + ":emails",
+				name:        "emails",
+				path:        "emails",
+				typ:         TypeComplex,
+				multiValued: true,
+				subAttributes: []*Attribute{
+					{id: id + ":emails.value", name: "value", path: "emails.value", typ: TypeString},
+					{id: id + ":emails.type", name: "type", path: "emails.type", typ: TypeString},
+				},
+			},
+			{
+				id:          id + ":groups",
+				name:        "groups",
+				path:        "groups",
+				typ:         TypeComplex,
+				multiValued: true,
+				subAttributes: []*Attribute{
+					{id: id + ":groups.value", name: "value", path: "groups.value", typ: TypeString},
+					{id: id + ":groups.$ref", name: "$ref", path: "groups.$ref", typ: TypeReference},
+				},
+			},
+		},
+	}
+}
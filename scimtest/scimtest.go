@@ -0,0 +1,385 @@
+// Package scimtest provides a fully configured, in-memory SCIM server for integration tests.
+//
+// The server serves the User (with the enterprise extension) and Group resource types, along with the service
+// provider config, schemas and resource types discovery endpoints. It is backed by in-memory databases seeded with
+// the user SeedUserID and the group SeedGroupID, which has the seeded user as its only member. Group memberships
+// are propagated to the "groups" attribute of users synchronously.
+//
+//	baseURL, teardown, err := scimtest.Start("../public", scimtest.WithoutUniqueness())
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer teardown()
+//
+//	resp, err := http.Get(baseURL + "/Users/" + scimtest.SeedUserID)
+package scimtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/imulab/go-scim/internal/handler"
+	"github.com/imulab/go-scim/pkg/v2/crud"
+	"github.com/imulab/go-scim/pkg/v2/db"
+	"github.com/imulab/go-scim/pkg/v2/groupsync"
+	"github.com/imulab/go-scim/pkg/v2/handlerutil"
+	"github.com/imulab/go-scim/pkg/v2/service"
+	"github.com/imulab/go-scim/pkg/v2/service/filter"
+	"github.com/imulab/go-scim/pkg/v2/spec"
+	"github.com/julienschmidt/httprouter"
+	"github.com/rs/zerolog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Identifiers and attributes of the seeded resources.
+const (
+	SeedUserID       = "3cc032f5-2361-417f-9e2f-bc80adddf4a3"
+	SeedUserName     = "imulab"
+	SeedUserPassword = "s3cret"
+	SeedGroupID      = "c5e2a0a6-9a3c-4dbb-8a2e-0fd7c1a0b6d4"
+	SeedGroupName    = "Engineering"
+)
+
+// Option customizes the server started by Start.
+type Option func(c *config)
+
+// WithoutUniqueness disables the uniqueness check of the validation filter, so that resources with duplicate
+// uniqueness=server values (i.e. userName) can be created.
+func WithoutUniqueness() Option {
+	return func(c *config) {
+		c.uniqueness = false
+	}
+}
+
+// WithConflictingID makes a failed uniqueness check report the id of the existing resource holding the conflicting
+// value, in addition to the conflicting attribute.
+func WithConflictingID() Option {
+	return func(c *config) {
+		c.revealConflicts = true
+	}
+}
+
+// WithoutBCrypt disables the BCrypt filter, so that passwords are saved as is.
+func WithoutBCrypt() Option {
+	return func(c *config) {
+		c.bcrypt = false
+	}
+}
+
+// WithNoContent makes the PUT and PATCH endpoints respond with 204 No Content instead of the updated resource, unless
+// the client prefers return=representation in the Prefer header.
+func WithNoContent() Option {
+	return func(c *config) {
+		c.noContent = true
+	}
+}
+
+// WithDefaultPageSize sets the number of resources returned by a query which does not specify count.
+func WithDefaultPageSize(size int) Option {
+	return func(c *config) {
+		c.defaultPageSize = size
+	}
+}
+
+// WithLocationBuilder sets the LocationBuilder computing the Location header and meta.location of the resources
+// served, i.e. to simulate a server behind a reverse proxy.
+func WithLocationBuilder(builder handlerutil.LocationBuilder) Option {
+	return func(c *config) {
+		c.location = builder
+	}
+}
+
+type config struct {
+	uniqueness      bool
+	revealConflicts bool
+	bcrypt          bool
+	noContent       bool
+	defaultPageSize int
+	location        handlerutil.LocationBuilder
+}
+
+var (
+	registerOnce sync.Once
+	registerErr  error
+)
+
+// Start starts a new in-memory SCIM server on a local httptest.Server and returns its base URL, along with the
+// teardown function which closes the server. Every server started has its own seeded databases.
+//
+// The publicDir is the directory holding the schemas and resource types, laid out as the public directory of this
+// repository: the service_provider_config.json file, the schemas directory, and the user_enterprise_resource_type.json
+// and group_resource_type.json files in the resource_types directory. The schemas are registered when Start is first
+// called.
+func Start(publicDir string, options ...Option) (baseURL string, teardown func(), err error) {
+	c := &config{uniqueness: true, bcrypt: true}
+	for _, opt := range options {
+		opt(c)
+	}
+
+	registerOnce.Do(func() {
+		registerErr = registerSchemas(filepath.Join(publicDir, "schemas"))
+	})
+	if err = registerErr; err != nil {
+		return
+	}
+
+	a := &app{config: c}
+	if err = a.load(publicDir); err != nil {
+		return
+	}
+	if err = a.seed(context.Background()); err != nil {
+		return
+	}
+
+	server := httptest.NewServer(handlerutil.Recover(handlerutil.LocationContext(a.router(), c.location), nil))
+	baseURL, teardown = server.URL, server.Close
+	return
+}
+
+type app struct {
+	*config
+	serviceProviderConfig *spec.ServiceProviderConfig
+	userResourceType      *spec.ResourceType
+	groupResourceType     *spec.ResourceType
+	userDatabase          db.DB
+	groupDatabase         db.DB
+	propagator            *groupsync.Propagator
+}
+
+func (a *app) load(publicDir string) error {
+	a.serviceProviderConfig = new(spec.ServiceProviderConfig)
+	if err := decodeFile(filepath.Join(publicDir, "service_provider_config.json"), a.serviceProviderConfig); err != nil {
+		return err
+	}
+
+	a.userResourceType = new(spec.ResourceType)
+	if err := decodeFile(filepath.Join(publicDir, "resource_types", "user_enterprise_resource_type.json"), a.userResourceType); err != nil {
+		return err
+	}
+
+	a.groupResourceType = new(spec.ResourceType)
+	if err := decodeFile(filepath.Join(publicDir, "resource_types", "group_resource_type.json"), a.groupResourceType); err != nil {
+		return err
+	}
+	crud.Register(a.userResourceType)
+	crud.Register(a.groupResourceType)
+
+	a.userDatabase = db.Memory("userName")
+	a.groupDatabase = db.Memory("displayName")
+	a.propagator = groupsync.NewPropagator(a.userDatabase, a.groupDatabase, filter.MetaFilter())
+	return nil
+}
+
+// seed inserts the seeded resources. The ids are generated by fixed generators, so that the seeded resources bear
+// their well known ids.
+func (a *app) seed(ctx context.Context) error {
+	userFilters := []filter.ByResource{a.userPropertyFilters(), filter.MetaFilter()}
+	if _, err := service.CreateService(a.userResourceType, a.userDatabase, userFilters, fixedId(SeedUserID)).Do(ctx, &service.CreateRequest{
+		PayloadSource: strings.NewReader(fmt.Sprintf(`{
+  "schemas": [
+    "urn:ietf:params:scim:schemas:core:2.0:User",
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+  ],
+  "userName": "%s",
+  "password": "%s",
+  "displayName": "Weinan Qiu",
+  "name": {
+    "givenName": "Weinan",
+    "familyName": "Qiu"
+  },
+  "emails": [
+    {
+      "value": "imulab@example.com",
+      "type": "work",
+      "primary": true
+    }
+  ],
+  "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {
+    "employeeNumber": "701984",
+    "department": "Engineering"
+  }
+}`, SeedUserName, SeedUserPassword)),
+	}); err != nil {
+		return err
+	}
+
+	groupFilters := []filter.ByResource{filter.MetaFilter()}
+	_, err := groupsync.CreateService(
+		service.CreateService(a.groupResourceType, a.groupDatabase, groupFilters, fixedId(SeedGroupID)),
+		a.propagator,
+	).Do(ctx, &service.CreateRequest{
+		PayloadSource: strings.NewReader(fmt.Sprintf(`{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+  "displayName": "%s",
+  "members": [
+    {
+      "value": "%s"
+    }
+  ]
+}`, SeedGroupName, SeedUserID)),
+	})
+	return err
+}
+
+func fixedId(id string) service.CreateOption {
+	return service.WithIdGenerator(service.IdGeneratorFunc(func(_ *spec.ResourceType) (string, error) {
+		return id, nil
+	}))
+}
+
+func (a *app) router() *httprouter.Router {
+	var (
+		logger = zerolog.Nop()
+		router = httprouter.New()
+	)
+
+	router.GET("/ServiceProviderConfig", handler.ServiceProviderConfigHandler(a.serviceProviderConfig))
+	router.GET("/Schemas", handler.SchemasHandler())
+	router.GET("/Schemas/:id", handler.SchemaByIdHandler())
+	router.GET("/ResourceTypes", handler.ResourceTypesHandler(a.userResourceType, a.groupResourceType))
+	router.GET("/ResourceTypes/:id", handler.ResourceTypeByIdHandler(a.userResourceType, a.groupResourceType))
+
+	router.GET("/Users/:id", handler.GetHandler(service.GetService(a.userDatabase, service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext))), &logger))
+	router.HEAD("/Users/:id", handler.HeadHandler(service.GetService(a.userDatabase, service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext))), &logger))
+	router.GET("/Users", handler.SearchHandler(a.queryService(a.userResourceType, a.userDatabase), &logger))
+	router.POST("/Users/.search", handler.SearchHandler(a.queryService(a.userResourceType, a.userDatabase), &logger))
+	router.POST("/Users", handler.CreateHandler(a.userCreateService(), &logger))
+	router.PUT("/Users/:id", handler.ReplaceOrCreateHandler(a.userReplaceService(), a.userCreateService(), a.noContent, &logger))
+	router.PATCH("/Users/:id", handler.PatchHandler(a.userPatchService(), a.noContent, &logger))
+	router.DELETE("/Users/:id", handler.DeleteHandler(service.DeleteService(a.serviceProviderConfig, a.userDatabase), &logger))
+	router.DELETE("/Users/:id/:attribute/:key", handler.DeleteElementHandler(service.DeleteElementService(a.userDatabase, a.userPatchService()), &logger))
+
+	router.GET("/Groups/:id", handler.GetHandler(service.GetService(a.groupDatabase, service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext))), &logger))
+	router.HEAD("/Groups/:id", handler.HeadHandler(service.GetService(a.groupDatabase, service.GetWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext))), &logger))
+	router.GET("/Groups", handler.SearchHandler(a.queryService(a.groupResourceType, a.groupDatabase), &logger))
+	router.POST("/Groups/.search", handler.SearchHandler(a.queryService(a.groupResourceType, a.groupDatabase), &logger))
+	router.POST("/Groups", handler.CreateHandler(a.groupCreateService(), &logger))
+	router.PUT("/Groups/:id", handler.ReplaceOrCreateHandler(a.groupReplaceService(), a.groupCreateService(), a.noContent, &logger))
+	router.PATCH("/Groups/:id", handler.PatchHandler(a.groupPatchService(), a.noContent, &logger))
+	router.DELETE("/Groups/:id", handler.DeleteHandler(a.groupDeleteService(), &logger))
+	router.DELETE("/Groups/:id/:attribute/:key", handler.DeleteElementHandler(service.DeleteElementService(a.groupDatabase, a.groupPatchService()), &logger))
+
+	return router
+}
+
+func (a *app) userCreateService() service.Create {
+	return service.CreateService(a.userResourceType, a.userDatabase, []filter.ByResource{
+		filter.ByPropertyToByResource(
+			filter.ReadOnlyFilter(),
+			filter.UUIDFilter(),
+		),
+		a.userPropertyFilters(),
+		filter.MetaFilter(),
+		filter.ByPropertyToByResource(a.validationFilter(a.userDatabase)),
+	})
+}
+
+func (a *app) groupCreateService() service.Create {
+	return groupsync.CreateService(service.CreateService(a.groupResourceType, a.groupDatabase, []filter.ByResource{
+		filter.ByPropertyToByResource(
+			filter.ReadOnlyFilter(),
+			filter.UUIDFilter(),
+		),
+		filter.MetaFilter(),
+		filter.ByPropertyToByResource(a.validationFilter(a.groupDatabase)),
+	}), a.propagator)
+}
+
+func (a *app) userReplaceService() service.Replace {
+	return service.ReplaceService(a.serviceProviderConfig, a.userResourceType, a.userDatabase, []filter.ByResource{
+		filter.ByPropertyToByResource(filter.ReadOnlyFilter()),
+		a.userPropertyFilters(),
+		filter.ByPropertyToByResource(a.validationFilter(a.userDatabase)),
+		filter.MetaFilter(),
+	})
+}
+
+func (a *app) groupReplaceService() service.Replace {
+	return groupsync.ReplaceService(service.ReplaceService(a.serviceProviderConfig, a.groupResourceType, a.groupDatabase, []filter.ByResource{
+		filter.ByPropertyToByResource(filter.ReadOnlyFilter()),
+		filter.ByPropertyToByResource(a.validationFilter(a.groupDatabase)),
+		filter.MetaFilter(),
+	}), a.propagator)
+}
+
+func (a *app) userPatchService() service.Patch {
+	return service.PatchService(a.serviceProviderConfig, a.userDatabase, []filter.ByResource{}, []filter.ByResource{
+		filter.ByPropertyToByResource(filter.ReadOnlyFilter()),
+		a.userPropertyFilters(),
+		filter.ByPropertyToByResource(a.validationFilter(a.userDatabase)),
+		filter.MetaFilter(),
+	})
+}
+
+func (a *app) groupPatchService() service.Patch {
+	return groupsync.PatchService(service.PatchService(a.serviceProviderConfig, a.groupDatabase, []filter.ByResource{}, []filter.ByResource{
+		filter.ByPropertyToByResource(filter.ReadOnlyFilter()),
+		filter.ByPropertyToByResource(a.validationFilter(a.groupDatabase)),
+		filter.MetaFilter(),
+	}), a.propagator)
+}
+
+func (a *app) groupDeleteService() service.Delete {
+	return groupsync.DeleteService(service.DeleteService(a.serviceProviderConfig, a.groupDatabase), a.propagator)
+}
+
+func (a *app) queryService(resourceType *spec.ResourceType, database db.DB) service.Query {
+	return service.QueryService(
+		a.serviceProviderConfig,
+		database,
+		service.QueryWithDefaultPageSize(a.defaultPageSize),
+		service.QueryWithResourceType(resourceType),
+		service.QueryWithFilters(filter.MetaReconcileFilter(handlerutil.LocationFromContext)),
+	)
+}
+
+// userPropertyFilters returns the property filters specific to the User resource type.
+func (a *app) userPropertyFilters() filter.ByResource {
+	filters := make([]filter.ByProperty, 0)
+	if a.bcrypt {
+		filters = append(filters, filter.BCryptFilter())
+	}
+	return filter.ByPropertyToByResource(filters...)
+}
+
+func (a *app) validationFilter(database db.DB) filter.ByProperty {
+	if !a.uniqueness {
+		return filter.ValidationFilter(nil)
+	}
+	if a.revealConflicts {
+		return filter.ValidationFilterRevealingConflicts(database)
+	}
+	return filter.ValidationFilter(database)
+}
+
+func registerSchemas(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !strings.HasSuffix(info.Name(), ".json") {
+			return nil
+		}
+
+		schema := new(spec.Schema)
+		if err := decodeFile(path, schema); err != nil {
+			return err
+		}
+		spec.Schemas().Register(schema)
+		return nil
+	})
+}
+
+func decodeFile(path string, v interface{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewDecoder(f).Decode(v)
+}
@@ -0,0 +1,339 @@
+package scimtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/imulab/go-scim/pkg/v2/prop"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		expect  func(t *testing.T, baseURL string)
+	}{
+		{
+			name: "seeded user is served with group membership",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodGet, baseURL+"/Users/"+SeedUserID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, SeedUserName, body["userName"])
+				assert.NotContains(t, body, "password")
+				assert.Equal(t, "Engineering", body["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].(map[string]interface{})["department"])
+				groups := body["groups"].([]interface{})
+				assert.Len(t, groups, 1)
+				assert.Equal(t, SeedGroupID, groups[0].(map[string]interface{})["value"])
+			},
+		},
+		{
+			name: "seeded group is served",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodGet, baseURL+"/Groups/"+SeedGroupID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, SeedGroupName, body["displayName"])
+				assert.Len(t, body["members"], 1)
+			},
+		},
+		{
+			name: "discovery endpoints are served",
+			expect: func(t *testing.T, baseURL string) {
+				for _, path := range []string{"/ServiceProviderConfig", "/Schemas", "/ResourceTypes/User"} {
+					status, _ := request(t, http.MethodGet, baseURL+path, "")
+					assert.Equal(t, http.StatusOK, status, path)
+				}
+			},
+		},
+		{
+			name: "users are searched by enterprise extension attribute",
+			expect: func(t *testing.T, baseURL string) {
+				for _, test := range []struct {
+					filter string
+					total  float64
+				}{
+					{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department eq "Engineering"`, total: 1},
+					{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department co "gineer"`, total: 1},
+					{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department pr`, total: 1},
+					{filter: `urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department eq "Sales"`, total: 0},
+					{filter: `department eq "Engineering"`, total: 1},
+				} {
+					status, body := request(t, http.MethodGet, baseURL+"/Users?filter="+url.QueryEscape(test.filter), "")
+					assert.Equal(t, http.StatusOK, status, test.filter)
+					assert.Equal(t, test.total, body["totalResults"], test.filter)
+				}
+			},
+		},
+		{
+			name: "users are searched via post",
+			expect: func(t *testing.T, baseURL string) {
+				for _, test := range []struct {
+					payload string
+					status  int
+					total   float64
+				}{
+					{
+						payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": "userName eq \"imulab\" or displayName eq \"100% & #1 + ?\"", "attributes": ["userName"], "startIndex": 1, "count": 10}`,
+						status:  http.StatusOK,
+						total:   1,
+					},
+					{
+						payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": "displayName eq \"100% & #1 + ?\""}`,
+						status:  http.StatusOK,
+						total:   0,
+					},
+					{
+						payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "attributes": ["userName"], "excludedAttributes": ["emails"]}`,
+						status:  http.StatusBadRequest,
+					},
+					{
+						payload: `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:SearchRequest"], "filter": "userName xx \"imulab\""}`,
+						status:  http.StatusBadRequest,
+					},
+				} {
+					status, body := request(t, http.MethodPost, baseURL+"/Users/.search", test.payload)
+					assert.Equal(t, test.status, status, test.payload)
+					if test.status == http.StatusOK {
+						assert.Equal(t, test.total, body["totalResults"], test.payload)
+					}
+					if test.total > 0 {
+						resources := body["Resources"].([]interface{})
+						assert.Len(t, resources, 1)
+						assert.Equal(t, SeedUserName, resources[0].(map[string]interface{})["userName"])
+						assert.NotContains(t, resources[0], "emails")
+					}
+				}
+			},
+		},
+		{
+			name: "users are not searched by undefined attributes",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodGet, baseURL+"/Users?filter="+url.QueryEscape(`nonExistentAttr eq "x"`), "")
+				assert.Equal(t, http.StatusBadRequest, status)
+				assert.Equal(t, "invalidFilter", body["scimType"])
+				assert.Contains(t, body["detail"], "nonExistentAttr")
+			},
+		},
+		{
+			name: "concurrent conditional creates result in exactly one resource",
+			expect: func(t *testing.T, baseURL string) {
+				const n = 8
+				statuses := make(chan int, n)
+				var wg sync.WaitGroup
+				for i := 0; i < n; i++ {
+					wg.Add(1)
+					go func(i int) {
+						defer wg.Done()
+						req, err := http.NewRequest(http.MethodPut, baseURL+"/Users/conditional", strings.NewReader(
+							strings.Replace(duplicateUser, `"imulab"`, fmt.Sprintf(`"conditional%d"`, i), 1)))
+						if !assert.Nil(t, err) {
+							return
+						}
+						req.Header.Set("If-None-Match", "*")
+						resp, err := http.DefaultClient.Do(req)
+						if !assert.Nil(t, err) {
+							return
+						}
+						_ = resp.Body.Close()
+						statuses <- resp.StatusCode
+					}(i)
+				}
+				wg.Wait()
+				close(statuses)
+
+				counts := map[int]int{}
+				for status := range statuses {
+					counts[status]++
+				}
+				assert.Equal(t, map[int]int{http.StatusCreated: 1, http.StatusPreconditionFailed: n - 1}, counts)
+
+				status, body := request(t, http.MethodGet, baseURL+"/Users/conditional", "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "conditional", body["id"])
+
+				status, body = request(t, http.MethodGet, baseURL+"/Users?filter="+url.QueryEscape(`userName sw "conditional"`), "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, float64(1), body["totalResults"])
+			},
+		},
+		{
+			name: "replace without conditional header does not create",
+			expect: func(t *testing.T, baseURL string) {
+				status, _ := request(t, http.MethodPut, baseURL+"/Users/missing", duplicateUser)
+				assert.Equal(t, http.StatusNotFound, status)
+			},
+		},
+		{
+			name: "replace projects the response by the attributes parameter",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodPut, baseURL+"/Users/"+SeedUserID+"?attributes=userName", renamedUser)
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "imulab2", body["userName"])
+				assert.NotContains(t, body, "groups")
+
+				status, body = request(t, http.MethodGet, baseURL+"/Users/"+SeedUserID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "imulab2", body["userName"])
+				assert.NotContains(t, body, "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User")
+			},
+		},
+		{
+			name: "replace is scoped by the replaceScope parameter",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodPut, baseURL+"/Users/"+SeedUserID+"?replaceScope=userName", renamedUser)
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "imulab2", body["userName"])
+
+				status, body = request(t, http.MethodGet, baseURL+"/Users/"+SeedUserID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, "imulab2", body["userName"])
+				assert.Equal(t, "Engineering", body["urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"].(map[string]interface{})["department"])
+			},
+		},
+		{
+			name: "duplicate userName is rejected by default",
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodPost, baseURL+"/Users", duplicateUser)
+				assert.Equal(t, http.StatusConflict, status)
+				assert.Equal(t, "uniqueness", body["scimType"])
+				assert.Equal(t, "userName", body["attribute"])
+				assert.Contains(t, body["detail"], "not unique")
+				assert.NotContains(t, body, "existingId")
+			},
+		},
+		{
+			name:    "duplicate userName reveals existing id when configured",
+			options: []Option{WithConflictingID()},
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodPost, baseURL+"/Users", duplicateUser)
+				assert.Equal(t, http.StatusConflict, status)
+				assert.Equal(t, "userName", body["attribute"])
+				assert.Equal(t, SeedUserID, body["existingId"])
+			},
+		},
+		{
+			name:    "duplicate userName is accepted without uniqueness",
+			options: []Option{WithoutUniqueness()},
+			expect: func(t *testing.T, baseURL string) {
+				status, body := request(t, http.MethodPost, baseURL+"/Users", duplicateUser)
+				assert.Equal(t, http.StatusCreated, status)
+				assert.NotEqual(t, SeedUserID, body["id"])
+			},
+		},
+		{
+			name: "group member is deleted via sub resource path",
+			expect: func(t *testing.T, baseURL string) {
+				status, _ := request(t, http.MethodDelete, baseURL+"/Groups/"+SeedGroupID+"/members/"+SeedUserID, "")
+				assert.Equal(t, http.StatusNoContent, status)
+
+				status, body := request(t, http.MethodGet, baseURL+"/Users/"+SeedUserID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.NotContains(t, body, "groups")
+
+				status, _ = request(t, http.MethodDelete, baseURL+"/Groups/"+SeedGroupID+"/members/"+SeedUserID, "")
+				assert.Equal(t, http.StatusNotFound, status)
+			},
+		},
+		{
+			name: "meta.location is derived from the location builder",
+			options: []Option{
+				WithLocationBuilder(func(r *http.Request, resource *prop.Resource) string {
+					return "https://scim.example.com/v2" + resource.ResourceType().Endpoint() + "/" + resource.IdOrEmpty()
+				}),
+			},
+			expect: func(t *testing.T, baseURL string) {
+				expect := "https://scim.example.com/v2/Users/" + SeedUserID
+
+				status, body := request(t, http.MethodGet, baseURL+"/Users/"+SeedUserID, "")
+				assert.Equal(t, http.StatusOK, status)
+				assert.Equal(t, expect, body["meta"].(map[string]interface{})["location"])
+
+				status, body = request(t, http.MethodGet, baseURL+"/Users?filter="+url.QueryEscape(`userName eq "imulab"`), "")
+				assert.Equal(t, http.StatusOK, status)
+				resources := body["Resources"].([]interface{})
+				if assert.Len(t, resources, 1) {
+					assert.Equal(t, expect, resources[0].(map[string]interface{})["meta"].(map[string]interface{})["location"])
+				}
+			},
+		},
+		{
+			name: "head carries the same headers as get",
+			expect: func(t *testing.T, baseURL string) {
+				for _, url := range []string{baseURL + "/Users/" + SeedUserID, baseURL + "/Groups/" + SeedGroupID} {
+					get, err := http.Get(url)
+					if !assert.Nil(t, err) {
+						return
+					}
+					_ = get.Body.Close()
+
+					head, err := http.Head(url)
+					if !assert.Nil(t, err) {
+						return
+					}
+					_ = head.Body.Close()
+
+					assert.Equal(t, http.StatusOK, head.StatusCode, url)
+					assert.NotEmpty(t, head.Header.Get("ETag"), url)
+					for _, header := range []string{"Content-Type", "ETag", "Location"} {
+						assert.Equal(t, get.Header.Get(header), head.Header.Get(header), url)
+					}
+				}
+
+				head, err := http.Head(baseURL + "/Users/missing")
+				if assert.Nil(t, err) {
+					_ = head.Body.Close()
+					assert.Equal(t, http.StatusNotFound, head.StatusCode)
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			baseURL, teardown, err := Start("../public", test.options...)
+			if !assert.Nil(t, err) {
+				return
+			}
+			defer teardown()
+
+			test.expect(t, baseURL)
+		})
+	}
+}
+
+const renamedUser = `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "userName": "imulab2",
+  "emails": [{"value": "imulab@example.com", "type": "work", "primary": true}]
+}`
+
+const duplicateUser = `{
+  "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+  "userName": "imulab",
+  "emails": [
+    {
+      "value": "imulab@example.com"
+    }
+  ]
+}`
+
+func request(t *testing.T, method string, url string, payload string) (int, map[string]interface{}) {
+	req, err := http.NewRequest(method, url, strings.NewReader(payload))
+	assert.Nil(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.Nil(t, err) {
+		return 0, nil
+	}
+	defer resp.Body.Close()
+
+	body := make(map[string]interface{})
+	if resp.StatusCode != http.StatusNoContent {
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return resp.StatusCode, body
+}